@@ -0,0 +1,134 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package turtlecoinrpc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+)
+
+// AddressPrefix is the base58 prefix of TurtleCoin addresses,
+// which makes every address start with TRTL
+const AddressPrefix = 3914525
+
+// Lengths of the base58 encoded addresses and of payment IDs
+const (
+	AddressLength           = 99
+	IntegratedAddressLength = 187
+	PaymentIDLength         = 64
+)
+
+// keySize is the size in bytes of a public key
+const keySize = 32
+
+// ErrInvalidAddress is returned when a string is not a TurtleCoin address
+var ErrInvalidAddress = errors.New("Invalid address")
+
+// Address is a decoded address. The keys are hex encoded and
+// PaymentID is empty unless the address is an integrated address.
+type Address struct {
+	Prefix         uint64
+	PublicSpendKey string
+	PublicViewKey  string
+	PaymentID      string
+}
+
+// ParseAddress decodes a standard address and checks its checksum
+// and prefix. Integrated addresses are rejected, see ParseIntegratedAddress.
+func ParseAddress(address string) (*Address, error) {
+	if len(address) == IntegratedAddressLength {
+		return nil, errors.New("Address is an integrated address")
+	}
+	if len(address) != AddressLength {
+		return nil, errors.New("Address must be " + strconv.Itoa(AddressLength) + " characters long")
+	}
+	return parseAddress(address)
+}
+
+// ParseIntegratedAddress decodes an integrated address and checks its
+// checksum, prefix and payment ID. Standard addresses are rejected.
+func ParseIntegratedAddress(address string) (*Address, error) {
+	if len(address) != IntegratedAddressLength {
+		return nil, errors.New("Integrated address must be " + strconv.Itoa(IntegratedAddressLength) + " characters long")
+	}
+	return parseAddress(address)
+}
+
+// NewIntegratedAddress encodes the payment ID into a standard address,
+// giving the same integrated address as the wallets do
+func NewIntegratedAddress(address string, paymentID string) (string, error) {
+	decoded, err := ParseAddress(address)
+	if err != nil {
+		return "", err
+	}
+	if !isPaymentID(paymentID) {
+		return "", errors.New("Payment ID must be " + strconv.Itoa(PaymentIDLength) + " hex characters")
+	}
+
+	decoded.PaymentID = paymentID
+	return decoded.String(), nil
+}
+
+// NewPaymentID returns a random payment ID from crypto/rand
+func NewPaymentID() (string, error) {
+	id := make([]byte, PaymentIDLength/2)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// String returns the base58 encoding of the address,
+// integrated if the address has a payment ID
+func (address *Address) String() string {
+	spendKey, _ := hex.DecodeString(address.PublicSpendKey)
+	viewKey, _ := hex.DecodeString(address.PublicViewKey)
+
+	data := append([]byte(address.PaymentID), spendKey...)
+	return encodeAddressData(address.Prefix, append(data, viewKey...))
+}
+
+// parseAddress decodes a standard or integrated address
+func parseAddress(address string) (*Address, error) {
+	prefix, data, err := decodeAddressData(address)
+	if err != nil {
+		return nil, err
+	}
+	if prefix != AddressPrefix {
+		return nil, errors.New("Address prefix is not the TRTL prefix")
+	}
+
+	// Integrated addresses carry the payment ID as hex
+	// characters in front of the keys
+	var paymentID string
+	switch len(data) {
+	case 2 * keySize:
+	case PaymentIDLength + 2*keySize:
+		paymentID = string(data[:PaymentIDLength])
+		if !isPaymentID(paymentID) {
+			return nil, errors.New("Invalid payment ID in the address")
+		}
+		data = data[PaymentIDLength:]
+	default:
+		return nil, ErrInvalidAddress
+	}
+
+	return &Address{
+		Prefix:         prefix,
+		PublicSpendKey: hex.EncodeToString(data[:keySize]),
+		PublicViewKey:  hex.EncodeToString(data[keySize:]),
+		PaymentID:      paymentID,
+	}, nil
+}
+
+// isPaymentID reports whether the string is a 64 character hex payment ID
+func isPaymentID(paymentID string) bool {
+	if len(paymentID) != PaymentIDLength {
+		return false
+	}
+	_, err := hex.DecodeString(paymentID)
+	return err == nil
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package turtlecoinrpc
+
+import "testing"
+
+// addressVectors are addresses used on the network with their keys
+var addressVectors = []struct {
+	address  string
+	spendKey string
+	viewKey  string
+}{
+	{
+		address:  "TRTLv1Hqo3wHdqLRXuCyX3MwvzKyxzwXeBtycnkDy8ceFp4E23bm3P467xLEbUusH6Q1mqQUBiYwJ2yULJbvr5nKe8kcyc4uyps",
+		spendKey: "95351a6263745afa66fcebe67d3c0386d55bf0e14124ce01abc9322f588d0e22",
+		viewKey:  "e9dc95471e9c1fd6f847047d8996734e8ee5ee510bccb4fb5abd94ccde01f3cd",
+	},
+	{
+		address:  "TRTLuxN6FVALYxeAEKhtWDYNS9Vd9dHVp3QHwjKbo76ggQKgUfVjQp8iPypECCy3MwZVyu89k1fWE2Ji6EKedbrqECHHWouZN6g",
+		spendKey: "22b5375174e34f40e73c250abb8bd711b96713670e5bb0547e6c9031eb91ddf6",
+		viewKey:  "35636d39f77de05c01c3cc16c24d87653dc3766707cdd21a4c0a8d024ee27e2c",
+	},
+}
+
+// integratedVectors are integrated addresses of the address vectors.
+// No integrated address made by wallet-api or turtle-service could be
+// checked, so they were computed by an implementation of the layout
+// independent of this package, checked against the address vectors.
+// The payment ID is the Keccak hash of nothing.
+var integratedVectors = []struct {
+	address    string
+	paymentID  string
+	integrated string
+}{
+	{
+		address:    addressVectors[0].address,
+		paymentID:  "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+		integrated: "TRTLuz1iWaV9jX5fsuMy6N9Q1AnwZAZcGAGNdianjwLJ94cEZwf35S7HRn4ofdVueZ9Q9xiJtFcCxHmFmJhj6uUYHG11pZqnXLMHdqLRXuCyX3MwvzKyxzwXeBtycnkDy8ceFp4E23bm3P467xLEbUusH6Q1mqQUBiYwJ2yULJbvr5nKe8kcyheNhrC",
+	},
+}
+
+func TestParseAddress(t *testing.T) {
+	for _, vector := range addressVectors {
+		address, err := ParseAddress(vector.address)
+		if err != nil {
+			t.Errorf("%.12s: %v", vector.address, err)
+			continue
+		}
+		if address.Prefix != AddressPrefix || address.PaymentID != "" {
+			t.Errorf("%.12s: prefix %d, payment ID %q", vector.address, address.Prefix, address.PaymentID)
+		}
+		if address.PublicSpendKey != vector.spendKey || address.PublicViewKey != vector.viewKey {
+			t.Errorf("%.12s: keys = %s, %s", vector.address, address.PublicSpendKey, address.PublicViewKey)
+		}
+		if address.String() != vector.address {
+			t.Errorf("%.12s: encoded as %s", vector.address, address.String())
+		}
+	}
+}
+
+func TestNewIntegratedAddress(t *testing.T) {
+	for _, vector := range integratedVectors {
+		integrated, err := NewIntegratedAddress(vector.address, vector.paymentID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(integrated) != IntegratedAddressLength {
+			t.Errorf("%.12s: %d characters", vector.address, len(integrated))
+		}
+		if integrated != vector.integrated {
+			t.Errorf("%.12s: integrated address = %s, want %s", vector.address, integrated, vector.integrated)
+		}
+	}
+}
+
+func TestParseIntegratedAddress(t *testing.T) {
+	for i, vector := range integratedVectors {
+		address, err := ParseIntegratedAddress(vector.integrated)
+		if err != nil {
+			t.Errorf("%.12s: %v", vector.integrated, err)
+			continue
+		}
+		if address.PaymentID != vector.paymentID {
+			t.Errorf("%.12s: payment ID = %s, want %s", vector.integrated, address.PaymentID, vector.paymentID)
+		}
+		if address.PublicSpendKey != addressVectors[i].spendKey || address.PublicViewKey != addressVectors[i].viewKey {
+			t.Errorf("%.12s: keys = %s, %s", vector.integrated, address.PublicSpendKey, address.PublicViewKey)
+		}
+
+		if _, err = ParseAddress(vector.integrated); err == nil {
+			t.Errorf("%.12s: parsed as a standard address", vector.integrated)
+		}
+		if _, err = ParseIntegratedAddress(vector.address); err == nil {
+			t.Errorf("%.12s: parsed as an integrated address", vector.address)
+		}
+	}
+}
+
+func TestAddressChecksum(t *testing.T) {
+	for _, vector := range addressVectors {
+		// Changing a character of the last block breaks the checksum
+		// while keeping a valid base58 block
+		b := []byte(vector.address)
+		if b[len(b)-2] == 'a' {
+			b[len(b)-2] = 'b'
+		} else {
+			b[len(b)-2] = 'a'
+		}
+		if _, err := ParseAddress(string(b)); err == nil {
+			t.Errorf("%s parsed", b)
+		}
+	}
+}
+
+func TestNewIntegratedAddressErrors(t *testing.T) {
+	for _, paymentID := range []string{"", "abc", integratedVectors[0].paymentID + "00", "z" + integratedVectors[0].paymentID[1:]} {
+		if _, err := NewIntegratedAddress(addressVectors[0].address, paymentID); err == nil {
+			t.Errorf("payment ID %q accepted", paymentID)
+		}
+	}
+	if _, err := NewIntegratedAddress(integratedVectors[0].integrated, integratedVectors[0].paymentID); err == nil {
+		t.Error("integrated address accepted as the standard address")
+	}
+}
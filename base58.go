@@ -0,0 +1,144 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package turtlecoinrpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// CryptoNote base58 encodes the data in blocks of 8 bytes, each block
+// taking 11 characters. The last block may be shorter and takes the
+// number of characters given by encodedBlockSizes.
+const (
+	base58Alphabet      = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	fullBlockSize       = 8
+	fullEncodedSize     = 11
+	addressChecksumSize = 4
+)
+
+var encodedBlockSizes = []int{0, 2, 3, 5, 6, 7, 9, 10, 11}
+
+// ErrInvalidBase58 is returned when a string is not valid CryptoNote base58
+var ErrInvalidBase58 = errors.New("Invalid base58 string")
+
+// base58Encode encodes data with the CryptoNote base58 block encoding
+func base58Encode(data []byte) string {
+	var encoded strings.Builder
+	for len(data) > 0 {
+		size := fullBlockSize
+		if len(data) < size {
+			size = len(data)
+		}
+
+		var block [fullEncodedSize]byte
+		var num uint64
+		for _, b := range data[:size] {
+			num = num<<8 | uint64(b)
+		}
+
+		chars := encodedBlockSizes[size]
+		for i := chars - 1; i >= 0; i-- {
+			block[i] = base58Alphabet[num%58]
+			num /= 58
+		}
+
+		encoded.Write(block[:chars])
+		data = data[size:]
+	}
+	return encoded.String()
+}
+
+// base58Decode decodes a CryptoNote base58 string
+func base58Decode(encoded string) ([]byte, error) {
+	var data []byte
+	for len(encoded) > 0 {
+		chars := fullEncodedSize
+		if len(encoded) < chars {
+			chars = len(encoded)
+		}
+
+		size := -1
+		for i, encodedSize := range encodedBlockSizes {
+			if encodedSize == chars {
+				size = i
+			}
+		}
+		if size < 0 {
+			return nil, ErrInvalidBase58
+		}
+
+		var num uint64
+		for i := 0; i < chars; i++ {
+			digit := strings.IndexByte(base58Alphabet, encoded[i])
+			if digit < 0 {
+				return nil, ErrInvalidBase58
+			}
+
+			// Catch a block encoding more than 64 bits
+			hi, lo := bits.Mul64(num, 58)
+			if hi != 0 || lo+uint64(digit) < lo {
+				return nil, ErrInvalidBase58
+			}
+			num = lo + uint64(digit)
+		}
+
+		// Catch a short block encoding more bytes than its size
+		if size < fullBlockSize && num>>(8*uint(size)) != 0 {
+			return nil, ErrInvalidBase58
+		}
+
+		var block [fullBlockSize]byte
+		binary.BigEndian.PutUint64(block[:], num)
+		data = append(data, block[fullBlockSize-size:]...)
+		encoded = encoded[chars:]
+	}
+	return data, nil
+}
+
+// keccak256 returns the original keccak hash used by CryptoNote,
+// which differs from the standardized SHA3-256 in its padding
+func keccak256(data ...[]byte) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		hash.Write(d)
+	}
+	return hash.Sum(nil)
+}
+
+// encodeAddressData encodes the prefix varint and the data
+// followed by their checksum as a CryptoNote address
+func encodeAddressData(prefix uint64, data []byte) string {
+	raw := binary.AppendUvarint(nil, prefix)
+	raw = append(raw, data...)
+	raw = append(raw, keccak256(raw)[:addressChecksumSize]...)
+	return base58Encode(raw)
+}
+
+// decodeAddressData decodes a CryptoNote address into its prefix
+// and data after checking its checksum
+func decodeAddressData(address string) (uint64, []byte, error) {
+	raw, err := base58Decode(address)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(raw) <= addressChecksumSize {
+		return 0, nil, ErrInvalidAddress
+	}
+
+	body, checksum := raw[:len(raw)-addressChecksumSize], raw[len(raw)-addressChecksumSize:]
+	if string(keccak256(body)[:addressChecksumSize]) != string(checksum) {
+		return 0, nil, errors.New("Invalid address checksum")
+	}
+
+	prefix, n := binary.Uvarint(body)
+	if n <= 0 {
+		return 0, nil, ErrInvalidAddress
+	}
+	return prefix, body[n:], nil
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package turtlecoinrpc
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"strconv"
+)
+
+// BlockWorkers is the number of windows of blocks
+// fetched concurrently by the Blocks iterator
+var BlockWorkers = 4
+
+// RangeBlock is a block yielded by the Blocks iterator. Block is
+// the full f_block_json result and Done out of Total tells how
+// far the iteration went, counting this block.
+type RangeBlock struct {
+	BlockHeader
+	Block map[string]interface{}
+	Done  int
+	Total int
+}
+
+// blockWindow is the result of fetching one window of blocks
+type blockWindow struct {
+	blocks []RangeBlock
+	err    error
+}
+
+/*
+Blocks method returns an iterator over the details of the blocks from height from
+to height to, both included, in ascending order. A negative to stands for the top
+block at the time of the call. Windows of blocks are fetched by BlockWorkers
+goroutines and yielded in order. An iteration is resumed by calling Blocks again
+from the height after the last yielded block. The iteration stops after yielding
+an error, which happens when a request fails, the context is cancelled or the
+chain reorganizes between two yielded blocks.
+*/
+func (daemon *TurtleCoind) Blocks(ctx context.Context, from int, to int) iter.Seq2[*RangeBlock, error] {
+	return func(yield func(*RangeBlock, error) bool) {
+		if to < 0 {
+			top, err := daemon.blockHeaderByHeight(ctx, -1)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			to = top.Height
+		}
+		if from > to {
+			return
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		workers := BlockWorkers
+		if workers < 1 {
+			workers = 1
+		}
+
+		// Each window gets its own channel queued in order. The
+		// window being yielded is out of the queue, so the queue
+		// holds one less window than the number fetched at once.
+		queue := make(chan chan blockWindow, workers-1)
+		go func() {
+			defer close(queue)
+			for first := from; first <= to; first += blocksPerRequest {
+				last := first + blocksPerRequest - 1
+				if last > to {
+					last = to
+				}
+
+				result := make(chan blockWindow, 1)
+				select {
+				case queue <- result:
+				case <-ctx.Done():
+					return
+				}
+				go func(first int, last int) {
+					blocks, err := daemon.blockWindow(ctx, first, last)
+					result <- blockWindow{blocks, err}
+				}(first, last)
+			}
+		}()
+
+		total := to - from + 1
+		done := 0
+		prev := ""
+		for result := range queue {
+			var window blockWindow
+			select {
+			case window = <-result:
+			case <-ctx.Done():
+				yield(nil, ctx.Err())
+				return
+			}
+			if window.err != nil {
+				// Requests cut short by the context fail with it
+				if err := ctx.Err(); err != nil {
+					window.err = err
+				}
+				yield(nil, window.err)
+				return
+			}
+
+			for i := range window.blocks {
+				// Blocks fetched before a cancellation are not yielded
+				if err := ctx.Err(); err != nil {
+					yield(nil, err)
+					return
+				}
+
+				block := &window.blocks[i]
+				if prev != "" && block.PrevHash != prev {
+					yield(nil, errors.New("Chain reorganized below block "+strconv.Itoa(block.Height)))
+					return
+				}
+				prev = block.Hash
+
+				done++
+				block.Done = done
+				block.Total = total
+				if !yield(block, nil) {
+					return
+				}
+			}
+		}
+
+		if err := ctx.Err(); err != nil && done < total {
+			yield(nil, err)
+		}
+	}
+}
+
+// blockWindow returns the details of the blocks from first to last
+func (daemon *TurtleCoind) blockWindow(ctx context.Context, first int, last int) ([]RangeBlock, error) {
+	hashes, err := daemon.blockHashes(ctx, first, last)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]RangeBlock, 0, last-first+1)
+	for height := first; height <= last; height++ {
+		hash, ok := hashes[height]
+		if !ok {
+			return nil, errors.New("Missing block " + strconv.Itoa(height) + " in the response")
+		}
+
+		block, header, err := daemon.blockDetails(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, RangeBlock{BlockHeader: header, Block: block})
+	}
+	return blocks, nil
+}
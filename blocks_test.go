@@ -0,0 +1,224 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package turtlecoinrpc_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+)
+
+// countingTransport counts the requests made with a live context and
+// the most made at once, calling before with the number of each one
+type countingTransport struct {
+	delay  time.Duration
+	before func(n int)
+
+	mu          sync.Mutex
+	requests    int
+	inFlight    int
+	maxInFlight int
+}
+
+func (transport *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport.mu.Lock()
+	if err := req.Context().Err(); err != nil {
+		transport.mu.Unlock()
+		return nil, err
+	}
+	transport.requests++
+	n := transport.requests
+	transport.inFlight++
+	if transport.inFlight > transport.maxInFlight {
+		transport.maxInFlight = transport.inFlight
+	}
+	transport.mu.Unlock()
+
+	defer func() {
+		transport.mu.Lock()
+		transport.inFlight--
+		transport.mu.Unlock()
+	}()
+	if transport.before != nil {
+		transport.before(n)
+	}
+	time.Sleep(transport.delay)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (transport *countingTransport) counts() (int, int) {
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	return transport.requests, transport.maxInFlight
+}
+
+// withBlockWorkers sets BlockWorkers for the rest of the test
+func withBlockWorkers(t *testing.T, workers int) {
+	saved := trpc.BlockWorkers
+	trpc.BlockWorkers = workers
+	t.Cleanup(func() { trpc.BlockWorkers = saved })
+}
+
+// checkBlock fails unless the block is the one of the fake daemon at
+// the height, yielded as the done-th block out of total
+func checkBlock(t *testing.T, blocks []trpc.BlockHeader, block *trpc.RangeBlock, height int, done int, total int) {
+	t.Helper()
+	if block.Height != height || block.Hash != blocks[height].Hash || block.Block["hash"] != block.Hash {
+		t.Fatalf("block %d yielded as %d %s", height, block.Height, block.Hash)
+	}
+	if block.Done != done || block.Total != total {
+		t.Fatalf("block %d yielded as %d of %d, want %d of %d", height, block.Done, block.Total, done, total)
+	}
+}
+
+// headers returns the headers of the blocks of the fake daemon
+func headers(t *testing.T, client *trpc.TurtleCoind, count int) []trpc.BlockHeader {
+	t.Helper()
+	var blocks []trpc.BlockHeader
+	for block, err := range client.Blocks(context.Background(), 0, count-1) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, block.BlockHeader)
+	}
+	return blocks
+}
+
+func TestBlocksOrder(t *testing.T) {
+	withBlockWorkers(t, 4)
+	fake, client := fakeDaemon(t, 150)
+	blocks := headers(t, client, fake.Height())
+	transport := &countingTransport{delay: time.Millisecond}
+	client.Transport = transport
+
+	// The top block is the end of the iteration
+	height := 3
+	for block, err := range client.Blocks(context.Background(), 3, -1) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkBlock(t, blocks, block, height, height-2, 148)
+		height++
+	}
+	if height != 151 {
+		t.Errorf("iteration ended before block %d", height)
+	}
+
+	if _, maxInFlight := transport.counts(); maxInFlight < 2 || maxInFlight > 4 {
+		t.Errorf("%d windows fetched at once, want 2 to 4", maxInFlight)
+	}
+}
+
+func TestBlocksResume(t *testing.T) {
+	withBlockWorkers(t, 2)
+	fake, client := fakeDaemon(t, 70)
+	blocks := headers(t, client, fake.Height())
+	ctx := context.Background()
+
+	last := -1
+	for block, err := range client.Blocks(ctx, 0, 70) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkBlock(t, blocks, block, block.Done-1, block.Done, 71)
+		last = block.Height
+		if last == 40 {
+			break
+		}
+	}
+
+	height := last + 1
+	for block, err := range client.Blocks(ctx, last+1, 70) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkBlock(t, blocks, block, height, height-last, 70-last)
+		height++
+	}
+	if height != 71 {
+		t.Errorf("resumed iteration ended before block %d", height)
+	}
+
+	for range client.Blocks(ctx, 71, 70) {
+		t.Error("block yielded past the end")
+	}
+}
+
+func TestBlocksBreak(t *testing.T) {
+	withBlockWorkers(t, 4)
+	_, client := fakeDaemon(t, 200)
+	transport := &countingTransport{delay: 2 * time.Millisecond}
+	client.Transport = transport
+
+	for _, err := range client.Blocks(context.Background(), 0, 200) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		break
+	}
+
+	// The workers stop their requests once the iteration returns
+	requests, _ := transport.counts()
+	time.Sleep(50 * time.Millisecond)
+	if after, _ := transport.counts(); after != requests {
+		t.Errorf("%d requests made after the iteration returned", after-requests)
+	}
+}
+
+func TestBlocksCancel(t *testing.T) {
+	_, client := fakeDaemon(t, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client.Transport = &countingTransport{delay: time.Millisecond}
+
+	// No block is yielded once the context is cancelled
+	done := 0
+	var err error
+	for block, e := range client.Blocks(ctx, 0, 100) {
+		if e != nil {
+			err = e
+			continue
+		}
+		done = block.Done
+		if done == 10 {
+			cancel()
+		}
+	}
+	if done != 10 || err != context.Canceled {
+		t.Errorf("%d blocks yielded, err = %v, want 10 and %v", done, err, context.Canceled)
+	}
+}
+
+func TestBlocksReorg(t *testing.T) {
+	withBlockWorkers(t, 1)
+	fake, client := fakeDaemon(t, 70)
+
+	// The chain reorganizes when the second window is fetched,
+	// once the first window is fetched with its 31 requests
+	client.Transport = &countingTransport{before: func(n int) {
+		if n == 32 {
+			fake.Orphan(20)
+		}
+	}}
+
+	var heights []int
+	var err error
+	for block, e := range client.Blocks(context.Background(), 1, 70) {
+		if e != nil {
+			err = e
+			break
+		}
+		heights = append(heights, block.Height)
+	}
+	if len(heights) != 30 || heights[29] != 30 {
+		t.Errorf("heights %v yielded, want 1 to 30", heights)
+	}
+	if err == nil || err.Error() != "Chain reorganized below block 31" {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+)
+
+// config holds the connection settings of the three services
+type config struct {
+	DaemonHost string `json:"daemon-host"`
+	DaemonPort int    `json:"daemon-port"`
+
+	WalletdHost     string `json:"walletd-host"`
+	WalletdPort     int    `json:"walletd-port"`
+	WalletdPassword string `json:"walletd-password"`
+
+	WalletAPIHost      string `json:"wallet-api-host"`
+	WalletAPIPort      int    `json:"wallet-api-port"`
+	WalletAPIPassword  string `json:"wallet-api-password"`
+	WalletAPIDaemonSSL bool   `json:"wallet-api-daemon-ssl"`
+
+	Output string `json:"output"`
+}
+
+// setting ties a config field to its flag and environment variable
+type setting struct {
+	name  string
+	usage string
+	value interface{}
+}
+
+func (cfg *config) settings() []setting {
+	return []setting{
+		{"daemon-host", "TurtleCoind host", &cfg.DaemonHost},
+		{"daemon-port", "TurtleCoind port", &cfg.DaemonPort},
+		{"walletd-host", "turtle-service host", &cfg.WalletdHost},
+		{"walletd-port", "turtle-service port", &cfg.WalletdPort},
+		{"walletd-password", "turtle-service RPC password", &cfg.WalletdPassword},
+		{"wallet-api-host", "wallet-api host", &cfg.WalletAPIHost},
+		{"wallet-api-port", "wallet-api port", &cfg.WalletAPIPort},
+		{"wallet-api-password", "wallet-api RPC password (X-API-KEY)", &cfg.WalletAPIPassword},
+		{"wallet-api-daemon-ssl", "connect wallet-api to the node over SSL", &cfg.WalletAPIDaemonSSL},
+		{"output", "output format: json, table or csv", &cfg.Output},
+	}
+}
+
+// envName returns the environment variable of a setting,
+// daemon-host is read from TRTL_RPC_DAEMON_HOST
+func envName(name string) string {
+	return "TRTL_RPC_" + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+func defaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".trtl-rpc.json")
+}
+
+// loadConfig parses the global flags in args and returns the
+// resulting config along with the remaining arguments
+func loadConfig(args []string) (*config, []string, error) {
+	cfg := &config{Output: "json"}
+
+	fs := flag.NewFlagSet("trtl-rpc", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path of the JSON config file (default ~/.trtl-rpc.json, env TRTL_RPC_CONFIG)")
+
+	// The flag values are parsed into a scratch config so that
+	// only the flags given on the command line override the
+	// config file and environment
+	flags := &config{}
+	for _, s := range flags.settings() {
+		usage := s.usage + " (env " + envName(s.name) + ")"
+		switch v := s.value.(type) {
+		case *string:
+			fs.StringVar(v, s.name, "", usage)
+		case *int:
+			fs.IntVar(v, s.name, 0, usage)
+		case *bool:
+			fs.BoolVar(v, s.name, false, usage)
+		}
+	}
+	fs.Usage = func() {
+		usage()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Global flags:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	path := *configPath
+	if path == "" {
+		path = os.Getenv("TRTL_RPC_CONFIG")
+	}
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err == nil {
+			if err = json.Unmarshal(data, cfg); err != nil {
+				return nil, nil, errors.New("invalid config file " + path + ": " + err.Error())
+			}
+		} else if explicit || !os.IsNotExist(err) {
+			return nil, nil, err
+		}
+	}
+
+	for _, s := range cfg.settings() {
+		env, ok := os.LookupEnv(envName(s.name))
+		if !ok {
+			continue
+		}
+		if err := setValue(s.value, env); err != nil {
+			return nil, nil, errors.New(envName(s.name) + ": " + err.Error())
+		}
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	flagSettings := flags.settings()
+	for i, s := range cfg.settings() {
+		if !set[s.name] {
+			continue
+		}
+		switch v := s.value.(type) {
+		case *string:
+			*v = *flagSettings[i].value.(*string)
+		case *int:
+			*v = *flagSettings[i].value.(*int)
+		case *bool:
+			*v = *flagSettings[i].value.(*bool)
+		}
+	}
+
+	switch cfg.Output {
+	case "json", "table", "csv":
+	default:
+		return nil, nil, errors.New("unknown output format " + cfg.Output)
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+func setValue(value interface{}, s string) error {
+	switch v := value.(type) {
+	case *string:
+		*v = s
+	case *int:
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		*v = i
+	case *bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		*v = b
+	}
+	return nil
+}
+
+func (cfg *config) daemon() *trpc.TurtleCoind {
+	return &trpc.TurtleCoind{
+		URL:  cfg.DaemonHost,
+		Port: cfg.DaemonPort,
+	}
+}
+
+func (cfg *config) walletd() *trpc.Walletd {
+	return &trpc.Walletd{
+		URL:         cfg.WalletdHost,
+		Port:        cfg.WalletdPort,
+		RPCPassword: cfg.WalletdPassword,
+	}
+}
+
+func (cfg *config) walletAPI() *trpc.WalletAPI {
+	return &trpc.WalletAPI{
+		URL:         cfg.WalletAPIHost,
+		Port:        cfg.WalletAPIPort,
+		DaemonURL:   cfg.DaemonHost,
+		DaemonPort:  cfg.DaemonPort,
+		DaemonSSL:   cfg.WalletAPIDaemonSSL,
+		RPCPassword: cfg.WalletAPIPassword,
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a config file in a temporary home,
+// so the config of the user is never read
+func writeConfig(t *testing.T, content string) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TRTL_RPC_CONFIG", "")
+	path := filepath.Join(home, "config.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := writeConfig(t, `{"daemon-host": "file", "daemon-port": 1, "walletd-host": "file", "walletd-port": 1, "output": "csv"}`)
+	t.Setenv("TRTL_RPC_DAEMON_PORT", "2")
+	t.Setenv("TRTL_RPC_WALLETD_HOST", "env")
+	t.Setenv("TRTL_RPC_WALLETD_PORT", "2")
+	t.Setenv("TRTL_RPC_WALLET_API_DAEMON_SSL", "true")
+
+	cfg, args, err := loadConfig([]string{"-config", path, "-walletd-port", "3", "-output", "table", "daemon", "info"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := config{
+		DaemonHost:         "file",
+		DaemonPort:         2,
+		WalletdHost:        "env",
+		WalletdPort:        3,
+		WalletAPIDaemonSSL: true,
+		Output:             "table",
+	}
+	if *cfg != want {
+		t.Errorf("config = %+v, want %+v", *cfg, want)
+	}
+	if strings.Join(args, " ") != "daemon info" {
+		t.Errorf("args = %q", args)
+	}
+
+	// A flag set to its zero value still overrides
+	if cfg, _, err = loadConfig([]string{"-config", path, "-wallet-api-daemon-ssl=false"}); err != nil || cfg.WalletAPIDaemonSSL {
+		t.Errorf("config = %+v, err = %v", cfg, err)
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := writeConfig(t, `{"output": "table"}`)
+
+	// The config file is found in the environment, or in the home
+	t.Setenv("TRTL_RPC_CONFIG", path)
+	if cfg, _, err := loadConfig(nil); err != nil || cfg.Output != "table" {
+		t.Errorf("config from the environment = %+v, err = %v", cfg, err)
+	}
+	t.Setenv("TRTL_RPC_CONFIG", "")
+	if cfg, _, err := loadConfig(nil); err != nil || cfg.Output != "json" {
+		t.Errorf("config without a file = %+v, err = %v", cfg, err)
+	}
+
+	tests := []struct {
+		args []string
+		env  string
+		err  string
+	}{
+		{[]string{"-config", path + ".missing"}, "0", "no such file"},
+		{[]string{"-config", writeConfig(t, "{")}, "0", "invalid config file"},
+		{[]string{"-output", "xml"}, "0", "unknown output format xml"},
+		{nil, "x", "TRTL_RPC_DAEMON_PORT: "},
+	}
+	for _, test := range tests {
+		t.Setenv("TRTL_RPC_DAEMON_PORT", test.env)
+		if _, _, err := loadConfig(test.args); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("loadConfig(%q): err = %v, want %q", test.args, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+)
+
+var daemonCommands = []command{
+	{"info", "network and connection information", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.daemon().Info() }
+	}},
+	{"height", "height of the blockchain", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.daemon().Height() }
+	}},
+	{"fee", "fee set by the node", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.daemon().Fee() }
+	}},
+	{"peers", "peers connected to the node", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.daemon().Peers() }
+	}},
+	{"blocks", "30 blocks down from a height", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		height := fs.Int("height", 0, "height of the first block")
+		return func() (interface{}, error) { return cfg.daemon().GetBlocks(*height) }
+	}},
+	{"block", "block details by hash", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		hash := fs.String("hash", "", "block hash")
+		return func() (interface{}, error) {
+			if *hash == "" {
+				return nil, errors.New("--hash is required")
+			}
+			return cfg.daemon().GetBlock(*hash)
+		}
+	}},
+	{"transaction", "transaction details by hash", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		hash := fs.String("hash", "", "transaction hash")
+		return func() (interface{}, error) {
+			if *hash == "" {
+				return nil, errors.New("--hash is required")
+			}
+			return cfg.daemon().GetTransaction(*hash)
+		}
+	}},
+	{"pool", "transactions in the mempool", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.daemon().GetTransactionPool() }
+	}},
+	{"block-count", "height of the top block", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.daemon().GetBlockCount() }
+	}},
+	{"block-hash", "block hash by height", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		height := fs.Int("height", 0, "block height")
+		return func() (interface{}, error) { return cfg.daemon().GetBlockHash(*height) }
+	}},
+	{"block-template", "block template for mining", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		reserveSize := fs.Int("reserve-size", 0, "size of the reserved space in bytes")
+		address := fs.String("address", "", "wallet address receiving the reward")
+		return func() (interface{}, error) {
+			if *address == "" {
+				return nil, errors.New("--address is required")
+			}
+			return cfg.daemon().GetBlockTemplate(*reserveSize, *address)
+		}
+	}},
+	{"currency-id", "currency id of the network", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.daemon().GetCurrencyID() }
+	}},
+	{"submit-block", "submit a block blob", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		blob := fs.String("blob", "", "hex encoded block blob")
+		return func() (interface{}, error) {
+			if *blob == "" {
+				return nil, errors.New("--blob is required")
+			}
+			return cfg.daemon().SubmitBlock(*blob)
+		}
+	}},
+	{"last-block-header", "header of the last block", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.daemon().GetLastBlockHeader() }
+	}},
+	{"block-header", "block header by hash or height", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		hash := fs.String("hash", "", "block hash")
+		height := fs.Int("height", -1, "block height")
+		return func() (interface{}, error) {
+			switch {
+			case *hash != "" && *height >= 0:
+				return nil, errors.New("--hash and --height cannot be given together")
+			case *hash != "":
+				return cfg.daemon().GetBlockHeaderByHash(*hash)
+			case *height >= 0:
+				return cfg.daemon().GetBlockHeaderByHeight(*height)
+			}
+			return nil, errors.New("--hash or --height is required")
+		}
+	}},
+	{"wallet-sync-data", "blocks for wallet scanning", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		var checkpoints stringList
+		fs.Var(&checkpoints, "checkpoint", "block hash checkpoint, newest first, can be repeated")
+		startHeight := fs.Int("start-height", 0, "height to start from when no checkpoint is known")
+		startTimestamp := fs.Int("start-timestamp", 0, "timestamp to start from when no checkpoint is known")
+		blockCount := fs.Int("count", 100, "number of blocks")
+		skipCoinbase := fs.Bool("skip-coinbase", false, "leave out the coinbase transactions")
+		return func() (interface{}, error) {
+			return cfg.daemon().GetWalletSyncData(checkpoints, *startHeight, *startTimestamp, *blockCount, *skipCoinbase)
+		}
+	}},
+	{"query-blocks-lite", "blocks with their transaction prefixes", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		var checkpoints stringList
+		fs.Var(&checkpoints, "checkpoint", "block hash checkpoint, newest first, can be repeated")
+		timestamp := fs.Int("timestamp", 0, "timestamp to start from when no checkpoint is known")
+		return func() (interface{}, error) { return cfg.daemon().QueryBlocksLite(checkpoints, *timestamp) }
+	}},
+	{"global-indexes", "global output indexes of a range of blocks", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		start := fs.Int("start", 0, "start height")
+		end := fs.Int("end", 0, "end height")
+		return func() (interface{}, error) { return cfg.daemon().GetGlobalIndexesForRange(*start, *end) }
+	}},
+	{"checkpoints", "sparse block hash checkpoints down from a height", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		height := fs.Int("height", 0, "height of the newest checkpoint")
+		return func() (interface{}, error) { return cfg.daemon().BlockHashCheckpoints(*height) }
+	}},
+	{"send-raw-transaction", "broadcast a hex encoded transaction", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		blob := fs.String("blob", "", "hex encoded transaction blob")
+		return func() (interface{}, error) { return nil, cfg.daemon().SendRawTransaction(context.Background(), *blob) }
+	}},
+	{"transactions-status", "pool, block or unknown state of transactions", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		var hashes stringList
+		fs.Var(&hashes, "hash", "transaction hash, can be repeated")
+		return func() (interface{}, error) { return cfg.daemon().TransactionsStatus(context.Background(), hashes) }
+	}},
+	{"wait-transaction", "wait until a transaction has enough confirmations", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		hash := fs.String("hash", "", "transaction hash")
+		confirmations := fs.Int("confirmations", 1, "number of confirmations to wait for")
+		return func() (interface{}, error) {
+			height, err := cfg.daemon().WaitForTransaction(context.Background(), *hash, *confirmations)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"hash": *hash, "blockHeight": height}, nil
+		}
+	}},
+	{"pool-changes", "pool changes since a tail block", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		tail := fs.String("tail", "", "hash of the tail block")
+		var known stringList
+		fs.Var(&known, "known", "known transaction hash, can be repeated")
+		return func() (interface{}, error) {
+			return cfg.daemon().GetPoolChangesLite(context.Background(), *tail, known)
+		}
+	}},
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"strconv"
+	"strings"
+)
+
+// decimalPlaces is the number of decimal places of a TRTL amount
+const decimalPlaces = 2
+
+// stringList is a flag which can be given several times
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+// secret is a string flag holding a password, a private key or a
+// seed. The shell prompts for it without echo when it is not given,
+// and keeps the lines setting it out of its history.
+type secret string
+
+func (s *secret) String() string {
+	return string(*s)
+}
+
+func (s *secret) Set(value string) error {
+	*s = secret(value)
+	return nil
+}
+
+// secretString registers a secret flag
+func secretString(fs *flag.FlagSet, name, usage string) *string {
+	value := new(string)
+	fs.Var((*secret)(value), name, usage)
+	return value
+}
+
+// amount is a flag holding a TRTL amount such as 12.34,
+// stored in atomic units
+type amount int
+
+func (a *amount) String() string {
+	return formatAmount(int(*a))
+}
+
+func (a *amount) Set(value string) error {
+	atomic, err := parseAmount(value)
+	if err != nil {
+		return err
+	}
+	*a = amount(atomic)
+	return nil
+}
+
+// parseAmount converts a decimal TRTL amount, made of digits with
+// an optional decimal point, into atomic units without going
+// through floating point
+func parseAmount(value string) (int, error) {
+	whole, fraction := value, ""
+	if i := strings.Index(value, "."); i >= 0 {
+		whole, fraction = value[:i], value[i+1:]
+	}
+	// Signs are rejected here as Atoi would take them
+	if whole+fraction == "" || strings.Trim(whole+fraction, "0123456789") != "" {
+		return 0, errors.New("invalid amount " + value)
+	}
+	if len(fraction) > decimalPlaces {
+		return 0, errors.New("amount " + value + " has more than " + strconv.Itoa(decimalPlaces) + " decimal places")
+	}
+	fraction += strings.Repeat("0", decimalPlaces-len(fraction))
+	if whole == "" {
+		whole = "0"
+	}
+
+	atomic, err := strconv.Atoi(whole + fraction)
+	if err != nil || atomic < 0 {
+		return 0, errors.New("invalid amount " + value)
+	}
+	return atomic, nil
+}
+
+func formatAmount(atomic int) string {
+	// The sign is formatted apart, or a negative amount would be
+	// split inside its digits
+	sign, magnitude := "", uint64(atomic)
+	if atomic < 0 {
+		sign, magnitude = "-", -magnitude
+	}
+	s := strconv.FormatUint(magnitude, 10)
+	if len(s) <= decimalPlaces {
+		s = strings.Repeat("0", decimalPlaces-len(s)+1) + s
+	}
+	return sign + s[:len(s)-decimalPlaces] + "." + s[len(s)-decimalPlaces:]
+}
+
+// parseDestinations turns address:amount pairs into the
+// destination objects expected by the send methods
+func parseDestinations(pairs []string) ([]map[string]interface{}, error) {
+	destinations := make([]map[string]interface{}, 0, len(pairs))
+	for _, pair := range pairs {
+		i := strings.LastIndex(pair, ":")
+		if i < 0 {
+			return nil, errors.New("destination " + pair + " is not in the form address:amount")
+		}
+		atomic, err := parseAmount(pair[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		destinations = append(destinations, map[string]interface{}{
+			"address": pair[:i],
+			"amount":  atomic,
+		})
+	}
+	return destinations, nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package main
+
+import "testing"
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value  string
+		atomic int
+		valid  bool
+	}{
+		{"12.34", 1234, true},
+		{"12", 1200, true},
+		{"12.3", 1230, true},
+		{".5", 50, true},
+		{"5.", 500, true},
+		{"0.01", 1, true},
+		{"007", 700, true},
+		{"1.234", 0, false},
+		{"0.001", 0, false},
+		{"-1", 0, false},
+		{"-0.5", 0, false},
+		{"+1", 0, false},
+		{"+.5", 0, false},
+		{"1.+5", 0, false},
+		{"", 0, false},
+		{".", 0, false},
+		{"1e3", 0, false},
+		{"1,5", 0, false},
+		{"99999999999999999999", 0, false},
+	}
+	for _, test := range tests {
+		atomic, err := parseAmount(test.value)
+		if (err == nil) != test.valid || atomic != test.atomic {
+			t.Errorf("parseAmount(%q) = %d, %v", test.value, atomic, err)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		atomic int
+		value  string
+	}{
+		{0, "0.00"},
+		{1, "0.01"},
+		{10, "0.10"},
+		{100, "1.00"},
+		{1234, "12.34"},
+		{-1, "-0.01"},
+		{-50, "-0.50"},
+		{-1234, "-12.34"},
+	}
+	for _, test := range tests {
+		if value := formatAmount(test.atomic); value != test.value {
+			t.Errorf("formatAmount(%d) = %s, want %s", test.atomic, value, test.value)
+		}
+		if test.atomic >= 0 {
+			if atomic, err := parseAmount(test.value); err != nil || atomic != test.atomic {
+				t.Errorf("parseAmount(%q) = %d, %v, want %d", test.value, atomic, err, test.atomic)
+			}
+		}
+	}
+}
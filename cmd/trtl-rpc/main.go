@@ -0,0 +1,168 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+// Command trtl-rpc exposes every TurtleCoind, Walletd and WalletAPI
+// method of the turtlecoinrpc package as a subcommand.
+//
+// Usage:
+//
+//	trtl-rpc [global flags] <group> <command> [flags]
+//
+// The groups are daemon, walletd and wallet-api, for example
+//
+//	trtl-rpc daemon block --hash <hash>
+//	trtl-rpc wallet-api send basic --to <address> --amount 12.34
+//	trtl-rpc -output table walletd get-balance
+//
+// trtl-rpc shell starts an interactive session with the wallet-api
+// commands and the daemon commands, tab completion of commands and
+// subwallet addresses, and confirmation before sends and resets.
+//
+// Connection settings are read from the config file, then from the
+// TRTL_RPC_* environment variables and finally from the global flags,
+// each overriding the previous one. Run trtl-rpc -h for the list.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// command is a single subcommand of a group. setup registers the
+// flags of the command and returns the function which runs it.
+type command struct {
+	name  string
+	help  string
+	setup func(fs *flag.FlagSet, cfg *config) func() (interface{}, error)
+}
+
+// group is a set of commands sharing a client
+type group struct {
+	name     string
+	help     string
+	commands []command
+}
+
+var groups = []group{
+	{"daemon", "TurtleCoind node methods", daemonCommands},
+	{"walletd", "turtle-service (walletd) methods", walletdCommands},
+	{"wallet-api", "wallet-api methods", walletAPICommands},
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "trtl-rpc:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	cfg, args, err := loadConfig(args)
+	if err == flag.ErrHelp {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		usage()
+		return errors.New("no group given")
+	}
+
+	if args[0] == "shell" {
+		if len(args) != 1 {
+			return errors.New("unexpected arguments " + strings.Join(args[1:], " "))
+		}
+		return runShell(cfg)
+	}
+
+	grp := findGroup(args[0])
+	if grp == nil {
+		usage()
+		return errors.New("unknown group " + args[0])
+	}
+
+	cmd, rest := findCommand(grp, args[1:])
+	if cmd == nil {
+		groupUsage(grp)
+		return errors.New("unknown command " + strings.Join(args[1:], " "))
+	}
+
+	fs := flag.NewFlagSet(grp.name+" "+cmd.name, flag.ContinueOnError)
+	runner := cmd.setup(fs, cfg)
+	if err = fs.Parse(rest); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errors.New("unexpected arguments " + strings.Join(fs.Args(), " "))
+	}
+
+	resp, err := runner()
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(os.Stdout, cfg.Output, resp)
+}
+
+func findGroup(name string) *group {
+	for i := range groups {
+		if groups[i].name == name {
+			return &groups[i]
+		}
+	}
+	return nil
+}
+
+// findCommand matches the longest command name made up
+// of the leading words of args, so "send fusion basic"
+// wins over "send" when both exist
+func findCommand(grp *group, args []string) (*command, []string) {
+	var found *command
+	var rest []string
+	for n := 1; n <= len(args); n++ {
+		if strings.HasPrefix(args[n-1], "-") {
+			break
+		}
+		name := strings.Join(args[:n], " ")
+		for i := range grp.commands {
+			if grp.commands[i].name == name {
+				found, rest = &grp.commands[i], args[n:]
+			}
+		}
+	}
+	return found, rest
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: trtl-rpc [global flags] <group> <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Groups:")
+	for _, grp := range groups {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", grp.name, grp.help)
+	}
+	fmt.Fprintf(os.Stderr, "  %-12s %s\n", "shell", "interactive wallet-api and node shell")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run trtl-rpc <group> to list its commands and trtl-rpc -h for the global flags.")
+}
+
+func groupUsage(grp *group) {
+	fmt.Fprintf(os.Stderr, "Usage: trtl-rpc [global flags] %s <command> [flags]\n", grp.name)
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	commands := append([]command(nil), grp.commands...)
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].name < commands[j].name
+	})
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-36s %s\n", cmd.name, cmd.help)
+	}
+}
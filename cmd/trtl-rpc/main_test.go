@@ -0,0 +1,38 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		name string
+		rest []string
+	}{
+		{[]string{"send", "fusion", "basic"}, "send fusion basic", nil},
+		{[]string{"send", "prepare", "advanced", "--to", "TRTL"}, "send prepare advanced", []string{"--to", "TRTL"}},
+		{[]string{"addresses", "import", "view", "--address", "a"}, "addresses import view", []string{"--address", "a"}},
+		{[]string{"addresses", "import", "--address", "a"}, "addresses import", []string{"--address", "a"}},
+		{[]string{"addresses", "--address", "import"}, "addresses", []string{"--address", "import"}},
+		{[]string{"keys", "mnemonic", "extra"}, "keys mnemonic", []string{"extra"}},
+		{[]string{"send"}, "", nil},
+		{[]string{"unknown", "send", "basic"}, "", nil},
+		{nil, "", nil},
+	}
+	grp := findGroup("wallet-api")
+	for _, test := range tests {
+		cmd, rest := findCommand(grp, test.args)
+		name := ""
+		if cmd != nil {
+			name = cmd.name
+		}
+		if name != test.name || strings.Join(rest, " ") != strings.Join(test.rest, " ") {
+			t.Errorf("findCommand(%q) = %q, %q", test.args, name, rest)
+		}
+	}
+}
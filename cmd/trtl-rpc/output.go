@@ -0,0 +1,188 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// writeOutput writes the response in the requested format
+func writeOutput(w io.Writer, format string, resp interface{}) error {
+	if resp == nil {
+		return nil
+	}
+
+	switch format {
+	case "table":
+		header, rows := tabulate(resp)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case "csv":
+		header, rows := tabulate(resp)
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		cw.WriteAll(rows)
+		return cw.Error()
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(resp)
+}
+
+// tabulate turns a response into rows. A list of objects, or an
+// object holding a single list of objects such as the addresses
+// or transactions responses, becomes one row per object. Anything
+// else becomes key/value rows with nested keys joined by dots.
+func tabulate(resp interface{}) ([]string, [][]string) {
+	resp = unwrap(toGeneric(resp))
+	if list, ok := objectList(resp); ok {
+		return objectRows(list)
+	}
+
+	flat := make(map[string]string)
+	flatten("", toGeneric(resp), flat)
+
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rows := make([][]string, len(keys))
+	for i, key := range keys {
+		rows[i] = []string{key, flat[key]}
+	}
+	return []string{"key", "value"}, rows
+}
+
+// toGeneric converts typed responses to plain maps and slices
+func toGeneric(resp interface{}) interface{} {
+	switch resp.(type) {
+	case map[string]interface{}, []interface{}:
+		return resp
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return resp
+	}
+	var generic interface{}
+	if json.Unmarshal(data, &generic) != nil {
+		return resp
+	}
+	return generic
+}
+
+// unwrap returns the result of a JSON-RPC response envelope
+func unwrap(resp interface{}) interface{} {
+	obj, ok := resp.(map[string]interface{})
+	if !ok || obj["jsonrpc"] == nil {
+		return resp
+	}
+	if result, ok := obj["result"]; ok {
+		return result
+	}
+	return resp
+}
+
+func objectList(resp interface{}) ([]map[string]interface{}, bool) {
+	if obj, ok := resp.(map[string]interface{}); ok {
+		// Objects holding a single list are shown as that list
+		if len(obj) != 1 {
+			return nil, false
+		}
+		for _, value := range obj {
+			resp = value
+		}
+	}
+
+	items, ok := resp.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, false
+	}
+
+	list := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		list[i] = obj
+	}
+	return list, true
+}
+
+func objectRows(list []map[string]interface{}) ([]string, [][]string) {
+	columns := make(map[string]bool)
+	for _, obj := range list {
+		for key := range obj {
+			columns[key] = true
+		}
+	}
+
+	header := make([]string, 0, len(columns))
+	for key := range columns {
+		header = append(header, key)
+	}
+	sort.Strings(header)
+
+	rows := make([][]string, len(list))
+	for i, obj := range list {
+		row := make([]string, len(header))
+		for j, key := range header {
+			if value, ok := obj[key]; ok {
+				row[j] = cell(value)
+			}
+		}
+		rows[i] = row
+	}
+	return header, rows
+}
+
+func flatten(prefix string, value interface{}, flat map[string]string) {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		flat[prefix] = cell(value)
+		return
+	}
+
+	for key, field := range obj {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		flatten(key, field, flat)
+	}
+}
+
+// cell formats a single value, nested values stay compact JSON
+func cell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return fmt.Sprint(v)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// decode returns a response as decoded by the clients
+func decode(t *testing.T, s string) interface{} {
+	var resp interface{}
+	if err := json.Unmarshal([]byte(s), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestWriteOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		resp   string
+		format string
+		output string
+	}{
+		{
+			name:   "object as json",
+			resp:   `{"b": 1, "a": "x"}`,
+			format: "json",
+			output: "{\n  \"a\": \"x\",\n  \"b\": 1\n}\n",
+		},
+		{
+			name:   "nested object as key/value rows",
+			resp:   `{"jsonrpc": "2.0", "result": {"status": {"height": 10, "synced": true}, "fee": 0.5}}`,
+			format: "csv",
+			output: "key,value\nfee,0.5\nstatus.height,10\nstatus.synced,true\n",
+		},
+		{
+			name:   "single list as rows",
+			resp:   `{"addresses": [{"address": "TRTLa", "balance": 10}, {"address": "TRTLb", "locked": 2}]}`,
+			format: "csv",
+			output: "address,balance,locked\nTRTLa,10,\nTRTLb,,2\n",
+		},
+		{
+			name:   "list with nested values",
+			resp:   `[{"hash": "h", "transfers": [{"amount": 5}]}]`,
+			format: "csv",
+			output: "hash,transfers\nh,\"[{\"\"amount\"\":5}]\"\n",
+		},
+		{
+			name:   "empty list",
+			resp:   `{"items": []}`,
+			format: "csv",
+			output: "key,value\nitems,[]\n",
+		},
+		{
+			name:   "table",
+			resp:   `[{"address": "TRTLa", "balance": 10}, {"address": "TRTLbb", "balance": 200}]`,
+			format: "table",
+			output: "address  balance\nTRTLa    10\nTRTLbb   200\n",
+		},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := writeOutput(&buf, test.format, decode(t, test.resp)); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if buf.String() != test.output {
+			t.Errorf("%s: output\n%s\nwant\n%s", test.name, buf.String(), test.output)
+		}
+	}
+}
+
+func TestTabulateTyped(t *testing.T) {
+	// Typed responses are tabulated through their JSON form
+	type balance struct {
+		Unlocked uint64 `json:"unlocked"`
+		Locked   uint64 `json:"locked"`
+	}
+	header, rows := tabulate([]balance{{1, 2}})
+	if len(header) != 2 || header[0] != "locked" || len(rows) != 1 || rows[0][0] != "2" || rows[0][1] != "1" {
+		t.Errorf("header %q, rows %q", header, rows)
+	}
+}
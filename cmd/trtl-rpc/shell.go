@@ -0,0 +1,547 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+	"golang.org/x/term"
+)
+
+// maxHistory is the number of lines kept in the history file
+const maxHistory = 1000
+
+// addressFlags complete to the subwallet addresses
+var addressFlags = []string{"to", "address", "source", "destination", "change-address"}
+
+// console is the input and output of the shell
+type console interface {
+	io.Writer
+	ReadLine() (string, error)
+	ReadPassword(prompt string) (string, error)
+}
+
+// lineConsole reads plain lines when stdin is not a terminal
+type lineConsole struct {
+	io.Writer
+	scanner *bufio.Scanner
+}
+
+func (c *lineConsole) ReadLine() (string, error) {
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return c.scanner.Text(), nil
+}
+
+func (c *lineConsole) ReadPassword(prompt string) (string, error) {
+	fmt.Fprint(c, prompt)
+	return c.ReadLine()
+}
+
+// history keeps the shell history in a file across sessions
+type history struct {
+	path    string
+	entries []string
+}
+
+func loadHistory() *history {
+	h := &history{}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return h
+	}
+
+	h.path = filepath.Join(home, ".trtl-rpc_history")
+	if data, err := ioutil.ReadFile(h.path); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line != "" {
+				h.entries = append(h.entries, line)
+			}
+		}
+	}
+	return h
+}
+
+func (h *history) Add(entry string) {
+	if setsSecret(entry) {
+		return
+	}
+
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+	if h.path != "" {
+		ioutil.WriteFile(h.path, []byte(strings.Join(h.entries, "\n")+"\n"), 0600)
+	}
+}
+
+// setsSecret reports whether a line sets a secret flag of its
+// command. Lines which are not a command are checked against the
+// secret flags of every command, as they may be mistyped.
+func setsSecret(line string) bool {
+	args, err := splitLine(line)
+	if err != nil {
+		args = strings.Fields(line)
+	}
+
+	commands := shellCommands()
+	if cmd, _ := findCommand(&group{commands: commands}, args); cmd != nil {
+		commands = []command{*cmd}
+	}
+	secrets := make(map[string]bool)
+	for _, cmd := range commands {
+		fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		cmd.setup(fs, nil)
+		fs.VisitAll(func(f *flag.Flag) {
+			if _, ok := f.Value.(*secret); ok {
+				secrets[f.Name] = true
+			}
+		})
+	}
+
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		if i := strings.Index(name, "="); i >= 0 {
+			name = name[:i]
+		}
+		if secrets[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *history) Len() int {
+	return len(h.entries)
+}
+
+func (h *history) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
+
+// shell is an interactive session on top of wallet-api and the node
+type shell struct {
+	cfg       *config
+	console   console
+	addresses []string
+	prepared  map[string]*trpc.PreparedTransaction
+}
+
+func runShell(cfg *config) error {
+	sh := &shell{cfg: cfg, prepared: make(map[string]*trpc.PreparedTransaction)}
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer term.Restore(fd, state)
+
+		terminal := term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{os.Stdin, os.Stdout}, "trtl> ")
+		terminal.History = loadHistory()
+		terminal.AutoCompleteCallback = sh.complete
+		if width, height, err := term.GetSize(fd); err == nil {
+			terminal.SetSize(width, height)
+		}
+		sh.console = terminal
+	} else {
+		sh.console = &lineConsole{os.Stdout, bufio.NewScanner(os.Stdin)}
+	}
+
+	fmt.Fprintln(sh.console, "TurtleCoin shell. Type help for the commands, exit to quit.")
+	sh.refreshAddresses()
+
+	for {
+		line, err := sh.console.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		args, err := splitLine(line)
+		if err != nil {
+			fmt.Fprintln(sh.console, "error:", err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		switch args[0] {
+		case "exit", "quit":
+			return nil
+		case "help":
+			sh.help()
+			continue
+		}
+
+		if err = sh.execute(args); err != nil {
+			fmt.Fprintln(sh.console, "error:", err)
+		}
+	}
+}
+
+// shellCommands returns the commands of the shell, wallet-api
+// commands are used as is and node commands are prefixed by daemon
+func shellCommands() []command {
+	commands := append([]command(nil), walletAPICommands...)
+	for _, cmd := range daemonCommands {
+		cmd.name = "daemon " + cmd.name
+		commands = append(commands, cmd)
+	}
+	return commands
+}
+
+func (sh *shell) help() {
+	commands := shellCommands()
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].name < commands[j].name
+	})
+	for _, cmd := range commands {
+		fmt.Fprintf(sh.console, "  %-28s %s\n", cmd.name, cmd.help)
+	}
+	fmt.Fprintf(sh.console, "  %-28s %s\n", "exit", "leave the shell")
+	fmt.Fprintln(sh.console, "Run <command> -h for the flags of a command.")
+}
+
+func (sh *shell) execute(args []string) error {
+	cmd, rest := findCommand(&group{commands: shellCommands()}, args)
+	if cmd == nil {
+		return errors.New("unknown command " + strings.Join(args, " ") + ", type help for the list")
+	}
+
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(sh.console)
+	runner := cmd.setup(fs, sh.cfg)
+	if err := fs.Parse(rest); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errors.New("unexpected arguments " + strings.Join(fs.Args(), " "))
+	}
+
+	if err := sh.promptSecrets(fs); err != nil {
+		return err
+	}
+
+	ok, err := sh.confirm(cmd.name, fs)
+	if err != nil || !ok {
+		return err
+	}
+
+	resp, err := runner()
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(cmd.name, "addresses") || strings.HasPrefix(cmd.name, "wallet") {
+		sh.refreshAddresses()
+	}
+	if prepared, ok := resp.(*trpc.PreparedTransaction); ok {
+		sh.prepared[prepared.TransactionHash] = prepared
+	}
+	if cmd.name == "send prepared" || cmd.name == "send cancel" {
+		delete(sh.prepared, fs.Lookup("hash").Value.String())
+	}
+
+	return writeOutput(sh.console, sh.cfg.Output, resp)
+}
+
+// promptSecrets asks for the secret flags of the command
+// which were not given on the line, without echoing them
+func (sh *shell) promptSecrets(fs *flag.FlagSet) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, ok := f.Value.(*secret); !ok || set[f.Name] || err != nil {
+			return
+		}
+
+		var value string
+		if value, err = sh.console.ReadPassword(f.Usage + ": "); err == nil {
+			err = fs.Set(f.Name, strings.TrimSpace(value))
+		}
+	})
+	return err
+}
+
+// confirm shows what a send or reset is about to do and asks the
+// user to go ahead. Other commands need no confirmation, except the
+// sends without a summary here, which are refused.
+func (sh *shell) confirm(name string, fs *flag.FlagSet) (bool, error) {
+	value := func(flagName string) string {
+		return fs.Lookup(flagName).Value.String()
+	}
+
+	var summary []string
+	switch name {
+	case "send basic":
+		summary = append(summary,
+			"Send "+value("amount")+" TRTL to "+value("to"),
+			"Network fee: minimum fee")
+		if paymentID := value("payment-id"); paymentID != "" {
+			summary = append(summary, "Payment ID: "+paymentID)
+		}
+		summary = append(summary, sh.nodeFee())
+	case "send advanced":
+		destinations, err := parseDestinations(*fs.Lookup("to").Value.(*stringList))
+		if err != nil {
+			return false, err
+		}
+		total := 0
+		for _, destination := range destinations {
+			atomic := destination["amount"].(int)
+			total += atomic
+			summary = append(summary, "Send "+formatAmount(atomic)+" TRTL to "+destination["address"].(string))
+		}
+		fee := value("fee")
+		if fee == "0.00" {
+			fee = "minimum fee"
+		} else {
+			fee += " TRTL"
+		}
+		summary = append(summary, "Total: "+formatAmount(total)+" TRTL", "Network fee: "+fee, sh.nodeFee())
+	case "send prepare basic", "send prepare advanced":
+		// Preparing sends nothing, the prepared
+		// transaction is confirmed when it is sent
+		return true, nil
+	case "send prepared":
+		summary = append(summary, "Send the prepared transaction "+value("hash"))
+		summary = append(summary, sh.preparedFees(value("hash"))...)
+	case "send cancel":
+		summary = append(summary, "Cancel the prepared transaction "+value("hash"))
+		summary = append(summary, sh.preparedFees(value("hash"))...)
+	case "send fusion basic", "send fusion advanced":
+		summary = append(summary, "Send a fusion transaction, no network fee is charged")
+	case "reset":
+		summary = append(summary, "Reset the wallet and rescan from height "+value("scan-height"))
+	default:
+		if strings.HasPrefix(name, "send") {
+			return false, errors.New("no confirmation summary for " + name + ", refusing to run it")
+		}
+		return true, nil
+	}
+
+	for _, line := range summary {
+		fmt.Fprintln(sh.console, line)
+	}
+
+	answer, err := sh.readAnswer("Confirm? [y/N] ")
+	if err != nil {
+		return false, err
+	}
+	if answer != "y" && answer != "yes" {
+		fmt.Fprintln(sh.console, "Cancelled")
+		return false, nil
+	}
+	return true, nil
+}
+
+func (sh *shell) readAnswer(prompt string) (string, error) {
+	if terminal, ok := sh.console.(*term.Terminal); ok {
+		terminal.SetPrompt(prompt)
+		defer terminal.SetPrompt("trtl> ")
+	} else {
+		fmt.Fprint(sh.console, prompt)
+	}
+
+	answer, err := sh.console.ReadLine()
+	return strings.ToLower(strings.TrimSpace(answer)), err
+}
+
+// nodeFee describes the fee charged by the node the wallet uses
+func (sh *shell) nodeFee() string {
+	details, err := sh.cfg.walletAPI().GetNodeDetails()
+	if err != nil {
+		return "Node fee: unknown (" + err.Error() + ")"
+	}
+
+	fee, _ := details["nodeFee"].(float64)
+	if fee == 0 {
+		return "Node fee: none"
+	}
+	address, _ := details["nodeAddress"].(string)
+	return "Node fee: " + formatAmount(int(fee)) + " TRTL to " + address
+}
+
+// preparedFees describes the fees of a transaction prepared in this
+// shell, or the current node fee for the other prepared transactions
+func (sh *shell) preparedFees(hash string) []string {
+	prepared, ok := sh.prepared[hash]
+	if !ok {
+		return []string{"Network fee: unknown, the transaction was not prepared in this shell", sh.nodeFee()}
+	}
+
+	nodeFee := "none"
+	if prepared.NodeFee != 0 {
+		nodeFee = formatAmount(int(prepared.NodeFee)) + " TRTL"
+	}
+	return []string{"Network fee: " + formatAmount(int(prepared.Fee)) + " TRTL", "Node fee: " + nodeFee}
+}
+
+func (sh *shell) refreshAddresses() {
+	resp, err := sh.cfg.walletAPI().Addresses()
+	if err != nil {
+		return
+	}
+
+	list, _ := resp["addresses"].([]interface{})
+	sh.addresses = sh.addresses[:0]
+	for _, address := range list {
+		if s, ok := address.(string); ok {
+			sh.addresses = append(sh.addresses, s)
+		}
+	}
+}
+
+// complete is the tab completion of the terminal. The word under
+// the cursor is completed to a command name at the start of the
+// line, and to a subwallet address after an address flag.
+func (sh *shell) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	start := strings.LastIndex(line[:pos], " ") + 1
+	word := line[start:pos]
+	before := strings.Fields(line[:start])
+
+	var candidates []string
+	if isAddressFlag(before) || strings.HasPrefix(word, "TRTL") {
+		if len(sh.addresses) == 0 {
+			sh.refreshAddresses()
+		}
+		candidates = sh.addresses
+	} else if !hasFlag(before) {
+		typed := strings.Join(before, " ")
+		for _, cmd := range append(shellCommands(), command{name: "help"}, command{name: "exit"}) {
+			words := strings.Fields(cmd.name)
+			if len(words) > len(before) && strings.Join(words[:len(before)], " ") == typed {
+				candidates = append(candidates, words[len(before)])
+			}
+		}
+	}
+
+	completion := commonPrefix(word, candidates)
+	if completion == "" || completion == word {
+		return "", 0, false
+	}
+
+	newLine := line[:start] + completion + line[pos:]
+	return newLine, start + len(completion), true
+}
+
+func isAddressFlag(words []string) bool {
+	if len(words) == 0 {
+		return false
+	}
+	last := strings.TrimLeft(words[len(words)-1], "-")
+	for _, name := range addressFlags {
+		if last == name && strings.HasPrefix(words[len(words)-1], "-") {
+			return true
+		}
+	}
+	return false
+}
+
+func hasFlag(words []string) bool {
+	for _, word := range words {
+		if strings.HasPrefix(word, "-") {
+			return true
+		}
+	}
+	return false
+}
+
+// commonPrefix returns the longest common prefix of the
+// candidates starting with word, with a trailing space
+// when there is a single match
+func commonPrefix(word string, candidates []string) string {
+	var matches []string
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) && !seen[candidate] {
+			matches = append(matches, candidate)
+			seen[candidate] = true
+		}
+	}
+
+	if len(matches) == 0 {
+		return ""
+	}
+	if len(matches) == 1 {
+		return matches[0] + " "
+	}
+
+	prefix := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// splitLine splits a line into words, double quotes
+// group words containing spaces
+func splitLine(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, quoted := false, false
+
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+		case r == ' ' && !quoted:
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quote at column " + strconv.Itoa(len(line)))
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+	"github.com/turtlecoin/turtlecoin-rpc-go/rpctest"
+)
+
+// newTestShell returns a shell on a fake wallet-api, reading
+// its input from the lines and writing its output to out
+func newTestShell(t *testing.T, lines ...string) (*shell, *bytes.Buffer) {
+	fake := rpctest.NewWalletAPI("key")
+	t.Cleanup(fake.Close)
+	client := fake.Client()
+
+	out := &bytes.Buffer{}
+	input := strings.NewReader(strings.Join(lines, "\n") + "\n")
+	return &shell{
+		cfg:      &config{WalletAPIHost: client.URL, WalletAPIPort: client.Port, WalletAPIPassword: "key"},
+		console:  &lineConsole{out, bufio.NewScanner(input)},
+		prepared: make(map[string]*trpc.PreparedTransaction),
+	}, out
+}
+
+// parseLine returns the name and the parsed flags of a shell command
+func parseLine(t *testing.T, line string) (string, *flag.FlagSet) {
+	t.Helper()
+	cmd, rest := findCommand(&group{commands: shellCommands()}, strings.Fields(line))
+	if cmd == nil {
+		t.Fatalf("no command %q", line)
+	}
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	cmd.setup(fs, nil)
+	if err := fs.Parse(rest); err != nil {
+		t.Fatal(err)
+	}
+	return cmd.name, fs
+}
+
+func TestPromptSecrets(t *testing.T) {
+	tests := []struct {
+		line    string
+		prompts string
+		values  map[string]string
+	}{
+		{
+			line:    "addresses import view --spend-key public",
+			prompts: "",
+			values:  map[string]string{"spend-key": "public"},
+		},
+		{
+			line:    "addresses import",
+			prompts: "private spend key: ",
+			values:  map[string]string{"spend-key": "first"},
+		},
+		{
+			line:    "wallet import view --filename w --address TRTL",
+			prompts: "wallet file password: private view key: ",
+			values:  map[string]string{"password": "first", "view-key": "second", "address": "TRTL"},
+		},
+		{
+			line:    "wallet import key --password p --spend-key s",
+			prompts: "private view key: ",
+			values:  map[string]string{"password": "p", "spend-key": "s", "view-key": "first"},
+		},
+	}
+	for _, test := range tests {
+		sh, out := newTestShell(t, " first ", "second")
+		_, fs := parseLine(t, test.line)
+		if err := sh.promptSecrets(fs); err != nil {
+			t.Fatalf("%s: %v", test.line, err)
+		}
+		if out.String() != test.prompts {
+			t.Errorf("%s: prompts %q, want %q", test.line, out.String(), test.prompts)
+		}
+		for name, value := range test.values {
+			if got := fs.Lookup(name).Value.String(); got != value {
+				t.Errorf("%s: %s = %q, want %q", test.line, name, got, value)
+			}
+		}
+	}
+}
+
+func TestSetsSecret(t *testing.T) {
+	tests := []struct {
+		line   string
+		secret bool
+	}{
+		{"wallet open --filename w", false},
+		{"wallet open --filename w --password p", true},
+		{"wallet open -password=p", true},
+		{`wallet import seed --seed "a b c"`, true},
+		{"addresses import --spend-key s", true},
+		{"addresses import view --spend-key s", false},
+		{"addresses import view --scan-height 5", false},
+		{"walet open --password p", true},
+		{"walet open --filename w", false},
+		{"send basic --to TRTL --amount 1", false},
+	}
+	for _, test := range tests {
+		if setsSecret(test.line) != test.secret {
+			t.Errorf("setsSecret(%q) = %v", test.line, !test.secret)
+		}
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		line    string
+		answer  string
+		ok      bool
+		summary string
+	}{
+		{
+			line:    "send basic --to TRTLa --amount 1.5",
+			answer:  "y",
+			ok:      true,
+			summary: "Send 1.50 TRTL to TRTLa\nNetwork fee: minimum fee\nNode fee: 0.05 TRTL to \nConfirm? [y/N] ",
+		},
+		{
+			line:    "send basic --to TRTLa --amount 1.5 --payment-id abc",
+			answer:  "YES",
+			ok:      true,
+			summary: "Send 1.50 TRTL to TRTLa\nNetwork fee: minimum fee\nPayment ID: abc\nNode fee: 0.05 TRTL to \nConfirm? [y/N] ",
+		},
+		{
+			line:    "send advanced --to TRTLa:1 --to TRTLb:2.25 --fee 0.1",
+			answer:  "n",
+			summary: "Send 1.00 TRTL to TRTLa\nSend 2.25 TRTL to TRTLb\nTotal: 3.25 TRTL\nNetwork fee: 0.10 TRTL\nNode fee: 0.05 TRTL to \nConfirm? [y/N] Cancelled\n",
+		},
+		{
+			line:    "send prepared --hash h",
+			answer:  "",
+			summary: "Send the prepared transaction h\nNetwork fee: unknown, the transaction was not prepared in this shell\nNode fee: 0.05 TRTL to \nConfirm? [y/N] Cancelled\n",
+		},
+		{
+			line:    "reset --scan-height 10",
+			answer:  "y",
+			ok:      true,
+			summary: "Reset the wallet and rescan from height 10\nConfirm? [y/N] ",
+		},
+		{
+			line: "send prepare basic --to TRTLa --amount 1",
+			ok:   true,
+		},
+		{
+			line: "addresses",
+			ok:   true,
+		},
+	}
+	for _, test := range tests {
+		sh, out := newTestShell(t, test.answer)
+		name, fs := parseLine(t, test.line)
+		ok, err := sh.confirm(name, fs)
+		if err != nil {
+			t.Fatalf("%s: %v", test.line, err)
+		}
+		if ok != test.ok || out.String() != test.summary {
+			t.Errorf("%s: confirmed %v after %q", test.line, ok, out.String())
+		}
+	}
+
+	// A prepared transaction is confirmed with its own fees
+	sh, out := newTestShell(t, "y")
+	sh.prepared["h"] = &trpc.PreparedTransaction{TransactionHash: "h", Fee: 10, NodeFee: 0}
+	name, fs := parseLine(t, "send cancel --hash h")
+	if ok, err := sh.confirm(name, fs); !ok || err != nil {
+		t.Errorf("cancel: confirmed %v, %v", ok, err)
+	}
+	if want := "Cancel the prepared transaction h\nNetwork fee: 0.10 TRTL\nNode fee: none\nConfirm? [y/N] "; out.String() != want {
+		t.Errorf("cancel: summary %q", out.String())
+	}
+
+	// Sends without a summary are refused
+	sh, _ = newTestShell(t)
+	if ok, err := sh.confirm("send unknown", flag.NewFlagSet("send unknown", flag.ContinueOnError)); ok || err == nil {
+		t.Errorf("send without a summary: confirmed %v, %v", ok, err)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	sh, _ := newTestShell(t)
+	sh.addresses = []string{"TRTLabc1", "TRTLabc2", "TRTLxyz"}
+
+	tests := []struct {
+		line    string
+		pos     int
+		want    string
+		wantPos int
+	}{
+		{"sen", 3, "send ", 5},
+		{"send", 4, "send ", 5},
+		{"send fu", 7, "send fusion ", 12},
+		{"addresses im", 12, "addresses import ", 17},
+		{"addresses import v", 18, "addresses import view ", 22},
+		{"send basic --to TRTLx", 21, "send basic --to TRTLxyz ", 24},
+		{"send basic --to TRTLa", 21, "send basic --to TRTLabc", 23},
+		{"send basic --to  --amount 1", 16, "send basic --to TRTL --amount 1", 20},
+		{"keys --address TRTLx", 20, "keys --address TRTLxyz ", 23},
+		{"ex", 2, "exit ", 5},
+		{"send basic --am", 15, "", 0},
+		{"unknown", 7, "", 0},
+		{"send basic --to TRTLq", 21, "", 0},
+	}
+	for _, test := range tests {
+		line, pos, ok := sh.complete(test.line, test.pos, '\t')
+		if ok != (test.want != "") || line != test.want || pos != test.wantPos {
+			t.Errorf("%q completed to %q at %d, want %q at %d", test.line, line, pos, test.want, test.wantPos)
+		}
+	}
+
+	if _, _, ok := sh.complete("sen", 3, 'a'); ok {
+		t.Error("completed on a key other than tab")
+	}
+}
+
+func TestSplitLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		words []string
+		err   bool
+	}{
+		{"", nil, false},
+		{"   ", nil, false},
+		{"send basic", []string{"send", "basic"}, false},
+		{"  send   basic  ", []string{"send", "basic"}, false},
+		{`wallet import seed --seed "one two three"`, []string{"wallet", "import", "seed", "--seed", "one two three"}, false},
+		{`--password ""`, []string{"--password", ""}, false},
+		{`a"b c"d`, []string{"ab cd"}, false},
+		{`--seed "one two`, nil, true},
+	}
+	for _, test := range tests {
+		words, err := splitLine(test.line)
+		if (err != nil) != test.err || !reflect.DeepEqual(words, test.words) {
+			t.Errorf("splitLine(%q) = %q, %v", test.line, words, err)
+		}
+	}
+}
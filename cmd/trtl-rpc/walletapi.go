@@ -0,0 +1,254 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package main
+
+import (
+	"errors"
+	"flag"
+)
+
+// walletFileFlags registers the filename and password
+// flags shared by the wallet open and import commands
+func walletFileFlags(fs *flag.FlagSet) (*string, *string) {
+	filename := fs.String("filename", "", "wallet file name")
+	password := secretString(fs, "password", "wallet file password")
+	return filename, password
+}
+
+var walletAPICommands = []command{
+	{"wallet create", "create a new wallet file", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		filename, password := walletFileFlags(fs)
+		return func() (interface{}, error) { return nil, cfg.walletAPI().CreateWallet(*filename, *password) }
+	}},
+	{"wallet import key", "import a wallet from its private keys", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		filename, password := walletFileFlags(fs)
+		scanHeight := fs.Int("scan-height", 0, "height to scan from")
+		spendKey := secretString(fs, "spend-key", "private spend key")
+		viewKey := secretString(fs, "view-key", "private view key")
+		return func() (interface{}, error) {
+			return nil, cfg.walletAPI().ImportKey(*filename, *password, *scanHeight, *spendKey, *viewKey)
+		}
+	}},
+	{"wallet import seed", "import a wallet from its mnemonic seed", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		filename, password := walletFileFlags(fs)
+		scanHeight := fs.Int("scan-height", 0, "height to scan from")
+		seed := secretString(fs, "seed", "25 word mnemonic seed")
+		return func() (interface{}, error) {
+			return nil, cfg.walletAPI().ImportSeed(*filename, *password, *scanHeight, *seed)
+		}
+	}},
+	{"wallet import view", "import a view only wallet", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		filename, password := walletFileFlags(fs)
+		scanHeight := fs.Int("scan-height", 0, "height to scan from")
+		viewKey := secretString(fs, "view-key", "private view key")
+		address := fs.String("address", "", "wallet address")
+		return func() (interface{}, error) {
+			return nil, cfg.walletAPI().ImportViewOnly(*filename, *password, *scanHeight, *viewKey, *address)
+		}
+	}},
+	{"wallet open", "open an existing wallet file", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		filename, password := walletFileFlags(fs)
+		return func() (interface{}, error) { return nil, cfg.walletAPI().OpenWallet(*filename, *password) }
+	}},
+	{"wallet close", "save and close the open wallet", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return nil, cfg.walletAPI().CloseWallet() }
+	}},
+	{"addresses", "addresses of the container", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.walletAPI().Addresses() }
+	}},
+	{"addresses delete", "delete a subwallet address", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		address := fs.String("address", "", "subwallet address")
+		return func() (interface{}, error) {
+			if err := requireFlag("address", *address); err != nil {
+				return nil, err
+			}
+			return nil, cfg.walletAPI().DeleteAddress(*address)
+		}
+	}},
+	{"addresses primary", "primary address of the container", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.walletAPI().Primary() }
+	}},
+	{"addresses create", "create a random subwallet", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.walletAPI().CreateAddress() }
+	}},
+	{"addresses import", "import a subwallet from its private spend key", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		scanHeight := fs.Int("scan-height", 0, "height to scan from")
+		spendKey := secretString(fs, "spend-key", "private spend key")
+		return func() (interface{}, error) { return cfg.walletAPI().ImportAddress(*scanHeight, *spendKey) }
+	}},
+	{"addresses import view", "import a view only subwallet from its public spend key", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		scanHeight := fs.Int("scan-height", 0, "height to scan from")
+		spendKey := fs.String("spend-key", "", "public spend key")
+		return func() (interface{}, error) { return cfg.walletAPI().ImportViewAddress(*scanHeight, *spendKey) }
+	}},
+	{"addresses integrated", "integrated address of an address and payment id", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		address := fs.String("address", "", "wallet address")
+		paymentID := fs.String("payment-id", "", "payment id")
+		return func() (interface{}, error) { return cfg.walletAPI().CreateIntegratedAddress(*address, *paymentID) }
+	}},
+	{"addresses validate", "validate an address", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		address := fs.String("address", "", "address to validate")
+		return func() (interface{}, error) { return cfg.walletAPI().ValidateAddress(*address) }
+	}},
+	{"node", "node the wallet is connected to", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.walletAPI().GetNodeDetails() }
+	}},
+	{"node set", "connect the wallet to another node", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		host := fs.String("host", "", "node host")
+		port := fs.Int("port", 11898, "node port")
+		ssl := fs.Bool("ssl", false, "connect over SSL")
+		return func() (interface{}, error) { return nil, cfg.walletAPI().SetNode(*host, *port, *ssl) }
+	}},
+	{"keys", "private view key, or the keys of an address", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		address := fs.String("address", "", "wallet address, empty for the shared private view key")
+		return func() (interface{}, error) {
+			if *address == "" {
+				return cfg.walletAPI().PrivateViewKey()
+			}
+			return cfg.walletAPI().Keys(*address)
+		}
+	}},
+	{"keys mnemonic", "mnemonic seed of an address", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		address := fs.String("address", "", "wallet address")
+		return func() (interface{}, error) { return cfg.walletAPI().MnemonicSeed(*address) }
+	}},
+	{"balance", "balance of the container or an address", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		address := fs.String("address", "", "wallet address, empty for the whole container")
+		return func() (interface{}, error) {
+			if *address == "" {
+				return cfg.walletAPI().TotalBalance()
+			}
+			return cfg.walletAPI().Balance(*address)
+		}
+	}},
+	{"balances", "balance of every address", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.walletAPI().Balances() }
+	}},
+	{"save", "save the wallet", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return nil, cfg.walletAPI().Save() }
+	}},
+	{"reset", "reset and rescan the wallet from a height", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		scanHeight := fs.Int("scan-height", 0, "height to scan from")
+		return func() (interface{}, error) { return nil, cfg.walletAPI().Reset(*scanHeight) }
+	}},
+	{"status", "sync status of the wallet", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.walletAPI().Status() }
+	}},
+	{"transactions", "transactions of the container", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		start := fs.Int("start", 0, "start height")
+		end := fs.Int("end", 0, "end height")
+		return func() (interface{}, error) { return cfg.walletAPI().Transactions(*start, *end) }
+	}},
+	{"transactions hash", "details of a transaction", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		hash := fs.String("hash", "", "transaction hash")
+		return func() (interface{}, error) {
+			if err := requireFlag("hash", *hash); err != nil {
+				return nil, err
+			}
+			return cfg.walletAPI().GetTransactionDetails(*hash)
+		}
+	}},
+	{"transactions unconfirmed", "unconfirmed transactions", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		address := fs.String("address", "", "wallet address, empty for the whole container")
+		return func() (interface{}, error) { return cfg.walletAPI().UnconfirmedTransactions(*address) }
+	}},
+	{"transactions address", "transactions of an address", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		address := fs.String("address", "", "wallet address")
+		start := fs.Int("start", 0, "start height")
+		end := fs.Int("end", 0, "end height")
+		return func() (interface{}, error) {
+			return cfg.walletAPI().TransactionsByAddress(*address, *start, *end)
+		}
+	}},
+	{"transactions privatekey", "private key of a transaction", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		hash := fs.String("hash", "", "transaction hash")
+		return func() (interface{}, error) { return cfg.walletAPI().TransactionPrivateKey(*hash) }
+	}},
+	{"send basic", "send an amount to an address", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		to := fs.String("to", "", "destination address")
+		value := amount(0)
+		fs.Var(&value, "amount", "amount in TRTL")
+		paymentID := fs.String("payment-id", "", "payment id")
+		return func() (interface{}, error) {
+			return cfg.walletAPI().SendBasicTransaction(*to, int(value), *paymentID)
+		}
+	}},
+	{"send advanced", "send to several destinations", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		var to, sources stringList
+		fs.Var(&to, "to", "destination as address:amount, can be repeated")
+		fs.Var(&sources, "source", "source address, can be repeated")
+		mixin := fs.Int("mixin", 3, "mixin")
+		fee := amount(0)
+		fs.Var(&fee, "fee", "network fee in TRTL")
+		paymentID := fs.String("payment-id", "", "payment id")
+		changeAddress := fs.String("change-address", "", "address receiving the change")
+		unlockTime := fs.Int("unlock-time", 0, "unlock time of the transaction")
+		return func() (interface{}, error) {
+			destinations, err := parseDestinations(to)
+			if err != nil {
+				return nil, err
+			}
+			if len(destinations) == 0 {
+				return nil, errors.New("at least one --to is required")
+			}
+			return cfg.walletAPI().SendAdvancedTransaction(destinations, *mixin, int(fee), sources, *paymentID, *changeAddress, *unlockTime)
+		}
+	}},
+	{"send prepare basic", "prepare a transaction to an address without sending it", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		to := fs.String("to", "", "destination address")
+		value := amount(0)
+		fs.Var(&value, "amount", "amount in TRTL")
+		paymentID := fs.String("payment-id", "", "payment id")
+		return func() (interface{}, error) {
+			return cfg.walletAPI().PrepareBasicTransaction(*to, int(value), *paymentID)
+		}
+	}},
+	{"send prepare advanced", "prepare a transaction to several destinations without sending it", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		var to, sources stringList
+		fs.Var(&to, "to", "destination as address:amount, can be repeated")
+		fs.Var(&sources, "source", "source address, can be repeated")
+		mixin := fs.Int("mixin", 3, "mixin")
+		fee := amount(0)
+		fs.Var(&fee, "fee", "network fee in TRTL")
+		paymentID := fs.String("payment-id", "", "payment id")
+		changeAddress := fs.String("change-address", "", "address receiving the change")
+		unlockTime := fs.Int("unlock-time", 0, "unlock time of the transaction")
+		return func() (interface{}, error) {
+			destinations, err := parseDestinations(to)
+			if err != nil {
+				return nil, err
+			}
+			if len(destinations) == 0 {
+				return nil, errors.New("at least one --to is required")
+			}
+			return cfg.walletAPI().PrepareAdvancedTransaction(destinations, *mixin, int(fee), sources, *paymentID, *changeAddress, *unlockTime)
+		}
+	}},
+	{"send prepared", "send a prepared transaction", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		hash := fs.String("hash", "", "prepared transaction hash")
+		return func() (interface{}, error) {
+			sent, err := cfg.walletAPI().SendPreparedTransaction(*hash)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"transactionHash": sent}, nil
+		}
+	}},
+	{"send cancel", "cancel a prepared transaction", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		hash := fs.String("hash", "", "prepared transaction hash")
+		return func() (interface{}, error) { return nil, cfg.walletAPI().CancelPreparedTransaction(*hash) }
+	}},
+	{"send fusion basic", "send a fusion transaction", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.walletAPI().SendBasicFusion() }
+	}},
+	{"send fusion advanced", "send a fusion transaction from selected addresses", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		var sources stringList
+		fs.Var(&sources, "source", "source address, can be repeated")
+		mixin := fs.Int("mixin", 3, "mixin")
+		destination := fs.String("destination", "", "address receiving the optimized outputs")
+		return func() (interface{}, error) {
+			return cfg.walletAPI().SendAdvancedFusion(*mixin, sources, *destination)
+		}
+	}},
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package main
+
+import (
+	"errors"
+	"flag"
+)
+
+// transferFlags registers the flags shared by sendTransaction
+// and createDelayedTransaction and returns the call to make
+func transferFlags(fs *flag.FlagSet, cfg *config, delayed bool) func() (interface{}, error) {
+	var addresses, to stringList
+	fs.Var(&addresses, "address", "source address, can be repeated")
+	fs.Var(&to, "to", "destination as address:amount, can be repeated")
+	fee := amount(0)
+	fs.Var(&fee, "fee", "network fee in TRTL")
+	unlockTime := fs.Int("unlock-time", 0, "unlock time of the transaction")
+	extra := fs.String("extra", "", "hex encoded extra")
+	paymentID := fs.String("payment-id", "", "payment id")
+	changeAddress := fs.String("change-address", "", "address receiving the change")
+	return func() (interface{}, error) {
+		transfers, err := parseDestinations(to)
+		if err != nil {
+			return nil, err
+		}
+		if len(transfers) == 0 {
+			return nil, errors.New("at least one --to is required")
+		}
+		if delayed {
+			return cfg.walletd().CreateDelayedTransaction(addresses, transfers, int(fee), *unlockTime, *extra, *paymentID, *changeAddress)
+		}
+		return cfg.walletd().SendTransaction(addresses, transfers, int(fee), *unlockTime, *extra, *paymentID, *changeAddress)
+	}
+}
+
+// rangeFlags registers the flags shared by getTransactionHashes
+// and getTransactions and returns the call to make
+func rangeFlags(fs *flag.FlagSet, cfg *config, hashesOnly bool) func() (interface{}, error) {
+	var addresses stringList
+	fs.Var(&addresses, "address", "address to filter by, can be repeated")
+	blockHash := fs.String("block-hash", "", "hash of the first block")
+	first := fs.Int("first", 0, "index of the first block, ignored if --block-hash is given")
+	count := fs.Int("count", 100, "number of blocks")
+	paymentID := fs.String("payment-id", "", "payment id to filter by")
+	return func() (interface{}, error) {
+		if hashesOnly {
+			return cfg.walletd().GetTransactionHashes(addresses, *blockHash, *first, *count, *paymentID)
+		}
+		return cfg.walletd().GetTransactions(addresses, *blockHash, *first, *count, *paymentID)
+	}
+}
+
+func requireFlag(name string, value string) error {
+	if value == "" {
+		return errors.New("--" + name + " is required")
+	}
+	return nil
+}
+
+var walletdCommands = []command{
+	{"save", "save the wallet", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.walletd().Save() }
+	}},
+	{"reset", "resync the wallet from a height", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		scanHeight := fs.Int("scan-height", 0, "height to scan from")
+		return func() (interface{}, error) { return cfg.walletd().Reset(*scanHeight) }
+	}},
+	{"create-address", "create or import an address", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		spendSecretKey := secretString(fs, "spend-secret-key", "private spend key to import")
+		spendPublicKey := fs.String("spend-public-key", "", "public spend key to import as view only")
+		scanHeight := fs.Int("scan-height", 0, "height to scan from")
+		newAddress := fs.Bool("new", false, "the address is new, no scanning is needed")
+		return func() (interface{}, error) {
+			return cfg.walletd().CreateAddress(*spendSecretKey, *spendPublicKey, *scanHeight, *newAddress)
+		}
+	}},
+	{"delete-address", "delete an address", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		address := fs.String("address", "", "address to delete")
+		return func() (interface{}, error) {
+			if err := requireFlag("address", *address); err != nil {
+				return nil, err
+			}
+			return cfg.walletd().DeleteAddress(*address)
+		}
+	}},
+	{"get-spend-keys", "spend keys of an address", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		address := fs.String("address", "", "wallet address")
+		return func() (interface{}, error) {
+			if err := requireFlag("address", *address); err != nil {
+				return nil, err
+			}
+			return cfg.walletd().GetSpendKeys(*address)
+		}
+	}},
+	{"get-balance", "balance of an address or the container", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		address := fs.String("address", "", "wallet address, empty for the whole container")
+		return func() (interface{}, error) { return cfg.walletd().GetBalance(*address) }
+	}},
+	{"get-block-hashes", "block hashes of a range", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		first := fs.Int("first", 0, "index of the first block")
+		count := fs.Int("count", 100, "number of blocks")
+		return func() (interface{}, error) { return cfg.walletd().GetBlockHashes(*first, *count) }
+	}},
+	{"get-transaction-hashes", "transaction hashes of a range", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return rangeFlags(fs, cfg, true)
+	}},
+	{"get-transactions", "transactions of a range", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return rangeFlags(fs, cfg, false)
+	}},
+	{"get-unconfirmed-transaction-hashes", "hashes of unconfirmed transactions", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		var addresses stringList
+		fs.Var(&addresses, "address", "address to filter by, can be repeated")
+		return func() (interface{}, error) { return cfg.walletd().GetUnconfirmedTransactionHashes(addresses) }
+	}},
+	{"get-transaction", "transaction details by hash", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		hash := fs.String("hash", "", "transaction hash")
+		return func() (interface{}, error) { return cfg.walletd().GetTransaction(*hash) }
+	}},
+	{"send-transaction", "send a transaction", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return transferFlags(fs, cfg, false)
+	}},
+	{"create-delayed-transaction", "create a transaction without sending it", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return transferFlags(fs, cfg, true)
+	}},
+	{"get-delayed-transaction-hashes", "hashes of delayed transactions", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.walletd().GetDelayedTransactionHashes() }
+	}},
+	{"delete-delayed-transaction", "delete a delayed transaction", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		hash := fs.String("hash", "", "transaction hash")
+		return func() (interface{}, error) {
+			if err := requireFlag("hash", *hash); err != nil {
+				return nil, err
+			}
+			return cfg.walletd().DeleteDelayedTransaction(*hash)
+		}
+	}},
+	{"send-delayed-transaction", "send a delayed transaction", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		hash := fs.String("hash", "", "transaction hash")
+		return func() (interface{}, error) {
+			if err := requireFlag("hash", *hash); err != nil {
+				return nil, err
+			}
+			return cfg.walletd().SendDelayedTransaction(*hash)
+		}
+	}},
+	{"get-view-key", "private view key of the container", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.walletd().GetViewKey() }
+	}},
+	{"get-mnemonic-seed", "mnemonic seed of an address", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		address := fs.String("address", "", "wallet address")
+		return func() (interface{}, error) {
+			if err := requireFlag("address", *address); err != nil {
+				return nil, err
+			}
+			return cfg.walletd().GetMnemonicSeed(*address)
+		}
+	}},
+	{"get-status", "sync status of the wallet", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.walletd().GetStatus() }
+	}},
+	{"get-addresses", "addresses of the container", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.walletd().GetAddresses() }
+	}},
+	{"send-fusion-transaction", "send a fusion transaction", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		threshold := amount(0)
+		fs.Var(&threshold, "threshold", "optimize outputs below this amount in TRTL")
+		var addresses stringList
+		fs.Var(&addresses, "address", "source address, can be repeated")
+		destination := fs.String("destination", "", "address receiving the optimized outputs")
+		return func() (interface{}, error) {
+			return cfg.walletd().SendFusionTransaction(int(threshold), addresses, *destination)
+		}
+	}},
+	{"estimate-fusion", "number of outputs which can be optimized", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		threshold := amount(0)
+		fs.Var(&threshold, "threshold", "count outputs below this amount in TRTL")
+		var addresses stringList
+		fs.Var(&addresses, "address", "address to estimate, can be repeated")
+		return func() (interface{}, error) { return cfg.walletd().EstimateFusion(int(threshold), addresses) }
+	}},
+	{"create-integrated-address", "integrated address of an address and payment id", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		address := fs.String("address", "", "wallet address")
+		paymentID := fs.String("payment-id", "", "payment id")
+		return func() (interface{}, error) {
+			return cfg.walletd().CreateIntegratedAddress(*address, *paymentID)
+		}
+	}},
+	{"get-fee-info", "fee of the connected node", func(fs *flag.FlagSet, cfg *config) func() (interface{}, error) {
+		return func() (interface{}, error) { return cfg.walletd().GetFeeInfo() }
+	}},
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package confirmations
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/turtlecoin/turtlecoin-rpc-go/internal/storage"
+	bolt "go.etcd.io/bbolt"
+)
+
+var watchesBucket = []byte("watches")
+
+// Watch is a transaction followed by a ConfirmationTracker. Height is
+// the height of the block holding the transaction, and LastSeen the
+// last time the daemon or the wallet knew the transaction.
+type Watch struct {
+	Hash          string    `json:"hash"`
+	State         State     `json:"state"`
+	Height        int       `json:"height"`
+	Confirmations int       `json:"confirmations"`
+	UnlockTime    uint64    `json:"unlockTime"`
+	Added         time.Time `json:"added"`
+	LastSeen      time.Time `json:"lastSeen"`
+}
+
+// Store persists the watch list of a ConfirmationTracker,
+// so it survives restarts. A Store must be safe for
+// concurrent use.
+type Store interface {
+	// Watches returns the watched transactions by hash
+	Watches() ([]*Watch, error)
+	// PutWatch stores the watch, replacing the one with the same hash
+	PutWatch(watch *Watch) error
+	// DeleteWatch removes the watch of the transaction, if any
+	DeleteWatch(hash string) error
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// MemoryStore is a Store keeping the watches in a map,
+// it is lost when the process exits
+type MemoryStore struct {
+	mutex   sync.RWMutex
+	watches map[string]Watch
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{watches: make(map[string]Watch)}
+}
+
+// Watches returns the watched transactions by hash
+func (store *MemoryStore) Watches() ([]*Watch, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	watches := make([]*Watch, 0, len(store.watches))
+	for _, watch := range store.watches {
+		copied := watch
+		watches = append(watches, &copied)
+	}
+	sort.Slice(watches, func(i, j int) bool {
+		return watches[i].Hash < watches[j].Hash
+	})
+	return watches, nil
+}
+
+// PutWatch stores the watch, replacing the one with the same hash
+func (store *MemoryStore) PutWatch(watch *Watch) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.watches[watch.Hash] = *watch
+	return nil
+}
+
+// DeleteWatch removes the watch of the transaction, if any
+func (store *MemoryStore) DeleteWatch(hash string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.watches, hash)
+	return nil
+}
+
+// Close does nothing for a MemoryStore
+func (store *MemoryStore) Close() error {
+	return nil
+}
+
+// BoltStore is a Store kept in a bbolt database
+// file, with the watches keyed by hash
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens the database file at path, creating it if needed
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(watchesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db}, nil
+}
+
+// Watches returns the watched transactions by hash
+func (store *BoltStore) Watches() ([]*Watch, error) {
+	var watches []*Watch
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(watchesBucket).ForEach(func(key []byte, value []byte) error {
+			var watch Watch
+			if err := json.Unmarshal(value, &watch); err != nil {
+				return err
+			}
+			watches = append(watches, &watch)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return watches, nil
+}
+
+// PutWatch stores the watch, replacing the one with the same hash
+func (store *BoltStore) PutWatch(watch *Watch) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return storage.PutJSON(tx.Bucket(watchesBucket), []byte(watch.Hash), watch)
+	})
+}
+
+// DeleteWatch removes the watch of the transaction, if any
+func (store *BoltStore) DeleteWatch(hash string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(watchesBucket).Delete([]byte(hash))
+	})
+}
+
+// Close closes the database file
+func (store *BoltStore) Close() error {
+	return store.db.Close()
+}
@@ -0,0 +1,365 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+// Package confirmations follows transactions until they are buried under
+// enough blocks and unlocked, or dropped. The watched transactions are
+// kept in a Store so they are followed across restarts.
+package confirmations
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+)
+
+// maxBlockNumber is the largest unlock time read as a block
+// height, larger unlock times are unix timestamps
+const maxBlockNumber = 500000000
+
+// daemonWrongParam is the error code of f_transaction_json
+// for transactions unknown to the daemon
+const daemonWrongParam = -1
+
+// lastSeenInterval is the age of the stored LastSeen of a watch
+// after which it is stored again, so that following a transaction
+// does not write to the store at every poll
+const lastSeenInterval = time.Minute
+
+// State is the progress of a watched transaction
+type State int
+
+const (
+	// Pending is a transaction waiting in the pool or in the
+	// wallet, not seen yet, or whose block left the chain
+	Pending State = iota
+	// InBlock is a transaction in the top block
+	InBlock
+	// Confirmed is a transaction covered by later blocks,
+	// but not finalized yet
+	Confirmed
+	// Finalized is a transaction with enough confirmations whose
+	// outputs are unlocked. It is no longer watched.
+	Finalized
+	// Dropped is a transaction unknown to the daemon and the wallet
+	// for DropAfter. It is no longer watched.
+	Dropped
+)
+
+// String returns the name of the state
+func (state State) String() string {
+	switch state {
+	case Pending:
+		return "pending"
+	case InBlock:
+		return "in-block"
+	case Confirmed:
+		return "confirmed"
+	case Finalized:
+		return "finalized"
+	case Dropped:
+		return "dropped"
+	}
+	return "unknown"
+}
+
+// Event is a change of the state of a watched transaction. Confirmed
+// events are sent again every time the confirmations grow.
+type Event struct {
+	Hash          string
+	State         State
+	Height        int
+	Confirmations int
+	UnlockTime    uint64
+}
+
+// ConfirmationTracker follows the watched transactions with the daemon,
+// and with the wallet when set, so the transactions still only known by
+// the wallet are not taken as dropped. A transaction is finalized once it
+// has Confirmations confirmations and its unlock time has passed, as a
+// height or a timestamp.
+//
+// OnEvent is called for every change before the change is stored, so an
+// event may be sent again after a crash but is never lost. A poll sends
+// the last state of each transaction only, so states may be skipped.
+type ConfirmationTracker struct {
+	Confirmations int
+	DropAfter     time.Duration
+	PollInterval  time.Duration
+	OnEvent       func(event Event)
+
+	daemon *trpc.TurtleCoind
+	wallet *trpc.WalletAPI
+	store  Store
+}
+
+// NewConfirmationTracker returns a tracker following the transactions of
+// the store with the daemon and the wallet. The wallet may be nil.
+func NewConfirmationTracker(daemon *trpc.TurtleCoind, wallet *trpc.WalletAPI, store Store) *ConfirmationTracker {
+	return &ConfirmationTracker{
+		Confirmations: 10,
+		DropAfter:     10 * time.Minute,
+		PollInterval:  trpc.DefaultPollInterval,
+		daemon:        daemon,
+		wallet:        wallet,
+		store:         store,
+	}
+}
+
+// Store returns the store of the tracker
+func (tracker *ConfirmationTracker) Store() Store {
+	return tracker.store
+}
+
+// Watch adds the transaction to the watch list, it does
+// nothing if the transaction is already watched
+func (tracker *ConfirmationTracker) Watch(hash string) error {
+	if hash == "" {
+		return errors.New("Transaction hash is required")
+	}
+
+	watches, err := tracker.store.Watches()
+	if err != nil {
+		return err
+	}
+	for _, watch := range watches {
+		if watch.Hash == hash {
+			return nil
+		}
+	}
+
+	now := time.Now()
+	return tracker.store.PutWatch(&Watch{Hash: hash, State: Pending, Added: now, LastSeen: now})
+}
+
+// Unwatch removes the transaction from the watch list
+func (tracker *ConfirmationTracker) Unwatch(hash string) error {
+	return tracker.store.DeleteWatch(hash)
+}
+
+// Run polls the watched transactions every PollInterval
+// until the context is cancelled or a poll fails
+func (tracker *ConfirmationTracker) Run(ctx context.Context) error {
+	for {
+		if _, err := tracker.Poll(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(tracker.PollInterval):
+		}
+	}
+}
+
+// Poll checks every watched transaction once and returns the
+// changes. Finalized and dropped transactions are unwatched.
+func (tracker *ConfirmationTracker) Poll() ([]Event, error) {
+	count, err := tracker.blockCount()
+	if err != nil {
+		return nil, err
+	}
+	watches, err := tracker.store.Watches()
+	if err != nil {
+		return nil, err
+	}
+	unconfirmed, err := tracker.unconfirmedHashes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var events []Event
+	for _, watch := range watches {
+		found, height, unlockTime, err := tracker.lookup(watch.Hash, unconfirmed)
+		if err != nil {
+			return events, err
+		}
+
+		previous := *watch
+		switch {
+		case !found && (watch.State == InBlock || watch.State == Confirmed):
+			// The block of the transaction was orphaned, it is
+			// dropped if it does not come back within DropAfter
+			watch.State = Pending
+			watch.Height = 0
+			watch.Confirmations = 0
+		case !found:
+			if now.Sub(watch.LastSeen) < tracker.DropAfter {
+				continue
+			}
+			watch.State = Dropped
+		case height < 0:
+			watch.State = Pending
+			watch.Height = 0
+			watch.Confirmations = 0
+		default:
+			watch.Height = height
+			watch.Confirmations = count - height
+			if watch.Confirmations < 1 {
+				watch.Confirmations = 1
+			}
+			switch {
+			case watch.Confirmations >= tracker.Confirmations && unlocked(unlockTime, count, now):
+				watch.State = Finalized
+			case watch.Confirmations == 1:
+				watch.State = InBlock
+			default:
+				watch.State = Confirmed
+			}
+		}
+		if found {
+			watch.UnlockTime = unlockTime
+			watch.LastSeen = now
+		}
+
+		if watch.State != previous.State || (watch.State == Confirmed && watch.Confirmations != previous.Confirmations) {
+			event := Event{
+				Hash:          watch.Hash,
+				State:         watch.State,
+				Height:        watch.Height,
+				Confirmations: watch.Confirmations,
+				UnlockTime:    watch.UnlockTime,
+			}
+			if tracker.OnEvent != nil {
+				tracker.OnEvent(event)
+			}
+			events = append(events, event)
+		}
+
+		switch {
+		case watch.State == Finalized || watch.State == Dropped:
+			err = tracker.store.DeleteWatch(watch.Hash)
+		case watch.State != previous.State || watch.Height != previous.Height ||
+			watch.Confirmations != previous.Confirmations || watch.UnlockTime != previous.UnlockTime ||
+			now.Sub(previous.LastSeen) >= lastSeenInterval:
+			err = tracker.store.PutWatch(watch)
+		}
+		if err != nil {
+			return events, err
+		}
+	}
+	return events, nil
+}
+
+// lookup reports whether the daemon or the wallet knows the transaction,
+// and returns the height of its block, or -1, along with its unlock time
+func (tracker *ConfirmationTracker) lookup(hash string, unconfirmed map[string]bool) (bool, int, uint64, error) {
+	resp, err := tracker.daemon.GetTransaction(hash)
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	// The daemon answers with a wrong param error for unknown
+	// transactions, any other error is returned
+	found := false
+	var unlockTime uint64
+	result, err := trpc.RPCResult(resp)
+	var rpcError *trpc.RPCError
+	switch {
+	case err == nil:
+		var tx struct {
+			Block struct {
+				Hash   string `json:"hash"`
+				Height int    `json:"height"`
+			} `json:"block"`
+			Tx struct {
+				UnlockTime uint64 `json:"unlock_time"`
+			} `json:"tx"`
+		}
+		if err = trpc.ConvertResponse(result, &tx); err != nil {
+			return false, 0, 0, err
+		}
+		if tx.Block.Hash != "" {
+			return true, tx.Block.Height, tx.Tx.UnlockTime, nil
+		}
+		found = true
+		unlockTime = tx.Tx.UnlockTime
+	case !errors.As(err, &rpcError) || rpcError.Code != daemonWrongParam:
+		return false, 0, 0, err
+	}
+
+	if tracker.wallet == nil {
+		return found, -1, unlockTime, nil
+	}
+	// The wallet answers with a not found status for unknown transactions
+	resp, err = tracker.wallet.GetTransactionDetails(hash)
+	var statusError *trpc.StatusError
+	switch {
+	case err == nil:
+		var result struct {
+			Transaction struct {
+				BlockHeight int    `json:"blockHeight"`
+				UnlockTime  uint64 `json:"unlockTime"`
+			} `json:"transaction"`
+		}
+		if err = trpc.ConvertResponse(resp, &result); err != nil {
+			return false, 0, 0, err
+		}
+		if result.Transaction.BlockHeight > 0 {
+			return true, result.Transaction.BlockHeight, result.Transaction.UnlockTime, nil
+		}
+		return true, -1, result.Transaction.UnlockTime, nil
+	case !errors.As(err, &statusError) || statusError.StatusCode != http.StatusNotFound:
+		return false, 0, 0, err
+	}
+	return found || unconfirmed[hash], -1, unlockTime, nil
+}
+
+// blockCount returns the number of blocks of the daemon
+func (tracker *ConfirmationTracker) blockCount() (int, error) {
+	resp, err := tracker.daemon.GetBlockCount()
+	if err != nil {
+		return 0, err
+	}
+	count, err := trpc.RPCResult(resp)
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Count int `json:"count"`
+	}
+	if err = trpc.ConvertResponse(count, &result); err != nil {
+		return 0, err
+	}
+	return result.Count, nil
+}
+
+// unconfirmedHashes returns the hashes of the unconfirmed
+// transactions of the wallet, none without a wallet
+func (tracker *ConfirmationTracker) unconfirmedHashes() (map[string]bool, error) {
+	hashes := make(map[string]bool)
+	if tracker.wallet == nil {
+		return hashes, nil
+	}
+
+	resp, err := tracker.wallet.UnconfirmedTransactions("")
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Transactions []struct {
+			Hash string `json:"hash"`
+		} `json:"transactions"`
+	}
+	if err = trpc.ConvertResponse(resp, &result); err != nil {
+		return nil, err
+	}
+	for _, tx := range result.Transactions {
+		hashes[tx.Hash] = true
+	}
+	return hashes, nil
+}
+
+// unlocked reports whether outputs with the unlock time can be
+// spent with count blocks in the chain at the time now
+func unlocked(unlockTime uint64, count int, now time.Time) bool {
+	if unlockTime < maxBlockNumber {
+		return uint64(count) >= unlockTime
+	}
+	return uint64(now.Unix()) >= unlockTime
+}
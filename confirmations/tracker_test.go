@@ -0,0 +1,273 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package confirmations
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+)
+
+// testTx is a transaction of a testDaemon, in the pool when height is -1
+type testTx struct {
+	height     int
+	unlockTime uint64
+}
+
+// testDaemon answers getblockcount and f_transaction_json from its
+// fields, or with a busy error when busy is set
+type testDaemon struct {
+	mu    sync.Mutex
+	count int
+	txs   map[string]testTx
+	busy  bool
+}
+
+func newTestDaemon(t *testing.T) (*testDaemon, *trpc.TurtleCoind) {
+	daemon := &testDaemon{count: 100, txs: make(map[string]testTx)}
+	server := httptest.NewServer(http.HandlerFunc(daemon.serveHTTP))
+	t.Cleanup(server.Close)
+
+	addr := strings.TrimPrefix(server.URL, "http://")
+	i := strings.LastIndex(addr, ":")
+	port, _ := strconv.Atoi(addr[i+1:])
+	return daemon, &trpc.TurtleCoind{URL: addr[:i], Port: port}
+}
+
+func (daemon *testDaemon) set(hash string, height int, unlockTime uint64) {
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+	daemon.txs[hash] = testTx{height, unlockTime}
+}
+
+func (daemon *testDaemon) remove(hash string) {
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+	delete(daemon.txs, hash)
+}
+
+func (daemon *testDaemon) mine(blocks int) {
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+	daemon.count += blocks
+}
+
+func (daemon *testDaemon) setBusy(busy bool) {
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+	daemon.busy = busy
+}
+
+func (daemon *testDaemon) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Method string `json:"method"`
+		Params struct {
+			Hash string `json:"hash"`
+		} `json:"params"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+	resp := map[string]interface{}{"jsonrpc": "2.0"}
+	tx, ok := daemon.txs[req.Params.Hash]
+	switch {
+	case req.Method == "getblockcount":
+		resp["result"] = map[string]interface{}{"count": daemon.count, "status": "OK"}
+	case daemon.busy:
+		resp["error"] = map[string]interface{}{"code": -9, "message": "Core is busy"}
+	case !ok:
+		resp["error"] = map[string]interface{}{"code": -1, "message": "transaction wasn't found. Hash = " + req.Params.Hash + "."}
+	default:
+		block := map[string]interface{}{}
+		if tx.height >= 0 {
+			block = map[string]interface{}{"hash": strings.Repeat("ab", 32), "height": tx.height}
+		}
+		resp["result"] = map[string]interface{}{
+			"block":  block,
+			"tx":     map[string]interface{}{"unlock_time": tx.unlockTime},
+			"status": "OK",
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// poll polls the tracker and returns the states of the events
+func poll(t *testing.T, tracker *ConfirmationTracker) []string {
+	t.Helper()
+	events, err := tracker.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var states []string
+	for _, event := range events {
+		states = append(states, event.State.String()+"("+strconv.Itoa(event.Confirmations)+")")
+	}
+	return states
+}
+
+func checkStates(t *testing.T, step string, got []string, want ...string) {
+	t.Helper()
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("%s: events %v, want %v", step, got, want)
+	}
+}
+
+func TestTrackerStates(t *testing.T) {
+	daemon, client := newTestDaemon(t)
+	tracker := NewConfirmationTracker(client, nil, NewMemoryStore())
+	tracker.Confirmations = 3
+	if err := tracker.Watch("tx"); err != nil {
+		t.Fatal(err)
+	}
+
+	daemon.set("tx", -1, 0)
+	checkStates(t, "in the pool", poll(t, tracker))
+	daemon.set("tx", 100, 0)
+	daemon.mine(1)
+	checkStates(t, "in the top block", poll(t, tracker), "in-block(1)")
+	checkStates(t, "same top block", poll(t, tracker))
+	daemon.mine(1)
+	checkStates(t, "one block later", poll(t, tracker), "confirmed(2)")
+	daemon.mine(1)
+	checkStates(t, "two blocks later", poll(t, tracker), "finalized(3)")
+
+	if watches, _ := tracker.Store().Watches(); len(watches) != 0 {
+		t.Errorf("finalized transaction still watched: %+v", watches[0])
+	}
+}
+
+func TestTrackerUnlockTime(t *testing.T) {
+	daemon, client := newTestDaemon(t)
+	tracker := NewConfirmationTracker(client, nil, NewMemoryStore())
+	tracker.Confirmations = 1
+	tracker.Watch("tx")
+
+	// Confirmed enough but locked until the block 105
+	daemon.set("tx", 90, 105)
+	checkStates(t, "locked", poll(t, tracker), "confirmed(10)")
+	daemon.mine(5)
+	checkStates(t, "unlocked", poll(t, tracker), "finalized(15)")
+
+	now := time.Unix(1600000000, 0)
+	tests := []struct {
+		unlockTime uint64
+		count      int
+		unlocked   bool
+	}{
+		{0, 1, true},
+		{105, 104, false},
+		{105, 105, true},
+		{maxBlockNumber - 1, 100, false},
+		{uint64(now.Unix()) + 1, 100, false},
+		{uint64(now.Unix()), 100, true},
+		{uint64(now.Unix()) - 3600, 1 << 30, true},
+	}
+	for _, test := range tests {
+		if unlocked(test.unlockTime, test.count, now) != test.unlocked {
+			t.Errorf("unlocked(%d, %d) = %v", test.unlockTime, test.count, !test.unlocked)
+		}
+	}
+}
+
+func TestTrackerDrop(t *testing.T) {
+	_, client := newTestDaemon(t)
+	store := NewMemoryStore()
+	tracker := NewConfirmationTracker(client, nil, store)
+	tracker.DropAfter = time.Hour
+
+	now := time.Now()
+	store.PutWatch(&Watch{Hash: "recent", Added: now, LastSeen: now.Add(-time.Hour + time.Minute)})
+	store.PutWatch(&Watch{Hash: "old", Added: now, LastSeen: now.Add(-time.Hour - time.Minute)})
+
+	events, err := tracker.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Hash != "old" || events[0].State != Dropped {
+		t.Errorf("events = %+v, want old dropped", events)
+	}
+	if watches, _ := store.Watches(); len(watches) != 1 || watches[0].Hash != "recent" {
+		t.Errorf("watches = %+v, want recent", watches)
+	}
+}
+
+func TestTrackerDaemonErrors(t *testing.T) {
+	daemon, client := newTestDaemon(t)
+	store := NewMemoryStore()
+	tracker := NewConfirmationTracker(client, nil, store)
+	tracker.DropAfter = time.Minute
+
+	// A busy daemon neither drops nor moves a transaction seen long ago
+	daemon.set("tx", 95, 0)
+	tracker.Watch("tx")
+	checkStates(t, "confirmed", poll(t, tracker), "confirmed(5)")
+	watches, _ := store.Watches()
+	watches[0].LastSeen = time.Now().Add(-time.Hour)
+	store.PutWatch(watches[0])
+
+	daemon.setBusy(true)
+	if _, err := tracker.Poll(); err == nil || err.Error() != "Core is busy" {
+		t.Errorf("err = %v, want Core is busy", err)
+	}
+	if watches, _ = store.Watches(); len(watches) != 1 || watches[0].State != Confirmed {
+		t.Errorf("watches = %+v, want tx confirmed", watches)
+	}
+}
+
+func TestTrackerReorg(t *testing.T) {
+	daemon, client := newTestDaemon(t)
+	tracker := NewConfirmationTracker(client, nil, NewMemoryStore())
+	tracker.DropAfter = time.Hour
+	tracker.Watch("tx")
+
+	daemon.set("tx", 98, 0)
+	checkStates(t, "confirmed", poll(t, tracker), "confirmed(2)")
+
+	// The block is orphaned and the transaction unknown for
+	// a while, then it is mined again in another block
+	daemon.remove("tx")
+	checkStates(t, "orphaned", poll(t, tracker), "pending(0)")
+	checkStates(t, "still unknown", poll(t, tracker))
+	daemon.set("tx", 99, 0)
+	checkStates(t, "mined again", poll(t, tracker), "in-block(1)")
+}
+
+func TestTrackerRestart(t *testing.T) {
+	daemon, client := newTestDaemon(t)
+	path := filepath.Join(t.TempDir(), "watches.db")
+	store, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := NewConfirmationTracker(client, nil, store)
+	tracker.Confirmations = 5
+	tracker.Watch("tx")
+	daemon.set("tx", 97, 0)
+	checkStates(t, "before the restart", poll(t, tracker), "confirmed(3)")
+	if err = store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The state survives, so only the new confirmations are sent
+	if store, err = OpenBoltStore(path); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	tracker = NewConfirmationTracker(client, nil, store)
+	tracker.Confirmations = 5
+	checkStates(t, "after the restart", poll(t, tracker))
+	daemon.mine(1)
+	checkStates(t, "one block later", poll(t, tracker), "confirmed(4)")
+	daemon.mine(1)
+	checkStates(t, "two blocks later", poll(t, tracker), "finalized(5)")
+}
@@ -0,0 +1,350 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package cryptonote
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+)
+
+// MergeMiningMajorVersion is the first block major version with
+// a parent block, which holds the timestamp and nonce of the block
+const MergeMiningMajorVersion = 2
+
+// maxBlockchainBranch is the deepest merge mining tag allowed
+const maxBlockchainBranch = 8 * 32
+
+// BlockHeader holds the header fields of a block. For blocks with a
+// parent block, Timestamp and Nonce are serialized in the parent block.
+type BlockHeader struct {
+	MajorVersion      uint64
+	MinorVersion      uint64
+	Timestamp         uint64
+	PreviousBlockHash Key
+	Nonce             uint32
+}
+
+// ParentBlock is the block of the merge mined chain whose coinbase
+// transaction commits to the block with a merge mining tag
+type ParentBlock struct {
+	MajorVersion          uint64
+	MinorVersion          uint64
+	PreviousBlockHash     Key
+	TransactionCount      uint64
+	BaseTransactionBranch []Key
+	BaseTransaction       Transaction
+	BlockchainBranch      []Key
+}
+
+// Block is a block along with the hashes of its transactions
+type Block struct {
+	BlockHeader
+	ParentBlock       ParentBlock
+	BaseTransaction   Transaction
+	TransactionHashes []Key
+}
+
+// ParseBlock decodes a binary block, such as the blocktemplate_blob
+// returned by GetBlockTemplate
+func ParseBlock(blob []byte) (*Block, error) {
+	r := &reader{data: blob}
+	block, err := r.block()
+	if err != nil {
+		return nil, err
+	}
+	if r.remaining() != 0 {
+		return nil, errors.New("Unexpected data after the block")
+	}
+	return block, nil
+}
+
+// ParseBlockHex decodes a hex block
+func ParseBlockHex(s string) (*Block, error) {
+	blob, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBlock(blob)
+}
+
+// Bytes returns the binary encoding of the block, as taken by SubmitBlock
+func (block *Block) Bytes() []byte {
+	b := block.appendHeader(nil)
+	if block.MajorVersion >= MergeMiningMajorVersion {
+		b = block.appendParent(b, false, false)
+	}
+	b = block.BaseTransaction.appendTo(b)
+
+	b = AppendVarint(b, uint64(len(block.TransactionHashes)))
+	for _, hash := range block.TransactionHashes {
+		b = append(b, hash[:]...)
+	}
+	return b
+}
+
+// Hex returns the hex encoding of the block
+func (block *Block) Hex() string {
+	return hex.EncodeToString(block.Bytes())
+}
+
+// MerkleRoot returns the root of the tree of the hashes
+// of the coinbase transaction and the other transactions
+func (block *Block) MerkleRoot() Key {
+	hashes := append([]Key{block.BaseTransaction.Hash()}, block.TransactionHashes...)
+	return TreeHash(hashes)
+}
+
+// HeaderHashingBlob returns the header of the block followed by
+// its Merkle root and its number of transactions
+func (block *Block) HeaderHashingBlob() []byte {
+	root := block.MerkleRoot()
+	b := block.appendHeader(nil)
+	b = append(b, root[:]...)
+	return AppendVarint(b, uint64(len(block.TransactionHashes)+1))
+}
+
+// HashingBlob returns the blob hashed by the proof of work. Blocks
+// with a parent block are mined through the header of the parent.
+func (block *Block) HashingBlob() []byte {
+	if block.MajorVersion < MergeMiningMajorVersion {
+		return block.HeaderHashingBlob()
+	}
+	return block.appendParent(nil, true, true)
+}
+
+// Hash returns the hash identifying the block
+func (block *Block) Hash() Key {
+	b := block.HeaderHashingBlob()
+	if block.MajorVersion >= MergeMiningMajorVersion {
+		b = block.appendParent(b, true, false)
+	}
+	return Keccak(AppendVarint(nil, uint64(len(b))), b)
+}
+
+// SetExtraNonce writes data at the start of the first extra nonce of
+// the coinbase transaction, which is the area reserved by the reserve
+// size given to GetBlockTemplate. The merge mining tag of the parent
+// block is updated to the new content of the block.
+func (block *Block) SetExtraNonce(data []byte) error {
+	extra, err := ParseExtra(block.BaseTransaction.Extra)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, field := range extra {
+		if nonce, ok := field.(ExtraNonce); ok {
+			if len(data) > len(nonce.Data) {
+				return errors.New("Extra nonce is larger than the reserved area")
+			}
+			copy(nonce.Data, data)
+			found = true
+			break
+		}
+	}
+	if !found {
+		return errors.New("Coinbase transaction has no reserved area")
+	}
+	if block.BaseTransaction.Extra, err = extra.Bytes(); err != nil {
+		return err
+	}
+
+	if block.MajorVersion >= MergeMiningMajorVersion {
+		return block.UpdateMergeMiningTag()
+	}
+	return nil
+}
+
+// UpdateMergeMiningTag points the merge mining tag in the coinbase
+// transaction of the parent block at the current content of the block.
+// Only tags without auxiliary chains, as in the templates of the
+// daemon, are supported.
+func (block *Block) UpdateMergeMiningTag() error {
+	extra, err := ParseExtra(block.ParentBlock.BaseTransaction.Extra)
+	if err != nil {
+		return err
+	}
+
+	header := block.HeaderHashingBlob()
+	for i, field := range extra {
+		if tag, ok := field.(ExtraMergeMiningTag); ok {
+			if tag.Depth != 0 || len(block.ParentBlock.BlockchainBranch) != 0 {
+				return errors.New("Only merge mining tags of depth 0 can be updated")
+			}
+			tag.MerkleRoot = Keccak(AppendVarint(nil, uint64(len(header))), header)
+			extra[i] = tag
+			block.ParentBlock.BaseTransaction.Extra, err = extra.Bytes()
+			return err
+		}
+	}
+	return errors.New("Parent block has no merge mining tag")
+}
+
+// SetReserved writes data into a block template blob at the
+// reserved_offset returned by GetBlockTemplate. It only suits blocks
+// without a parent block, see Block.SetExtraNonce otherwise.
+func SetReserved(blob []byte, offset int, data []byte) error {
+	if offset < 0 || offset+len(data) > len(blob) {
+		return errors.New("Reserved area is out of the blob")
+	}
+	copy(blob[offset:], data)
+	return nil
+}
+
+func (block *Block) appendHeader(b []byte) []byte {
+	b = AppendVarint(b, block.MajorVersion)
+	b = AppendVarint(b, block.MinorVersion)
+	if block.MajorVersion < MergeMiningMajorVersion {
+		b = AppendVarint(b, block.Timestamp)
+		b = append(b, block.PreviousBlockHash[:]...)
+		return binary.LittleEndian.AppendUint32(b, block.Nonce)
+	}
+	return append(b, block.PreviousBlockHash[:]...)
+}
+
+// appendParent serializes the parent block. The hashing form replaces
+// the coinbase transaction with the Merkle root of the parent block,
+// and the header only form stops after the number of transactions.
+func (block *Block) appendParent(b []byte, hashing bool, headerOnly bool) []byte {
+	parent := &block.ParentBlock
+	b = AppendVarint(b, parent.MajorVersion)
+	b = AppendVarint(b, parent.MinorVersion)
+	b = AppendVarint(b, block.Timestamp)
+	b = append(b, parent.PreviousBlockHash[:]...)
+	b = binary.LittleEndian.AppendUint32(b, block.Nonce)
+
+	if hashing {
+		root := TreeHashFromBranch(parent.BaseTransactionBranch, parent.BaseTransaction.Hash())
+		b = append(b, root[:]...)
+	}
+	b = AppendVarint(b, parent.TransactionCount)
+	if headerOnly {
+		return b
+	}
+
+	for _, hash := range parent.BaseTransactionBranch {
+		b = append(b, hash[:]...)
+	}
+	b = parent.BaseTransaction.appendTo(b)
+	for _, hash := range parent.BlockchainBranch {
+		b = append(b, hash[:]...)
+	}
+	return b
+}
+
+func (r *reader) block() (*Block, error) {
+	var block Block
+	var err error
+	if block.MajorVersion, err = r.varint(); err != nil {
+		return nil, err
+	}
+	if block.MinorVersion, err = r.varint(); err != nil {
+		return nil, err
+	}
+
+	if block.MajorVersion < MergeMiningMajorVersion {
+		if block.Timestamp, err = r.varint(); err != nil {
+			return nil, err
+		}
+		if block.PreviousBlockHash, err = r.key(); err != nil {
+			return nil, err
+		}
+		if block.Nonce, err = r.nonce(); err != nil {
+			return nil, err
+		}
+	} else {
+		if block.PreviousBlockHash, err = r.key(); err != nil {
+			return nil, err
+		}
+		if err = r.parentBlock(&block); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := r.transaction()
+	if err != nil {
+		return nil, err
+	}
+	block.BaseTransaction = *tx
+
+	count, err := r.count(32)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < count; i++ {
+		hash, _ := r.key()
+		block.TransactionHashes = append(block.TransactionHashes, hash)
+	}
+	return &block, nil
+}
+
+func (r *reader) parentBlock(block *Block) error {
+	parent := &block.ParentBlock
+	var err error
+	if parent.MajorVersion, err = r.varint(); err != nil {
+		return err
+	}
+	if parent.MinorVersion, err = r.varint(); err != nil {
+		return err
+	}
+	if block.Timestamp, err = r.varint(); err != nil {
+		return err
+	}
+	if parent.PreviousBlockHash, err = r.key(); err != nil {
+		return err
+	}
+	if block.Nonce, err = r.nonce(); err != nil {
+		return err
+	}
+
+	if parent.TransactionCount, err = r.varint(); err != nil {
+		return err
+	}
+	if parent.TransactionCount < 1 {
+		return errors.New("Parent block has no transaction")
+	}
+	if parent.BaseTransactionBranch, err = r.keys(TreeDepth(parent.TransactionCount)); err != nil {
+		return err
+	}
+
+	tx, err := r.transaction()
+	if err != nil {
+		return err
+	}
+	parent.BaseTransaction = *tx
+
+	extra, err := ParseExtra(tx.Extra)
+	if err != nil {
+		return err
+	}
+	tag, ok := extra.MergeMiningTag()
+	if !ok {
+		return errors.New("Parent block has no merge mining tag")
+	}
+	if tag.Depth > maxBlockchainBranch {
+		return errors.New("Merge mining tag is too deep")
+	}
+	parent.BlockchainBranch, err = r.keys(int(tag.Depth))
+	return err
+}
+
+func (r *reader) nonce() (uint32, error) {
+	raw, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(raw), nil
+}
+
+func (r *reader) keys(n int) ([]Key, error) {
+	if n > r.remaining()/32 {
+		return nil, ErrUnexpectedEnd
+	}
+	keys := make([]Key, n)
+	for i := range keys {
+		keys[i], _ = r.key()
+	}
+	return keys, nil
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package cryptonote
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// genesisBlock is the genesis block of the TurtleCoin mainnet, made by
+// the daemon from its genesis coinbase transaction with a zero
+// timestamp and the nonce 70. Its hash is the checkpoint at height 0.
+const (
+	genesisBlock = "010000000000000000000000000000000000000000000000000000000000000000000046000000010a01ff000188f3b501029b2e4c0281c0b02e7c53291a94d1d0cbff8883f8024f5142ee494ffbbd088071210142694232c5b04151d9e4c27d31ec7a68ea568b19488cfcb422659a07a0e44dd500"
+	genesisHash  = "7fb97df81221dd1366051b2d0bc7f49c66c22ac4431d879c895b06d66ef66f4c"
+)
+
+// mergeMinedBlock is a version 4 block on top of the genesis block,
+// with two transactions and a parent block of three transactions. It
+// was serialized and hashed by an implementation of the reference
+// serialization independent of this package, as is mergeMinedNonce,
+// the same block with deadbeef at the start of the extra nonce.
+var (
+	mergeMinedBlock = mergeMinedVector{
+		blob:    "04007fb97df81221dd1366051b2d0bc7f49c66c22ac4431d879c895b06d66ef66f4c010080a0f8fa0560685bb7750ce95aefa035a20c7c7f440450aebbc751715a54497311d23bb01501020304038c63909ede07b442b5a31de4cee658ff436d1ffd7afafc88205bc364ac49ee66010001ff00002303210010bef5a302c8c59035c225ccdcc872904231667b22906ba1c0276a57d53b7c7a012801ff2701c9ede08a0b022798c2e092278bc6fe6052ebcf6f62507205c96130245a384c3e7911378878202b018608a0cbf31fced1d75250ea10452ad20f20f4b9f63f14811c8af1202eda731502080000000000000000025194ead3df889a15f3d33e47bcc128114dbb9dcd1147f2de8a8ffba6a815f248183a7d361ca1625fa85289cbdf578effaa4376f038587b9ab574e3fe80e5edc5",
+		hash:    "a4df231e98891b9412c82cdb657d1ccf82c9e39e2b081d237825aa8cbdefdb8f",
+		hashing: "010080a0f8fa0560685bb7750ce95aefa035a20c7c7f440450aebbc751715a54497311d23bb01501020304c051aa43cac0486cb5b2a307ddb06157c48d0983b38bc0e607d4a81b923f0c3203",
+		tag:     "10bef5a302c8c59035c225ccdcc872904231667b22906ba1c0276a57d53b7c7a",
+	}
+	mergeMinedNonce = mergeMinedVector{
+		blob:    "04007fb97df81221dd1366051b2d0bc7f49c66c22ac4431d879c895b06d66ef66f4c010080a0f8fa0560685bb7750ce95aefa035a20c7c7f440450aebbc751715a54497311d23bb01501020304038c63909ede07b442b5a31de4cee658ff436d1ffd7afafc88205bc364ac49ee66010001ff0000230321001f4d1ced5dfd7749b5d7d1c25ad89ecabe79214d3f1a6f23bc51cacd2889f16a012801ff2701c9ede08a0b022798c2e092278bc6fe6052ebcf6f62507205c96130245a384c3e7911378878202b018608a0cbf31fced1d75250ea10452ad20f20f4b9f63f14811c8af1202eda73150208deadbeef00000000025194ead3df889a15f3d33e47bcc128114dbb9dcd1147f2de8a8ffba6a815f248183a7d361ca1625fa85289cbdf578effaa4376f038587b9ab574e3fe80e5edc5",
+		hash:    "50a804143087fdbec8d9e84664d96e13b8d2590749f3e9ca56756077a610abbb",
+		hashing: "010080a0f8fa0560685bb7750ce95aefa035a20c7c7f440450aebbc751715a54497311d23bb01501020304ed047f46ebe89b86c7a9e281c3b56d3e1b3d6ea2c8052336cb642e4a885bc06903",
+		tag:     "1f4d1ced5dfd7749b5d7d1c25ad89ecabe79214d3f1a6f23bc51cacd2889f16a",
+	}
+)
+
+type mergeMinedVector struct {
+	blob    string
+	hash    string
+	hashing string
+	tag     string
+}
+
+// check compares the block with the vector
+func (vector mergeMinedVector) check(t *testing.T, name string, block *Block) {
+	t.Helper()
+	if block.Hex() != vector.blob {
+		t.Errorf("%s: encoded as %s", name, block.Hex())
+	}
+	if hash := block.Hash().String(); hash != vector.hash {
+		t.Errorf("%s: hash = %s, want %s", name, hash, vector.hash)
+	}
+	if hashing := hex.EncodeToString(block.HashingBlob()); hashing != vector.hashing {
+		t.Errorf("%s: hashing blob = %s, want %s", name, hashing, vector.hashing)
+	}
+
+	extra, err := ParseExtra(block.ParentBlock.BaseTransaction.Extra)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	header := block.HeaderHashingBlob()
+	tag, _ := extra.MergeMiningTag()
+	if tag.MerkleRoot.String() != vector.tag || tag.MerkleRoot != Keccak(AppendVarint(nil, uint64(len(header))), header) {
+		t.Errorf("%s: merge mining tag = %s, want %s", name, tag.MerkleRoot, vector.tag)
+	}
+}
+
+func TestParseBlockGenesis(t *testing.T) {
+	block, err := ParseBlockHex(genesisBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.MajorVersion != 1 || block.Timestamp != 0 || block.Nonce != 70 || block.PreviousBlockHash != (Key{}) {
+		t.Errorf("header = %+v", block.BlockHeader)
+	}
+	if len(block.TransactionHashes) != 0 || block.BaseTransaction.Inputs[0] != (BaseInput{BlockIndex: 0}) {
+		t.Errorf("block = %+v", block)
+	}
+	if block.Hex() != genesisBlock {
+		t.Errorf("encoded as %s", block.Hex())
+	}
+
+	if block.MerkleRoot() != block.BaseTransaction.Hash() {
+		t.Errorf("Merkle root %s of a single transaction", block.MerkleRoot())
+	}
+	if hex.EncodeToString(block.HashingBlob()) != hex.EncodeToString(block.HeaderHashingBlob()) {
+		t.Error("hashing blob of a version 1 block is not its header hashing blob")
+	}
+	if hash := block.Hash().String(); hash != genesisHash {
+		t.Errorf("hash = %s, want %s", hash, genesisHash)
+	}
+}
+
+func TestParseBlockMergeMined(t *testing.T) {
+	block, err := ParseBlockHex(mergeMinedBlock.blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.MajorVersion != 4 || block.PreviousBlockHash.String() != genesisHash ||
+		block.Timestamp != 1600000000 || block.Nonce != 0x04030201 {
+		t.Errorf("header = %+v", block.BlockHeader)
+	}
+	parent := block.ParentBlock
+	if parent.MajorVersion != 1 || parent.TransactionCount != 3 ||
+		len(parent.BaseTransactionBranch) != 1 || len(parent.BlockchainBranch) != 0 {
+		t.Errorf("parent block = %+v", parent)
+	}
+	if len(block.TransactionHashes) != 2 {
+		t.Errorf("%d transactions, want 2", len(block.TransactionHashes))
+	}
+	mergeMinedBlock.check(t, "parsed", block)
+
+	blob, _ := hex.DecodeString(mergeMinedBlock.blob)
+	for _, n := range []int{1, 40, len(blob) - 1} {
+		if _, err = ParseBlock(blob[:n]); err == nil {
+			t.Errorf("block cut at %d bytes parsed", n)
+		}
+	}
+	if _, err = ParseBlock(append(blob, 0)); err == nil {
+		t.Error("block followed by a byte parsed")
+	}
+}
+
+func TestSetExtraNonce(t *testing.T) {
+	block, _ := ParseBlockHex(mergeMinedBlock.blob)
+	if err := block.SetExtraNonce([]byte{0xde, 0xad, 0xbe, 0xef}); err != nil {
+		t.Fatal(err)
+	}
+	mergeMinedNonce.check(t, "extra nonce set", block)
+
+	if err := block.SetExtraNonce(make([]byte, 9)); err == nil || err.Error() != "Extra nonce is larger than the reserved area" {
+		t.Errorf("oversized nonce: err = %v", err)
+	}
+	genesis, _ := ParseBlockHex(genesisBlock)
+	if err := genesis.SetExtraNonce([]byte{1}); err == nil || err.Error() != "Coinbase transaction has no reserved area" {
+		t.Errorf("block without a reserved area: err = %v", err)
+	}
+}
+
+func TestUpdateMergeMiningTag(t *testing.T) {
+	block, _ := ParseBlockHex(mergeMinedBlock.blob)
+	block.BaseTransaction.Extra[len(block.BaseTransaction.Extra)-1] = 1
+	if err := block.UpdateMergeMiningTag(); err != nil {
+		t.Fatal(err)
+	}
+	if block.Hex() == mergeMinedBlock.blob {
+		t.Error("merge mining tag not updated")
+	}
+	block.BaseTransaction.Extra[len(block.BaseTransaction.Extra)-1] = 0
+	if err := block.UpdateMergeMiningTag(); err != nil {
+		t.Fatal(err)
+	}
+	mergeMinedBlock.check(t, "tag updated back", block)
+
+	block.ParentBlock.BlockchainBranch = []Key{{1}}
+	if err := block.UpdateMergeMiningTag(); err == nil || err.Error() != "Only merge mining tags of depth 0 can be updated" {
+		t.Errorf("tag with auxiliary chains: err = %v", err)
+	}
+	block.ParentBlock.BaseTransaction.Extra = nil
+	if err := block.UpdateMergeMiningTag(); err == nil || err.Error() != "Parent block has no merge mining tag" {
+		t.Errorf("parent block without a tag: err = %v", err)
+	}
+}
+
+// treeHashVectors are the roots of the trees of the first leaves of
+// treeHashLeaves, computed with a port of the reference tree-hash.c
+var treeHashVectors = []string{
+	"5fe7f977e71dba2ea1a68e21057beebb9be2ac30c6410aa38d4f3fbe41dcffd2",
+	"71d8979cbfae9b197a4fbcc7d387b1fae9560e2f284d30b4e90c80f6bc074f57",
+	"dc3dcc4d1222540be7b15924642a61424c3e257f9b431f3a4c1c6caa475cd5d8",
+	"3ef0aafe18dd152bae57740da1e512ec0906e71c61ce5155cbcddfa31084a177",
+	"9971f8909c04a40b5f6bc09253db8969cfb77411e56fc4151bb9d175e5a25cdb",
+}
+
+// treeHashLeaves returns the hashes of the bytes 1 to n
+func treeHashLeaves(n int) []Key {
+	leaves := make([]Key, n)
+	for i := range leaves {
+		leaves[i] = Keccak([]byte{byte(i + 1)})
+	}
+	return leaves
+}
+
+func TestTreeHash(t *testing.T) {
+	if TreeHash(nil) != (Key{}) {
+		t.Errorf("tree hash of no hashes = %s", TreeHash(nil))
+	}
+	for i, want := range treeHashVectors {
+		if root := TreeHash(treeHashLeaves(i + 1)).String(); root != want {
+			t.Errorf("%d hashes: root = %s, want %s", i+1, root, want)
+		}
+	}
+
+	// The branches of the first leaf, the sibling next to the root first
+	l := treeHashLeaves(5)
+	h := func(a, b Key) Key { return Keccak(a[:], b[:]) }
+	branches := [][]Key{
+		{},
+		{l[1]},
+		{h(l[1], l[2])},
+		{h(l[2], l[3]), l[1]},
+		{h(l[2], h(l[3], l[4])), l[1]},
+	}
+	for i, branch := range branches {
+		if depth := TreeDepth(uint64(i + 1)); depth != len(branch) {
+			t.Errorf("%d hashes: depth %d, want %d", i+1, depth, len(branch))
+		}
+		if root := TreeHashFromBranch(branch, l[0]).String(); root != treeHashVectors[i] {
+			t.Errorf("%d hashes: root from the branch = %s, want %s", i+1, root, treeHashVectors[i])
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+// Package cryptonote parses and serializes the binary blobs of the
+// TurtleCoin daemon, such as the hex transactions sent through
+// sendrawtransaction, and computes their hashes.
+package cryptonote
+
+import (
+	"encoding/hex"
+	"errors"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// ErrUnexpectedEnd is returned when a blob ends in the middle of a field
+var ErrUnexpectedEnd = errors.New("Unexpected end of blob")
+
+// ErrInvalidVarint is returned for a varint which overflows 64 bits
+// or is not in its shortest form
+var ErrInvalidVarint = errors.New("Invalid varint")
+
+// Key is a public key, key image or hash
+type Key [32]byte
+
+// Signature is a ring signature element
+type Signature [64]byte
+
+// ParseKey decodes a hex key
+func ParseKey(s string) (Key, error) {
+	var key Key
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		return key, err
+	}
+	if len(raw) != len(key) {
+		return key, errors.New("Key must be 64 hex characters")
+	}
+	copy(key[:], raw)
+	return key, nil
+}
+
+// String returns the hex encoding of the key
+func (key Key) String() string {
+	return hex.EncodeToString(key[:])
+}
+
+// String returns the hex encoding of the signature
+func (signature Signature) String() string {
+	return hex.EncodeToString(signature[:])
+}
+
+// Keccak returns the keccak hash used by CryptoNote, which is
+// the original keccak and not the standardized SHA3-256
+func Keccak(data ...[]byte) Key {
+	hash := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		hash.Write(d)
+	}
+
+	var key Key
+	hash.Sum(key[:0])
+	return key
+}
+
+// AppendVarint appends the varint encoding of v, 7 bits
+// per byte with the high bit set on all but the last byte
+func AppendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// ReadVarint decodes the varint at the start of data
+// and returns it along with the number of bytes read
+func ReadVarint(data []byte) (uint64, int, error) {
+	var v uint64
+	for i, b := range data {
+		if i == 9 && b > 1 {
+			return 0, 0, ErrInvalidVarint
+		}
+		v |= uint64(b&0x7f) << (7 * uint(i))
+		if b < 0x80 {
+			if b == 0 && i > 0 {
+				return 0, 0, ErrInvalidVarint
+			}
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, ErrUnexpectedEnd
+}
+
+// reader reads the fields of a blob in order
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) varint() (uint64, error) {
+	v, n, err := ReadVarint(r.data[r.pos:])
+	r.pos += n
+	return v, err
+}
+
+// count reads a varint counting elements of at least size
+// bytes, which must not be more than the rest of the blob
+func (r *reader) count(size int) (int, error) {
+	n, err := r.varint()
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(r.remaining()/size) {
+		return 0, ErrUnexpectedEnd
+	}
+	return int(n), nil
+}
+
+func (r *reader) byte() (byte, error) {
+	if r.remaining() < 1 {
+		return 0, ErrUnexpectedEnd
+	}
+	r.pos++
+	return r.data[r.pos-1], nil
+}
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if r.remaining() < n {
+		return nil, ErrUnexpectedEnd
+	}
+	r.pos += n
+	return r.data[r.pos-n : r.pos], nil
+}
+
+func (r *reader) key() (Key, error) {
+	var key Key
+	raw, err := r.bytes(len(key))
+	copy(key[:], raw)
+	return key, err
+}
+
+func (r *reader) remaining() int {
+	return len(r.data) - r.pos
+}
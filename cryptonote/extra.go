@@ -0,0 +1,258 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package cryptonote
+
+import (
+	"encoding/hex"
+	"errors"
+)
+
+// Tags of the fields of the extra blob of a transaction
+const (
+	ExtraPaddingTag        = 0x00
+	ExtraPublicKeyTag      = 0x01
+	ExtraNonceTag          = 0x02
+	ExtraMergeMiningTagTag = 0x03
+)
+
+// Size limits of the extra fields
+const (
+	MaxExtraPaddingSize = 255
+	MaxExtraNonceSize   = 255
+)
+
+// ExtraNoncePaymentIDTag marks an extra nonce holding a payment ID
+const ExtraNoncePaymentIDTag = 0x00
+
+// Errors of Extra.Bytes for fields which cannot be encoded
+var (
+	ErrExtraPaddingSize = errors.New("Extra padding size must be between 1 and 255")
+	ErrExtraPaddingLast = errors.New("Extra padding must be the last field")
+	ErrExtraNonceSize   = errors.New("Extra nonce is longer than 255 bytes")
+)
+
+// ExtraField is one of the Extra* field types
+type ExtraField interface {
+	appendTo(b []byte) ([]byte, error)
+}
+
+// ExtraPadding is Size zero bytes, the tag included, running to the
+// end of the extra blob. Size is between 1 and MaxExtraPaddingSize.
+type ExtraPadding struct {
+	Size int
+}
+
+// ExtraPublicKey is the public key of the transaction,
+// used by the recipients to find their outputs
+type ExtraPublicKey struct {
+	Key Key
+}
+
+// ExtraNonce is arbitrary data, such as a payment ID or the extra
+// nonce of a mining pool. Its size is encoded in a single byte, so
+// Data is at most MaxExtraNonceSize bytes long.
+type ExtraNonce struct {
+	Data []byte
+}
+
+// ExtraMergeMiningTag commits a merge mined block
+// to the Merkle root of the auxiliary chains
+type ExtraMergeMiningTag struct {
+	Depth      uint64
+	MerkleRoot Key
+}
+
+// ExtraUnknown is a field with a tag the parser does not know.
+// As the size of such a field cannot be known, Data holds the
+// rest of the extra blob.
+type ExtraUnknown struct {
+	Tag  byte
+	Data []byte
+}
+
+// Extra is the parsed extra blob of a transaction
+type Extra []ExtraField
+
+// ParseExtra parses the extra blob of a transaction
+func ParseExtra(blob []byte) (Extra, error) {
+	r := &reader{data: blob}
+	var extra Extra
+	for r.remaining() > 0 {
+		tag, _ := r.byte()
+		switch tag {
+		case ExtraPaddingTag:
+			rest, _ := r.bytes(r.remaining())
+			for _, b := range rest {
+				if b != 0 {
+					return nil, errors.New("Extra padding contains non zero bytes")
+				}
+			}
+			if len(rest)+1 > MaxExtraPaddingSize {
+				return nil, errors.New("Extra padding is too long")
+			}
+			extra = append(extra, ExtraPadding{Size: len(rest) + 1})
+
+		case ExtraPublicKeyTag:
+			key, err := r.key()
+			if err != nil {
+				return nil, err
+			}
+			extra = append(extra, ExtraPublicKey{Key: key})
+
+		case ExtraNonceTag:
+			// The size of the nonce is a single byte, not a varint
+			size, err := r.byte()
+			if err != nil {
+				return nil, err
+			}
+			data, err := r.bytes(int(size))
+			if err != nil {
+				return nil, err
+			}
+			extra = append(extra, ExtraNonce{Data: append([]byte{}, data...)})
+
+		case ExtraMergeMiningTagTag:
+			size, err := r.count(1)
+			if err != nil {
+				return nil, err
+			}
+			data, _ := r.bytes(size)
+			field := &reader{data: data}
+
+			var tag ExtraMergeMiningTag
+			if tag.Depth, err = field.varint(); err != nil {
+				return nil, err
+			}
+			if tag.MerkleRoot, err = field.key(); err != nil {
+				return nil, err
+			}
+			if field.remaining() != 0 {
+				return nil, errors.New("Unexpected data in the merge mining tag")
+			}
+			extra = append(extra, tag)
+
+		default:
+			rest, _ := r.bytes(r.remaining())
+			extra = append(extra, ExtraUnknown{Tag: tag, Data: append([]byte{}, rest...)})
+		}
+	}
+	return extra, nil
+}
+
+// ParseExtraHex parses a hex extra blob, such as the extra
+// of the transactions in the JSON responses of the daemon
+func ParseExtraHex(s string) (Extra, error) {
+	blob, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return ParseExtra(blob)
+}
+
+// Bytes returns the extra blob of the fields, or an error
+// if a field does not fit its encoding
+func (extra Extra) Bytes() ([]byte, error) {
+	var b []byte
+	for i, field := range extra {
+		if _, ok := field.(ExtraPadding); ok && i != len(extra)-1 {
+			return nil, ErrExtraPaddingLast
+		}
+		var err error
+		if b, err = field.appendTo(b); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Hex returns the hex extra blob of the fields, as taken
+// by the extra parameter of Walletd.SendTransaction
+func (extra Extra) Hex() (string, error) {
+	b, err := extra.Bytes()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// PublicKey returns the first transaction public key
+func (extra Extra) PublicKey() (Key, bool) {
+	for _, field := range extra {
+		if field, ok := field.(ExtraPublicKey); ok {
+			return field.Key, true
+		}
+	}
+	return Key{}, false
+}
+
+// PaymentID returns the payment ID of the first extra nonce holding one
+func (extra Extra) PaymentID() (Key, bool) {
+	for _, field := range extra {
+		if field, ok := field.(ExtraNonce); ok {
+			if id, ok := field.PaymentID(); ok {
+				return id, true
+			}
+		}
+	}
+	return Key{}, false
+}
+
+// MergeMiningTag returns the first merge mining tag
+func (extra Extra) MergeMiningTag() (ExtraMergeMiningTag, bool) {
+	for _, field := range extra {
+		if field, ok := field.(ExtraMergeMiningTag); ok {
+			return field, true
+		}
+	}
+	return ExtraMergeMiningTag{}, false
+}
+
+// PaymentIDNonce returns the extra nonce holding a payment ID
+func PaymentIDNonce(paymentID Key) ExtraNonce {
+	return ExtraNonce{Data: append([]byte{ExtraNoncePaymentIDTag}, paymentID[:]...)}
+}
+
+// PaymentID returns the payment ID held by the nonce
+func (nonce ExtraNonce) PaymentID() (Key, bool) {
+	var id Key
+	if len(nonce.Data) != 1+len(id) || nonce.Data[0] != ExtraNoncePaymentIDTag {
+		return id, false
+	}
+	copy(id[:], nonce.Data[1:])
+	return id, true
+}
+
+func (padding ExtraPadding) appendTo(b []byte) ([]byte, error) {
+	if padding.Size < 1 || padding.Size > MaxExtraPaddingSize {
+		return nil, ErrExtraPaddingSize
+	}
+	return append(b, make([]byte, padding.Size)...), nil
+}
+
+func (key ExtraPublicKey) appendTo(b []byte) ([]byte, error) {
+	b = append(b, ExtraPublicKeyTag)
+	return append(b, key.Key[:]...), nil
+}
+
+func (nonce ExtraNonce) appendTo(b []byte) ([]byte, error) {
+	if len(nonce.Data) > MaxExtraNonceSize {
+		return nil, ErrExtraNonceSize
+	}
+	b = append(b, ExtraNonceTag, byte(len(nonce.Data)))
+	return append(b, nonce.Data...), nil
+}
+
+func (tag ExtraMergeMiningTag) appendTo(b []byte) ([]byte, error) {
+	field := AppendVarint(nil, tag.Depth)
+	field = append(field, tag.MerkleRoot[:]...)
+
+	b = append(b, ExtraMergeMiningTagTag)
+	b = AppendVarint(b, uint64(len(field)))
+	return append(b, field...), nil
+}
+
+func (unknown ExtraUnknown) appendTo(b []byte) ([]byte, error) {
+	b = append(b, unknown.Tag)
+	return append(b, unknown.Data...), nil
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package cryptonote
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExtraNonceSize(t *testing.T) {
+	for _, size := range []int{0, 1, 127, 128, 255} {
+		data := bytes.Repeat([]byte{0xab}, size)
+		blob, err := Extra{ExtraNonce{Data: data}}.Bytes()
+		if err != nil {
+			t.Errorf("size %d: %v", size, err)
+			continue
+		}
+
+		// A varint would take two bytes from 128 on
+		if len(blob) != 2+size || blob[0] != ExtraNonceTag || int(blob[1]) != size {
+			t.Errorf("size %d: blob starts with % x, length %d", size, blob[:2], len(blob))
+			continue
+		}
+
+		extra, err := ParseExtra(blob)
+		if err != nil {
+			t.Errorf("size %d: %v", size, err)
+			continue
+		}
+		if len(extra) != 1 {
+			t.Errorf("size %d: parsed %d fields", size, len(extra))
+			continue
+		}
+		if nonce, ok := extra[0].(ExtraNonce); !ok || !bytes.Equal(nonce.Data, data) {
+			t.Errorf("size %d: parsed %+v", size, extra[0])
+		}
+	}
+}
+
+func TestExtraNonceTruncated(t *testing.T) {
+	// The nonce claims 128 bytes, only 127 follow
+	blob := append([]byte{ExtraNonceTag, 128}, make([]byte, 127)...)
+	if _, err := ParseExtra(blob); err != ErrUnexpectedEnd {
+		t.Errorf("err = %v, want %v", err, ErrUnexpectedEnd)
+	}
+}
+
+func TestExtraInvalid(t *testing.T) {
+	key := ExtraPublicKey{Key: Key{1}}
+	tests := []struct {
+		name  string
+		extra Extra
+		err   error
+	}{
+		{"oversized nonce", Extra{key, ExtraNonce{Data: make([]byte, MaxExtraNonceSize+1)}}, ErrExtraNonceSize},
+		{"empty padding", Extra{key, ExtraPadding{}}, ErrExtraPaddingSize},
+		{"oversized padding", Extra{key, ExtraPadding{Size: MaxExtraPaddingSize + 1}}, ErrExtraPaddingSize},
+		{"padding before a field", Extra{ExtraPadding{Size: 1}, key}, ErrExtraPaddingLast},
+	}
+	for _, test := range tests {
+		if blob, err := test.extra.Bytes(); err != test.err {
+			t.Errorf("%s: blob % x, err = %v, want %v", test.name, blob, err, test.err)
+		}
+		if _, err := test.extra.Hex(); err != test.err {
+			t.Errorf("%s: Hex err = %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestExtraPadding(t *testing.T) {
+	for _, size := range []int{1, 2, MaxExtraPaddingSize} {
+		blob, err := Extra{ExtraPublicKey{}, ExtraPadding{Size: size}}.Bytes()
+		if err != nil {
+			t.Errorf("size %d: %v", size, err)
+			continue
+		}
+		if len(blob) != 33+size {
+			t.Errorf("size %d: blob of %d bytes", size, len(blob))
+			continue
+		}
+		extra, err := ParseExtra(blob)
+		if err != nil || len(extra) != 2 || extra[1] != (ExtraPadding{Size: size}) {
+			t.Errorf("size %d: parsed %+v, err = %v", size, extra, err)
+		}
+	}
+}
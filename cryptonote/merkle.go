@@ -0,0 +1,61 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package cryptonote
+
+// TreeHash returns the root of the CryptoNote Merkle tree of the
+// hashes, which is not a plain binary tree when the number of hashes
+// is not a power of two. It returns the zero key for no hashes.
+func TreeHash(hashes []Key) Key {
+	switch len(hashes) {
+	case 0:
+		return Key{}
+	case 1:
+		return hashes[0]
+	case 2:
+		return Keccak(hashes[0][:], hashes[1][:])
+	}
+
+	// The leaves beyond the largest power of two below
+	// the count are paired first
+	count := 1
+	for count*2 < len(hashes) {
+		count *= 2
+	}
+
+	level := make([]Key, count)
+	direct := 2*count - len(hashes)
+	copy(level, hashes[:direct])
+	for i, j := direct, direct; j < count; i, j = i+2, j+1 {
+		level[j] = Keccak(hashes[i][:], hashes[i+1][:])
+	}
+
+	for ; count > 2; count /= 2 {
+		for i := 0; i < count/2; i++ {
+			level[i] = Keccak(level[2*i][:], level[2*i+1][:])
+		}
+	}
+	return Keccak(level[0][:], level[1][:])
+}
+
+// TreeDepth returns the number of hashes in the branch
+// proving the first leaf of a tree of count hashes
+func TreeDepth(count uint64) int {
+	depth := 0
+	for count > 1 {
+		count >>= 1
+		depth++
+	}
+	return depth
+}
+
+// TreeHashFromBranch returns the root of the tree whose first leaf
+// is leaf, given the siblings on its path, the one next to the root
+// first, as stored in merge mined blocks
+func TreeHashFromBranch(branch []Key, leaf Key) Key {
+	root := leaf
+	for depth := len(branch) - 1; depth >= 0; depth-- {
+		root = Keccak(root[:], branch[depth][:])
+	}
+	return root
+}
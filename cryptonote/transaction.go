@@ -0,0 +1,274 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package cryptonote
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+)
+
+// Tags of the input and output types
+const (
+	BaseInputTag = 0xff
+	KeyInputTag  = 0x02
+	KeyOutputTag = 0x02
+)
+
+// Input is either a BaseInput or a KeyInput
+type Input interface {
+	tag() byte
+}
+
+// BaseInput is the input of a coinbase transaction
+// creating the reward of the block at BlockIndex
+type BaseInput struct {
+	BlockIndex uint64
+}
+
+// KeyInput spends one of the outputs of the ring given by KeyOffsets,
+// which are global output indexes, each relative to the previous one
+type KeyInput struct {
+	Amount     uint64
+	KeyOffsets []uint64
+	KeyImage   Key
+}
+
+// Output sends Amount to the one time public Key
+type Output struct {
+	Amount uint64
+	Key    Key
+}
+
+func (BaseInput) tag() byte { return BaseInputTag }
+func (KeyInput) tag() byte  { return KeyInputTag }
+
+// TransactionPrefix is the part of a transaction covered by its signatures
+type TransactionPrefix struct {
+	Version    uint64
+	UnlockTime uint64
+	Inputs     []Input
+	Outputs    []Output
+	Extra      []byte
+}
+
+// Transaction is a transaction prefix with the ring signatures
+// of its inputs, one signature per ring member of a KeyInput
+// and none for a BaseInput
+type Transaction struct {
+	TransactionPrefix
+	Signatures [][]Signature
+}
+
+// ParseTransaction decodes a binary transaction
+func ParseTransaction(blob []byte) (*Transaction, error) {
+	r := &reader{data: blob}
+	tx, err := r.transaction()
+	if err != nil {
+		return nil, err
+	}
+	if r.remaining() != 0 {
+		return nil, errors.New("Unexpected data after the transaction")
+	}
+	return tx, nil
+}
+
+// ParseTransactionHex decodes a hex transaction such as the
+// tx_as_hex payload of sendrawtransaction
+func ParseTransactionHex(s string) (*Transaction, error) {
+	blob, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTransaction(blob)
+}
+
+// Bytes returns the binary encoding of the prefix
+func (prefix *TransactionPrefix) Bytes() []byte {
+	return prefix.appendTo(nil)
+}
+
+// Hash returns the hash of the prefix, which is the message
+// signed by the ring signatures
+func (prefix *TransactionPrefix) Hash() Key {
+	return Keccak(prefix.Bytes())
+}
+
+// Bytes returns the binary encoding of the transaction
+func (tx *Transaction) Bytes() []byte {
+	return tx.appendTo(nil)
+}
+
+// Hex returns the hex encoding of the transaction
+func (tx *Transaction) Hex() string {
+	return hex.EncodeToString(tx.Bytes())
+}
+
+// Hash returns the hash of the transaction as used by the daemon
+func (tx *Transaction) Hash() Key {
+	return Keccak(tx.Bytes())
+}
+
+// Coinbase reports whether the transaction creates a block reward
+func (tx *Transaction) Coinbase() bool {
+	if len(tx.Inputs) != 1 {
+		return false
+	}
+	_, ok := tx.Inputs[0].(BaseInput)
+	return ok
+}
+
+func (prefix *TransactionPrefix) appendTo(b []byte) []byte {
+	b = AppendVarint(b, prefix.Version)
+	b = AppendVarint(b, prefix.UnlockTime)
+
+	b = AppendVarint(b, uint64(len(prefix.Inputs)))
+	for _, input := range prefix.Inputs {
+		b = append(b, input.tag())
+		switch input := input.(type) {
+		case BaseInput:
+			b = AppendVarint(b, input.BlockIndex)
+		case KeyInput:
+			b = AppendVarint(b, input.Amount)
+			b = AppendVarint(b, uint64(len(input.KeyOffsets)))
+			for _, offset := range input.KeyOffsets {
+				b = AppendVarint(b, offset)
+			}
+			b = append(b, input.KeyImage[:]...)
+		}
+	}
+
+	b = AppendVarint(b, uint64(len(prefix.Outputs)))
+	for _, output := range prefix.Outputs {
+		b = AppendVarint(b, output.Amount)
+		b = append(b, KeyOutputTag)
+		b = append(b, output.Key[:]...)
+	}
+
+	b = AppendVarint(b, uint64(len(prefix.Extra)))
+	return append(b, prefix.Extra...)
+}
+
+func (tx *Transaction) appendTo(b []byte) []byte {
+	b = tx.TransactionPrefix.appendTo(b)
+	for _, signatures := range tx.Signatures {
+		for _, signature := range signatures {
+			b = append(b, signature[:]...)
+		}
+	}
+	return b
+}
+
+func (r *reader) transactionPrefix() (*TransactionPrefix, error) {
+	var prefix TransactionPrefix
+	var err error
+	if prefix.Version, err = r.varint(); err != nil {
+		return nil, err
+	}
+	if prefix.UnlockTime, err = r.varint(); err != nil {
+		return nil, err
+	}
+
+	inputs, err := r.count(2)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < inputs; i++ {
+		input, err := r.input()
+		if err != nil {
+			return nil, err
+		}
+		prefix.Inputs = append(prefix.Inputs, input)
+	}
+
+	outputs, err := r.count(34)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < outputs; i++ {
+		var output Output
+		if output.Amount, err = r.varint(); err != nil {
+			return nil, err
+		}
+		tag, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if tag != KeyOutputTag {
+			return nil, errors.New("Unknown output type " + strconv.Itoa(int(tag)))
+		}
+		if output.Key, err = r.key(); err != nil {
+			return nil, err
+		}
+		prefix.Outputs = append(prefix.Outputs, output)
+	}
+
+	size, err := r.count(1)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := r.bytes(size)
+	if err != nil {
+		return nil, err
+	}
+	prefix.Extra = append([]byte{}, extra...)
+	return &prefix, nil
+}
+
+func (r *reader) input() (Input, error) {
+	tag, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case BaseInputTag:
+		index, err := r.varint()
+		return BaseInput{BlockIndex: index}, err
+	case KeyInputTag:
+		var input KeyInput
+		if input.Amount, err = r.varint(); err != nil {
+			return nil, err
+		}
+		offsets, err := r.count(1)
+		if err != nil {
+			return nil, err
+		}
+		input.KeyOffsets = make([]uint64, offsets)
+		for i := range input.KeyOffsets {
+			if input.KeyOffsets[i], err = r.varint(); err != nil {
+				return nil, err
+			}
+		}
+		input.KeyImage, err = r.key()
+		return input, err
+	}
+	return nil, errors.New("Unknown input type " + strconv.Itoa(int(tag)))
+}
+
+func (r *reader) transaction() (*Transaction, error) {
+	prefix, err := r.transactionPrefix()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &Transaction{TransactionPrefix: *prefix}
+	for _, input := range tx.Inputs {
+		var count int
+		if input, ok := input.(KeyInput); ok {
+			count = len(input.KeyOffsets)
+		}
+
+		signatures := make([]Signature, count)
+		for i := range signatures {
+			raw, err := r.bytes(len(signatures[i]))
+			if err != nil {
+				return nil, err
+			}
+			copy(signatures[i][:], raw)
+		}
+		tx.Signatures = append(tx.Signatures, signatures)
+	}
+	return tx, nil
+}
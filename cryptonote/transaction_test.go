@@ -0,0 +1,180 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package cryptonote
+
+import "testing"
+
+// transactionVectors are version 1 transactions of the Monero mainnet,
+// which shares the CryptoNote transaction format with TurtleCoin. The
+// hash of each blob is the hash of the transaction on that chain.
+var transactionVectors = []struct {
+	name       string
+	blob       string
+	hash       string
+	prefixHash string
+	unlockTime uint64
+	blockIndex uint64
+	inputSum   uint64
+	outputSum  uint64
+	keyImages  []string
+	outputKeys []string
+}{
+	{
+		name:       "coinbase of block 15",
+		blob:       "014b01ff0f098fd61702a3ef0df2a51b14891676b39f18d6c0b8c52bfc3f6ba5f63f792e993f73b900df8092f40102204e4d5992876b76cc8a30760c8fd82dbaf948cb2d858278a08f4d8db326682a8087a70e027a2328c2d86a1f84335010d183d0eef067195da4a57169ac9d1c8c1f84ec74a580d293ad03023c6411e620104487402583db89dd15245448092f339a30a4982e076c7ba0a7578094ebdc0302861696af6e92cea973fa217f3b4df3e8c43a2a1cb888b515e23d2d5d3d41ba688088aca3cf0202d3087348abe2dffa189c79f82e032f14cbd12cfd6ece0a5192ec235a4cf2c96e8090cad2c60e02feb619b90856473e906295252cc9a87abbb7f5db409579d3169be5ee23b35ab680e08d84ddcb010275cd0dbe02a00558669ef424c2d5f8cbefc8c4af711f1afde0404628c401b13580c0caf384a30202b1bb2e13bf7c1d88885b7ebf2be56e5064af24d69f3c92d9264550122eed1a0b2101bbac13803d9b7941444cc817292b91a9634fa6cee88ced917571df2e0c87ad79",
+		hash:       "e3a799da24d9f41aac231ba2efb853ae649283feaf5e1ba46b5fc2c194414c5d",
+		prefixHash: "e3a799da24d9f41aac231ba2efb853ae649283feaf5e1ba46b5fc2c194414c5d",
+		unlockTime: 75,
+		blockIndex: 15,
+		outputSum:  17591934387855,
+		outputKeys: []string{
+			"a3ef0df2a51b14891676b39f18d6c0b8c52bfc3f6ba5f63f792e993f73b900df",
+			"204e4d5992876b76cc8a30760c8fd82dbaf948cb2d858278a08f4d8db326682a",
+			"7a2328c2d86a1f84335010d183d0eef067195da4a57169ac9d1c8c1f84ec74a5",
+			"3c6411e620104487402583db89dd15245448092f339a30a4982e076c7ba0a757",
+			"861696af6e92cea973fa217f3b4df3e8c43a2a1cb888b515e23d2d5d3d41ba68",
+			"d3087348abe2dffa189c79f82e032f14cbd12cfd6ece0a5192ec235a4cf2c96e",
+			"feb619b90856473e906295252cc9a87abbb7f5db409579d3169be5ee23b35ab6",
+			"75cd0dbe02a00558669ef424c2d5f8cbefc8c4af711f1afde0404628c401b135",
+			"b1bb2e13bf7c1d88885b7ebf2be56e5064af24d69f3c92d9264550122eed1a0b",
+		},
+	},
+	{
+		name:       "coinbase of block 1000",
+		blob:       "01a40801ffe80709d9f53102b68a80f92240fca8c0456db6aeb065c264f5b9c319ae478171e115fd04a0297c809bee0202c4abb05169a7188f37e4fcbca954cda85887ecd651ea986ca66748cf2cfed60480ade20402bdda1625687fad38c89473db3a0eb77294ca39e71f4771dcdf4e13bd124ff62d8088debe010276910ddc3da7c84b2d6ac9edc640d70025dd10e972d646670d453b6b9576bfca80e497d0120264ec845e5e738fa650d4d109c8c8972cbe657edc8494d0040d40abec1f2c0d7a80f8cce284020267c9d11fccc47abd45fe2b9cab90ee625ce7c0f63d19193a57fcb6971e9241628090cad2c60e02d66329f76861f0390a21d3133194aa2ac674b657743036fad3dcce1e1e87e82a80e08d84ddcb010205de735f3a734a84032b418f48d2520c7e1a7111767b937a1fe18cd4739b088880c0caf384a30202efb9148c55da56343bb95f4cf1f4b81965979e15f5365fc1d5701be197d6fa942101df90bfecd9e549ad895b79f9313a8cc43139c0b30c0258b0f41173e729ca9457",
+		hash:       "80be5324c155542182a73bf29480d4c1d5cf71eb4931c1934b55699e56c2bbb2",
+		prefixHash: "80be5324c155542182a73bf29480d4c1d5cf71eb4931c1934b55699e56c2bbb2",
+		unlockTime: 1060,
+		blockIndex: 1000,
+		outputSum:  17575416817881,
+		outputKeys: []string{
+			"b68a80f92240fca8c0456db6aeb065c264f5b9c319ae478171e115fd04a0297c",
+			"c4abb05169a7188f37e4fcbca954cda85887ecd651ea986ca66748cf2cfed604",
+			"bdda1625687fad38c89473db3a0eb77294ca39e71f4771dcdf4e13bd124ff62d",
+			"76910ddc3da7c84b2d6ac9edc640d70025dd10e972d646670d453b6b9576bfca",
+			"64ec845e5e738fa650d4d109c8c8972cbe657edc8494d0040d40abec1f2c0d7a",
+			"67c9d11fccc47abd45fe2b9cab90ee625ce7c0f63d19193a57fcb6971e924162",
+			"d66329f76861f0390a21d3133194aa2ac674b657743036fad3dcce1e1e87e82a",
+			"05de735f3a734a84032b418f48d2520c7e1a7111767b937a1fe18cd4739b0888",
+			"efb9148c55da56343bb95f4cf1f4b81965979e15f5365fc1d5701be197d6fa94",
+		},
+	},
+	{
+		name:       "17 inputs from block 40646",
+		blob:       "01001102809bee0201d11fc9679ba9ca8a6fa87a1352985e46ea3723489d3699ab1af075532f711739b9c50280b4c4c32101c6210e99a2f46b383802f04a7f231047a2ed414e861b1d2a3494b371c04759cc270d028095f52a01882074b213379239558f5d4628a48a43a36ce9cdbe673453608719836664f7e809490280c0a8ca9a3a01b102f61c2293448c98e77ccf1165638e908986de0789917fd291f9bc32491360360b0280897a01d12004d0d8575cdde390173e269f61e8edb9406a76e479106987adc714c14f4530150280d88ee16f01eb1d58dc8327b8c0b944b05024863ecd64bceac940001e93e3acabfc570bff5483620280b4c4c32101bd204b8ff554752b31af4ad50f7edcaec088b4f7ee2693a59661c06890119fc48121028090bcfd0201cf1fa1b66ceefb3af10b0184798a78a3a26d0bd1d762366927540fb053634a8b97b00280b09dc2df0101a6239388ec3806bf2f3997d76f7e05820330068598deea35dd8eefcc31fced30b8670280d293ad0301b92045905146e79364df9fffa56da7fa37e1534acaeaac951cc6d754516c5b808510028080a2a9eae80101880147e6864c2c086c13dcde36e360e3adcc3bbe295a893b9f6183020e3da1920e8a0280b4c4c32101b620f1ea44fc891f7d5ea914abefd964f46147eb650585825731e6ecbd33e469952c0280b081daaf1401aa0222f06bb1b9ec96b84763f07c554469b8c2d9ae7bae97ccb7602a960302bcf69b02809bee0201d51ea044522fb61ddfc366666b9de517d2901c3b821ffb1aa09e8932dad3f2e4272702809bee0201d61e1253cf28f88eaa38cce28d43d6d076780e1d85b14eeccb65b76f6b79d6e079a00280d0b8e1981a01b810a06b60e2069a79706e442cb60b9c1c66d018a9222dd03205527c9ab94c2a2053028088aca3cf0201812194f83724e913891d5add7d7a4256f17a9fd76fb91f6600289bcc42e83ccdb79e06c0a8a50402eebdccc569747e7ad5787d1a88f7b67dd753b86b03c331bc93dbbcffd413ee368090bcfd0202d5d6274573883a2a1231b15701e2447e4c5ad3f4a6901701ea1ab477ba8d502e80a0d9e61d029c6a48ca222ac5b4f037828166b95929b766b8d71582ddf8dbc718983e6626b88080dd9da4170250ec4b429b04fa717bab4c315660862137848064536f9a02bb1d4e5894e36f3380a094a58d1d0200197ee25626aa3cc0adc1c372ce8e58c5a3f3cb97f341412306cdbf1a5f25e380c0caf384a30202ebc842713e4bd0124917c34c361d2b31dd2343db91c24e44a23653d800aa2199440221003fe2d8b0f49996be3fdf4bc732ad0fda3fde42488bd9a6dc3fef018c4b77aa53015f641367cb5d2c4c40f5b7dc726ce1ac651623b8082746221e468a47c46556cd11b4d1bd92e85f38152848cbf100c6f8b15c9de5278e4506bb9131230807d60e658188593715e7980a9d9e188d2114f2a3b71541cfe66fb94413237edf36dc0aa15a56d12471a2cf2e25be43a3ee48571602dd19ebf4e4e266aec5fe3102650e1a60e1343243d80083df37004a1f2854276d3c4c0f2aca56c39ae7b1d254180036f40c9af0528811fea9037b5d622f8c8c35df908e1603e3ba6e68ce8cb75809f6d08eb841a31d9ba3431c67449cef0892347c1e04c69dec0e09d29b522ac50bab5482839d1cb11a87f7725fe5e8783becb0ea0fa72a78de4971c0322b7923042d8160d8328228ba25a2da542c2405317f5bb5e1a2c9bb5feb46c9c2b037d40ab1c98c70cb8734070bf96fd84125180b8b89906335eab6b09e3ca0a5687c9807fd24038e8ae0e7eaacae4c5bfbaaaea58aad3b0d412bf1018232826eafc40c00eb495e5cb3471025744818fcf6b816dd817f64d5eea8947f847e180bf8607e068e3ea474372d1fa6403b936841f53ec3de8b46c844f2e448e32d64c6767c4909938010d942fe8443326196ce93046d99a0c215182d3db9ede168e28380833002e78a7366b2150946ec2bf63796dd978fad325236c97ab7141b8dcec316b07a0269ccff03c6b402f4f9403f88b00b778f8468ec1e00a8528492b49a8b7cfa6e042ce6cde5341618d5f6a019db385644c598332dcd68e4f16166af3a6c824a8801ea768e60ff37038e78958e68b423c5510c98d86482af42c1c1df33a967ab8c0a6cfd56986640333b75f0ff31922d5ff70829799340cc0d18f494f7a38a27b30b099b882b67da4fb48e245c6456241c19eff98f066c0fe1d7a0995b3b5e14de0cdf2ac0f8ffe135d0710b7854df42f990ffc9bf260a4656a51aadb64eecfd6600df7aad92c9fc0d6d377e05ab10286cf86501b77929bb2c4939dc88b1b0001e0f040d0a1260b8f38d2f68a7052267d43e389bb0365982e63c6e347c8c4109430fff86011fa605ff377f4c626c7178f1f5938da6305730e52ee19bfc4335ce87001e90adda866adfbbd662376871fdb9680b2d03c0b43a4195e9e3a1111c24c40c25e8cb88df2f983fbd0894513bd50d7f6ab17e3ec3d93a9dd5d0dff27e6110038b5c05dd084dd957eef2d9fb0de7ca67d75e1b8a81b10dc3edf099a020536f0cfd6a1a7294ab1db71d78486324d2e7baf84147e304ba450d07ae6b97624dd9009bc3a3d857d80c2af5729707522f5fca14a07deaa73e16b2429b0a5d35a8970b0dadafdcd67834f0241443b935d57f77cd4cf6f309235e71c987c9a5bb15110818f4d46aa56d6c55dcb07b49b899d617be4237b98282e2dfbff86365c3d532077f7fa7550dc29c79419410132dc1a2f2f0598212b483fa59d56ecbc662c09e0d3d944ad18d9a5a55ec9b8af82a1d148f0677de4526d26ce7fe123bdd809c0c06f60b6cceb8c694da13a8ac807258a8dc45368fde5b46e02947de10b13dab5b035a9490369ee32e2917fa00965c03592e5c32489b21400741479d9451aac8d708",
+		hash:       "ca9ea576d67af4926e31ebeb159aaee58950aea18e5e0ad0bae23b2d85ede8c1",
+		prefixHash: "aeecb4170b276d2ac69a7abca86f82621f56d943c8d4a8900cd56192da8d442d",
+		inputSum:   11808810000000,
+		outputSum:  11808809000000,
+		keyImages: []string{
+			"c9679ba9ca8a6fa87a1352985e46ea3723489d3699ab1af075532f711739b9c5",
+			"0e99a2f46b383802f04a7f231047a2ed414e861b1d2a3494b371c04759cc270d",
+			"74b213379239558f5d4628a48a43a36ce9cdbe673453608719836664f7e80949",
+			"f61c2293448c98e77ccf1165638e908986de0789917fd291f9bc32491360360b",
+			"04d0d8575cdde390173e269f61e8edb9406a76e479106987adc714c14f453015",
+			"58dc8327b8c0b944b05024863ecd64bceac940001e93e3acabfc570bff548362",
+			"4b8ff554752b31af4ad50f7edcaec088b4f7ee2693a59661c06890119fc48121",
+			"a1b66ceefb3af10b0184798a78a3a26d0bd1d762366927540fb053634a8b97b0",
+			"9388ec3806bf2f3997d76f7e05820330068598deea35dd8eefcc31fced30b867",
+			"45905146e79364df9fffa56da7fa37e1534acaeaac951cc6d754516c5b808510",
+			"47e6864c2c086c13dcde36e360e3adcc3bbe295a893b9f6183020e3da1920e8a",
+			"f1ea44fc891f7d5ea914abefd964f46147eb650585825731e6ecbd33e469952c",
+			"22f06bb1b9ec96b84763f07c554469b8c2d9ae7bae97ccb7602a960302bcf69b",
+			"a044522fb61ddfc366666b9de517d2901c3b821ffb1aa09e8932dad3f2e42727",
+			"1253cf28f88eaa38cce28d43d6d076780e1d85b14eeccb65b76f6b79d6e079a0",
+			"a06b60e2069a79706e442cb60b9c1c66d018a9222dd03205527c9ab94c2a2053",
+			"94f83724e913891d5add7d7a4256f17a9fd76fb91f6600289bcc42e83ccdb79e",
+		},
+		outputKeys: []string{
+			"eebdccc569747e7ad5787d1a88f7b67dd753b86b03c331bc93dbbcffd413ee36",
+			"d5d6274573883a2a1231b15701e2447e4c5ad3f4a6901701ea1ab477ba8d502e",
+			"9c6a48ca222ac5b4f037828166b95929b766b8d71582ddf8dbc718983e6626b8",
+			"50ec4b429b04fa717bab4c315660862137848064536f9a02bb1d4e5894e36f33",
+			"00197ee25626aa3cc0adc1c372ce8e58c5a3f3cb97f341412306cdbf1a5f25e3",
+			"ebc842713e4bd0124917c34c361d2b31dd2343db91c24e44a23653d800aa2199",
+		},
+	},
+}
+
+func TestTransactionRoundTrip(t *testing.T) {
+	for _, vector := range transactionVectors {
+		tx, err := ParseTransactionHex(vector.blob)
+		if err != nil {
+			t.Errorf("%s: %v", vector.name, err)
+			continue
+		}
+		if tx.Hex() != vector.blob {
+			t.Errorf("%s: encoded as %s", vector.name, tx.Hex())
+		}
+		if hash := tx.Hash().String(); hash != vector.hash {
+			t.Errorf("%s: hash = %s, want %s", vector.name, hash, vector.hash)
+		}
+		if hash := tx.TransactionPrefix.Hash().String(); hash != vector.prefixHash {
+			t.Errorf("%s: prefix hash = %s, want %s", vector.name, hash, vector.prefixHash)
+		}
+		if tx.Version != 1 || tx.UnlockTime != vector.unlockTime {
+			t.Errorf("%s: version %d, unlock time %d", vector.name, tx.Version, tx.UnlockTime)
+		}
+
+		if tx.Coinbase() != (len(vector.keyImages) == 0) {
+			t.Errorf("%s: coinbase = %v", vector.name, tx.Coinbase())
+		}
+		if tx.Coinbase() {
+			if input := tx.Inputs[0].(BaseInput); input.BlockIndex != vector.blockIndex {
+				t.Errorf("%s: block index = %d, want %d", vector.name, input.BlockIndex, vector.blockIndex)
+			}
+			if len(tx.Signatures) != 1 || len(tx.Signatures[0]) != 0 {
+				t.Errorf("%s: signatures = %v, want none for the base input", vector.name, tx.Signatures)
+			}
+		} else {
+			checkKeyInputs(t, vector.name, tx, vector.keyImages, vector.inputSum)
+		}
+
+		var outputSum uint64
+		for _, output := range tx.Outputs {
+			outputSum += output.Amount
+		}
+		if outputSum != vector.outputSum {
+			t.Errorf("%s: output sum = %d, want %d", vector.name, outputSum, vector.outputSum)
+		}
+		if len(tx.Outputs) != len(vector.outputKeys) {
+			t.Errorf("%s: %d outputs, want %d", vector.name, len(tx.Outputs), len(vector.outputKeys))
+			continue
+		}
+		for i, key := range vector.outputKeys {
+			if tx.Outputs[i].Key.String() != key {
+				t.Errorf("%s: output %d key = %s, want %s", vector.name, i, tx.Outputs[i].Key, key)
+			}
+		}
+	}
+}
+
+// checkKeyInputs checks the key images and amounts of the inputs
+// and that each member of a ring has a signature
+func checkKeyInputs(t *testing.T, name string, tx *Transaction, keyImages []string, inputSum uint64) {
+	t.Helper()
+	if len(tx.Inputs) != len(keyImages) || len(tx.Signatures) != len(keyImages) {
+		t.Errorf("%s: %d inputs and %d signatures, want %d", name, len(tx.Inputs), len(tx.Signatures), len(keyImages))
+		return
+	}
+
+	var sum uint64
+	for i, keyImage := range keyImages {
+		input, ok := tx.Inputs[i].(KeyInput)
+		if !ok {
+			t.Errorf("%s: input %d is a %T", name, i, tx.Inputs[i])
+			continue
+		}
+		if input.KeyImage.String() != keyImage {
+			t.Errorf("%s: input %d key image = %s, want %s", name, i, input.KeyImage, keyImage)
+		}
+		if len(tx.Signatures[i]) != len(input.KeyOffsets) {
+			t.Errorf("%s: input %d has %d signatures for %d ring members", name, i, len(tx.Signatures[i]), len(input.KeyOffsets))
+		}
+		sum += input.Amount
+	}
+	if sum != inputSum {
+		t.Errorf("%s: input sum = %d, want %d", name, sum, inputSum)
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/turtlecoin/turtlecoin-rpc-go
+
+go 1.23.0
+
+require (
+	filippo.io/edwards25519 v1.1.1
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/crypto v0.41.0
+	golang.org/x/term v0.34.0
+)
+
+require golang.org/x/sys v0.35.0 // indirect
@@ -0,0 +1,322 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package indexer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/turtlecoin/turtlecoin-rpc-go/internal/storage"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	blocksBucket     = []byte("blocks")
+	timesBucket      = []byte("times")
+	txsBucket        = []byte("transactions")
+	paymentIDsBucket = []byte("paymentIDs")
+	keyImagesBucket  = []byte("keyImages")
+	outputKeysBucket = []byte("outputKeys")
+)
+
+// BoltStore is a Store kept in a bbolt database file.
+// Blocks are keyed by height, the time and payment ID
+// indexes are keys sorted by timestamp or payment ID
+// and then by height.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens the database file at path, creating it if needed
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{blocksBucket, timesBucket, txsBucket, paymentIDsBucket, keyImagesBucket, outputKeysBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db}, nil
+}
+
+// Tip returns the highest block
+func (store *BoltStore) Tip() (*Block, error) {
+	var block *Block
+	err := store.db.View(func(tx *bolt.Tx) error {
+		_, value := tx.Bucket(blocksBucket).Cursor().Last()
+		if value == nil {
+			return ErrNotFound
+		}
+		block = new(Block)
+		return json.Unmarshal(value, block)
+	})
+	return block, err
+}
+
+// AddBlock adds a block above the tip along with its transactions
+func (store *BoltStore) AddBlock(block *Block, txs []*Transaction) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		blocks := tx.Bucket(blocksBucket)
+		if key, _ := blocks.Cursor().Last(); key != nil && int(binary.BigEndian.Uint64(key))+1 != block.Height {
+			return errors.New("Block is not above the tip")
+		}
+
+		if err := storage.PutJSON(blocks, storage.Uint64Key(uint64(block.Height)), block); err != nil {
+			return err
+		}
+		if err := tx.Bucket(timesBucket).Put(timeKey(block), nil); err != nil {
+			return err
+		}
+
+		for _, transaction := range txs {
+			if err := storage.PutJSON(tx.Bucket(txsBucket), []byte(transaction.Hash), transaction); err != nil {
+				return err
+			}
+			if transaction.PaymentID != "" {
+				if err := tx.Bucket(paymentIDsBucket).Put(paymentIDKey(transaction), nil); err != nil {
+					return err
+				}
+			}
+			for _, input := range transaction.Inputs {
+				if err := tx.Bucket(keyImagesBucket).Put([]byte(input.KeyImage), []byte(transaction.Hash)); err != nil {
+					return err
+				}
+			}
+			for _, output := range transaction.Outputs {
+				if err := tx.Bucket(outputKeysBucket).Put([]byte(output.Key), []byte(transaction.Hash)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// RemoveTip removes the highest block and its transactions
+func (store *BoltStore) RemoveTip() (*Block, error) {
+	var block *Block
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		blocks := tx.Bucket(blocksBucket)
+		key, value := blocks.Cursor().Last()
+		if value == nil {
+			return ErrNotFound
+		}
+		block = new(Block)
+		if err := json.Unmarshal(value, block); err != nil {
+			return err
+		}
+
+		if err := blocks.Delete(key); err != nil {
+			return err
+		}
+		if err := tx.Bucket(timesBucket).Delete(timeKey(block)); err != nil {
+			return err
+		}
+
+		txs := tx.Bucket(txsBucket)
+		for _, hash := range block.Transactions {
+			value := txs.Get([]byte(hash))
+			if value == nil {
+				continue
+			}
+			var transaction Transaction
+			if err := json.Unmarshal(value, &transaction); err != nil {
+				return err
+			}
+
+			if err := txs.Delete([]byte(hash)); err != nil {
+				return err
+			}
+			if transaction.PaymentID != "" {
+				if err := tx.Bucket(paymentIDsBucket).Delete(paymentIDKey(&transaction)); err != nil {
+					return err
+				}
+			}
+			for _, input := range transaction.Inputs {
+				if err := tx.Bucket(keyImagesBucket).Delete([]byte(input.KeyImage)); err != nil {
+					return err
+				}
+			}
+			for _, output := range transaction.Outputs {
+				if err := tx.Bucket(outputKeysBucket).Delete([]byte(output.Key)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// BlockByHeight returns the block at the height
+func (store *BoltStore) BlockByHeight(height int) (*Block, error) {
+	if height < 0 {
+		return nil, ErrNotFound
+	}
+
+	var block *Block
+	err := store.db.View(func(tx *bolt.Tx) error {
+		var err error
+		block, err = getBlock(tx, storage.Uint64Key(uint64(height)))
+		return err
+	})
+	return block, err
+}
+
+// BlocksByTime returns the blocks with a timestamp
+// from start to end, both included, by height
+func (store *BoltStore) BlocksByTime(start int64, end int64) ([]*Block, error) {
+	var blocks []*Block
+	if start < 0 {
+		start = 0
+	}
+	if end < start {
+		return blocks, nil
+	}
+
+	err := store.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(timesBucket).Cursor()
+		for key, _ := cursor.Seek(storage.Uint64Key(uint64(start))); key != nil; key, _ = cursor.Next() {
+			if int64(binary.BigEndian.Uint64(key[:8])) > end {
+				break
+			}
+			block, err := getBlock(tx, key[8:])
+			if err != nil {
+				return err
+			}
+			blocks = append(blocks, block)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortBlocks(blocks)
+	return blocks, nil
+}
+
+// Transaction returns the transaction with the hash
+func (store *BoltStore) Transaction(hash string) (*Transaction, error) {
+	var transaction *Transaction
+	err := store.db.View(func(tx *bolt.Tx) error {
+		var err error
+		transaction, err = getTransaction(tx, []byte(hash))
+		return err
+	})
+	return transaction, err
+}
+
+// TransactionsByPaymentID returns the transactions with the payment ID, by height
+func (store *BoltStore) TransactionsByPaymentID(paymentID string) ([]*Transaction, error) {
+	var txs []*Transaction
+	prefix := append([]byte(paymentID), 0)
+
+	err := store.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(paymentIDsBucket).Cursor()
+		for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+			transaction, err := getTransaction(tx, key[len(prefix)+8:])
+			if err != nil {
+				return err
+			}
+			txs = append(txs, transaction)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+// KeyImage returns the hash of the transaction spending the key image
+func (store *BoltStore) KeyImage(keyImage string) (string, error) {
+	return store.get(keyImagesBucket, keyImage)
+}
+
+// OutputKey returns the hash of the transaction creating the output key
+func (store *BoltStore) OutputKey(key string) (string, error) {
+	return store.get(outputKeysBucket, key)
+}
+
+// Close closes the database file
+func (store *BoltStore) Close() error {
+	return store.db.Close()
+}
+
+func (store *BoltStore) get(bucket []byte, key string) (string, error) {
+	var value string
+	err := store.db.View(func(tx *bolt.Tx) error {
+		found := tx.Bucket(bucket).Get([]byte(key))
+		if found == nil {
+			return ErrNotFound
+		}
+		value = string(found)
+		return nil
+	})
+	return value, err
+}
+
+func getBlock(tx *bolt.Tx, key []byte) (*Block, error) {
+	value := tx.Bucket(blocksBucket).Get(key)
+	if value == nil {
+		return nil, ErrNotFound
+	}
+	var block Block
+	if err := json.Unmarshal(value, &block); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+func getTransaction(tx *bolt.Tx, hash []byte) (*Transaction, error) {
+	value := tx.Bucket(txsBucket).Get(hash)
+	if value == nil {
+		return nil, ErrNotFound
+	}
+	var transaction Transaction
+	if err := json.Unmarshal(value, &transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// timeKey is the timestamp followed by the height of the block
+func timeKey(block *Block) []byte {
+	timestamp := block.Timestamp
+	if timestamp < 0 {
+		timestamp = 0
+	}
+	return append(storage.Uint64Key(uint64(timestamp)), storage.Uint64Key(uint64(block.Height))...)
+}
+
+// paymentIDKey is the payment ID, a zero byte, the block
+// height and the hash of the transaction
+func paymentIDKey(transaction *Transaction) []byte {
+	key := append([]byte(transaction.PaymentID), 0)
+	key = append(key, storage.Uint64Key(uint64(transaction.BlockHeight))...)
+	return append(key, transaction.Hash...)
+}
+
+func sortBlocks(blocks []*Block) {
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Height < blocks[j].Height
+	})
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+// Package indexer copies the blocks, transactions, key images and output
+// keys of the chain of a TurtleCoind into a Store which can be queried
+// by transaction hash, block time, payment ID and key image.
+package indexer
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+)
+
+// Indexer syncs a Store with the chain of a daemon. StartHeight is the
+// height of the first indexed block of an empty store, BatchSize the
+// number of blocks requested at once and PollInterval the time between
+// two syncs of Run.
+type Indexer struct {
+	StartHeight  int
+	BatchSize    int
+	PollInterval time.Duration
+
+	daemon *trpc.TurtleCoind
+	store  Store
+}
+
+// New returns an Indexer filling the store from the daemon
+func New(daemon *trpc.TurtleCoind, store Store) *Indexer {
+	return &Indexer{
+		BatchSize:    100,
+		PollInterval: trpc.DefaultPollInterval,
+		daemon:       daemon,
+		store:        store,
+	}
+}
+
+// Store returns the store filled by the indexer
+func (indexer *Indexer) Store() Store {
+	return indexer.store
+}
+
+// Run syncs the store and then polls the daemon every
+// PollInterval until the context is cancelled or a sync fails
+func (indexer *Indexer) Run(ctx context.Context) error {
+	for {
+		if err := indexer.Sync(ctx); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(indexer.PollInterval):
+		}
+	}
+}
+
+// Sync indexes blocks until the store reaches the top of the
+// chain. Blocks which left the main chain are removed first.
+func (indexer *Indexer) Sync(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		synced, err := indexer.syncBatch()
+		if err != nil || synced {
+			return err
+		}
+	}
+}
+
+// syncBatch indexes the next batch of blocks and
+// reports whether the daemon has no more to give
+func (indexer *Indexer) syncBatch() (bool, error) {
+	tip, err := indexer.tip()
+	if err != nil {
+		return false, err
+	}
+	checkpoints, err := indexer.checkpoints(tip)
+	if err != nil {
+		return false, err
+	}
+
+	data, err := indexer.daemon.GetWalletSyncData(checkpoints, indexer.StartHeight, 0, indexer.BatchSize, false)
+	if err != nil {
+		return false, err
+	}
+
+	added := 0
+	for i := range data.Items {
+		item := &data.Items[i]
+
+		// The daemon starts over from the newest checkpoint it knows,
+		// any indexed block it sends again must still be the same
+		if tip != nil && item.BlockHeight <= tip.Height {
+			block, err := indexer.store.BlockByHeight(item.BlockHeight)
+			if err != nil && err != ErrNotFound {
+				return false, err
+			}
+			if block != nil && block.Hash == item.BlockHash {
+				continue
+			}
+			if tip, err = indexer.rollback(item.BlockHeight - 1); err != nil {
+				return false, err
+			}
+		}
+
+		if tip != nil && item.BlockHeight != tip.Height+1 {
+			return false, errors.New("Expected block " + strconv.Itoa(tip.Height+1) + " but got block " + strconv.Itoa(item.BlockHeight))
+		}
+
+		block, txs := convertBlock(item)
+		if err = indexer.store.AddBlock(block, txs); err != nil {
+			return false, err
+		}
+		tip = block
+		added++
+	}
+
+	if added == 0 && !data.Synced {
+		return false, errors.New("Daemon sent no new block before being synced")
+	}
+	return data.Synced, nil
+}
+
+// tip returns the highest indexed block, or nil if the store is empty
+func (indexer *Indexer) tip() (*Block, error) {
+	tip, err := indexer.store.Tip()
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	return tip, err
+}
+
+// rollback removes the indexed blocks above height
+// and returns the new tip
+func (indexer *Indexer) rollback(height int) (*Block, error) {
+	for {
+		tip, err := indexer.tip()
+		if err != nil || tip == nil || tip.Height <= height {
+			return tip, err
+		}
+		if _, err = indexer.store.RemoveTip(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// checkpoints returns the hashes of indexed blocks from the tip down,
+// the latest 10 blocks consecutive and the gaps doubling after that
+func (indexer *Indexer) checkpoints(tip *Block) ([]string, error) {
+	checkpoints := []string{}
+	if tip == nil {
+		return checkpoints, nil
+	}
+
+	offset, step := 0, 1
+	for height := tip.Height; height >= 0; height = tip.Height - offset {
+		block, err := indexer.store.BlockByHeight(height)
+		if err == ErrNotFound {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, block.Hash)
+
+		if len(checkpoints) >= 10 {
+			step *= 2
+		}
+		offset += step
+	}
+	return checkpoints, nil
+}
+
+// convertBlock turns the wallet sync data of a block into the indexed block
+func convertBlock(item *trpc.WalletBlockInfo) (*Block, []*Transaction) {
+	block := &Block{
+		Height:       item.BlockHeight,
+		Hash:         item.BlockHash,
+		Timestamp:    item.BlockTimestamp,
+		Transactions: []string{},
+	}
+	var txs []*Transaction
+
+	if item.CoinbaseTX != nil {
+		txs = append(txs, &Transaction{
+			Hash:       item.CoinbaseTX.Hash,
+			Coinbase:   true,
+			PublicKey:  item.CoinbaseTX.TxPublicKey,
+			UnlockTime: item.CoinbaseTX.UnlockTime,
+			Outputs:    item.CoinbaseTX.Outputs,
+		})
+	}
+	for _, raw := range item.Transactions {
+		txs = append(txs, &Transaction{
+			Hash:       raw.Hash,
+			PublicKey:  raw.TxPublicKey,
+			PaymentID:  raw.PaymentID,
+			UnlockTime: raw.UnlockTime,
+			Inputs:     raw.Inputs,
+			Outputs:    raw.Outputs,
+		})
+	}
+
+	for _, tx := range txs {
+		tx.BlockHeight = block.Height
+		tx.BlockHash = block.Hash
+		block.Transactions = append(block.Transactions, tx.Hash)
+	}
+	return block, txs
+}
+
+// Transaction returns the indexed transaction with the hash
+func (indexer *Indexer) Transaction(hash string) (*Transaction, error) {
+	return indexer.store.Transaction(hash)
+}
+
+// BlocksByTime returns the indexed blocks with a
+// timestamp from start to end, both included
+func (indexer *Indexer) BlocksByTime(start time.Time, end time.Time) ([]*Block, error) {
+	return indexer.store.BlocksByTime(start.Unix(), end.Unix())
+}
+
+// TransactionsByPaymentID returns the indexed transactions with the payment ID
+func (indexer *Indexer) TransactionsByPaymentID(paymentID string) ([]*Transaction, error) {
+	return indexer.store.TransactionsByPaymentID(paymentID)
+}
+
+// KeyImageSeen reports whether an indexed transaction spends the key image
+func (indexer *Indexer) KeyImageSeen(keyImage string) (bool, error) {
+	_, err := indexer.store.KeyImage(keyImage)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
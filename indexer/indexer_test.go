@@ -0,0 +1,134 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package indexer
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/turtlecoin/turtlecoin-rpc-go/rpctest"
+)
+
+// forEachStore runs the test against an empty store of each kind
+func forEachStore(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryStore())
+	})
+	t.Run("bolt", func(t *testing.T) {
+		store, err := OpenBoltStore(filepath.Join(t.TempDir(), "index.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+		test(t, store)
+	})
+}
+
+// checkChain checks that the store holds the chain of the daemon
+// and the coinbase transaction of every block
+func checkChain(t *testing.T, daemon *rpctest.TurtleCoind, store Store) {
+	t.Helper()
+	blocks := daemon.Blocks()
+	tip, err := store.Tip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tip.Height != len(blocks)-1 || tip.Hash != blocks[len(blocks)-1].Hash {
+		t.Errorf("tip = %d %s, want %d %s", tip.Height, tip.Hash, len(blocks)-1, blocks[len(blocks)-1].Hash)
+	}
+
+	for _, want := range blocks {
+		block, err := store.BlockByHeight(want.Height)
+		if err != nil {
+			t.Fatalf("block %d: %v", want.Height, err)
+		}
+		if block.Hash != want.Hash || len(block.Transactions) != 1 {
+			t.Errorf("block %d = %s with %d transactions, want %s", want.Height, block.Hash, len(block.Transactions), want.Hash)
+			continue
+		}
+		tx, err := store.Transaction(block.Transactions[0])
+		if err != nil {
+			t.Fatalf("block %d: %v", want.Height, err)
+		}
+		if !tx.Coinbase || tx.BlockHash != want.Hash || len(tx.Outputs) != 1 {
+			t.Errorf("block %d: coinbase transaction = %+v", want.Height, tx)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		daemon := rpctest.NewTurtleCoind()
+		defer daemon.Close()
+		for i := 0; i < 25; i++ {
+			daemon.MineBlock()
+		}
+
+		indexer := New(daemon.Client(), store)
+		indexer.BatchSize = 10
+		if err := indexer.Sync(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		checkChain(t, daemon, store)
+
+		// A synced store only gets the new blocks
+		daemon.MineBlock()
+		daemon.MineBlock()
+		if err := indexer.Sync(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		checkChain(t, daemon, store)
+	})
+}
+
+func TestSyncReorg(t *testing.T) {
+	// Heights within the 10 consecutive checkpoints
+	// and below them, where the gaps double
+	for _, height := range []int{27, 4} {
+		forEachStore(t, func(t *testing.T, store Store) {
+			daemon := rpctest.NewTurtleCoind()
+			defer daemon.Close()
+			for i := 0; i < 30; i++ {
+				daemon.MineBlock()
+			}
+
+			indexer := New(daemon.Client(), store)
+			indexer.BatchSize = 10
+			if err := indexer.Sync(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			var orphaned []*Transaction
+			for h := height; h < daemon.Height(); h++ {
+				block, err := store.BlockByHeight(h)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tx, err := store.Transaction(block.Transactions[0])
+				if err != nil {
+					t.Fatal(err)
+				}
+				orphaned = append(orphaned, tx)
+			}
+
+			daemon.Orphan(height)
+			daemon.MineBlock()
+			if err := indexer.Sync(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			checkChain(t, daemon, store)
+
+			// The transactions of the replaced blocks are gone
+			for _, tx := range orphaned {
+				if _, err := store.Transaction(tx.Hash); err != ErrNotFound {
+					t.Errorf("transaction %s of block %d: err = %v, want %v", tx.Hash, tx.BlockHeight, err, ErrNotFound)
+				}
+				if _, err := store.OutputKey(tx.Outputs[0].Key); err != ErrNotFound {
+					t.Errorf("output key of block %d: err = %v, want %v", tx.BlockHeight, err, ErrNotFound)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package indexer
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// MemoryStore is a Store keeping everything in maps,
+// it is lost when the process exits
+type MemoryStore struct {
+	mutex      sync.RWMutex
+	blocks     []*Block
+	txs        map[string]*Transaction
+	paymentIDs map[string][]string
+	keyImages  map[string]string
+	outputKeys map[string]string
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		txs:        make(map[string]*Transaction),
+		paymentIDs: make(map[string][]string),
+		keyImages:  make(map[string]string),
+		outputKeys: make(map[string]string),
+	}
+}
+
+// Tip returns the highest block
+func (store *MemoryStore) Tip() (*Block, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	if len(store.blocks) == 0 {
+		return nil, ErrNotFound
+	}
+	return store.blocks[len(store.blocks)-1], nil
+}
+
+// AddBlock adds a block above the tip along with its transactions
+func (store *MemoryStore) AddBlock(block *Block, txs []*Transaction) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if n := len(store.blocks); n != 0 && store.blocks[n-1].Height+1 != block.Height {
+		return errors.New("Block is not above the tip")
+	}
+
+	store.blocks = append(store.blocks, block)
+	for _, tx := range txs {
+		store.txs[tx.Hash] = tx
+		if tx.PaymentID != "" {
+			store.paymentIDs[tx.PaymentID] = append(store.paymentIDs[tx.PaymentID], tx.Hash)
+		}
+		for _, input := range tx.Inputs {
+			store.keyImages[input.KeyImage] = tx.Hash
+		}
+		for _, output := range tx.Outputs {
+			store.outputKeys[output.Key] = tx.Hash
+		}
+	}
+	return nil
+}
+
+// RemoveTip removes the highest block and its transactions
+func (store *MemoryStore) RemoveTip() (*Block, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	n := len(store.blocks)
+	if n == 0 {
+		return nil, ErrNotFound
+	}
+	block := store.blocks[n-1]
+	store.blocks = store.blocks[:n-1]
+
+	for _, hash := range block.Transactions {
+		tx, ok := store.txs[hash]
+		if !ok {
+			continue
+		}
+		delete(store.txs, hash)
+		if tx.PaymentID != "" {
+			hashes := store.paymentIDs[tx.PaymentID]
+			if len(hashes) <= 1 {
+				delete(store.paymentIDs, tx.PaymentID)
+			} else {
+				store.paymentIDs[tx.PaymentID] = hashes[:len(hashes)-1]
+			}
+		}
+		for _, input := range tx.Inputs {
+			delete(store.keyImages, input.KeyImage)
+		}
+		for _, output := range tx.Outputs {
+			delete(store.outputKeys, output.Key)
+		}
+	}
+	return block, nil
+}
+
+// BlockByHeight returns the block at the height
+func (store *MemoryStore) BlockByHeight(height int) (*Block, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	if len(store.blocks) == 0 {
+		return nil, ErrNotFound
+	}
+	i := height - store.blocks[0].Height
+	if i < 0 || i >= len(store.blocks) {
+		return nil, ErrNotFound
+	}
+	return store.blocks[i], nil
+}
+
+// BlocksByTime returns the blocks with a timestamp
+// from start to end, both included, by height
+func (store *MemoryStore) BlocksByTime(start int64, end int64) ([]*Block, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	var blocks []*Block
+	for _, block := range store.blocks {
+		if block.Timestamp >= start && block.Timestamp <= end {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks, nil
+}
+
+// Transaction returns the transaction with the hash
+func (store *MemoryStore) Transaction(hash string) (*Transaction, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	tx, ok := store.txs[hash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return tx, nil
+}
+
+// TransactionsByPaymentID returns the transactions with the payment ID, by height
+func (store *MemoryStore) TransactionsByPaymentID(paymentID string) ([]*Transaction, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	var txs []*Transaction
+	for _, hash := range store.paymentIDs[paymentID] {
+		txs = append(txs, store.txs[hash])
+	}
+	sort.SliceStable(txs, func(i, j int) bool {
+		return txs[i].BlockHeight < txs[j].BlockHeight
+	})
+	return txs, nil
+}
+
+// KeyImage returns the hash of the transaction spending the key image
+func (store *MemoryStore) KeyImage(keyImage string) (string, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	hash, ok := store.keyImages[keyImage]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return hash, nil
+}
+
+// OutputKey returns the hash of the transaction creating the output key
+func (store *MemoryStore) OutputKey(key string) (string, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	hash, ok := store.outputKeys[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return hash, nil
+}
+
+// Close does nothing for a MemoryStore
+func (store *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package indexer
+
+import (
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+	"github.com/turtlecoin/turtlecoin-rpc-go/internal/storage"
+)
+
+// ErrNotFound is returned by the lookups of a Store
+// when nothing is indexed under the given key
+var ErrNotFound = storage.ErrNotFound
+
+// Block is an indexed block. Transactions holds the hashes
+// of its transactions, the coinbase transaction first.
+type Block struct {
+	Height       int      `json:"height"`
+	Hash         string   `json:"hash"`
+	Timestamp    int64    `json:"timestamp"`
+	Transactions []string `json:"transactions"`
+}
+
+// Transaction is an indexed transaction along with the block including it
+type Transaction struct {
+	Hash        string           `json:"hash"`
+	BlockHeight int              `json:"blockHeight"`
+	BlockHash   string           `json:"blockHash"`
+	Coinbase    bool             `json:"coinbase"`
+	PublicKey   string           `json:"publicKey"`
+	PaymentID   string           `json:"paymentID,omitempty"`
+	UnlockTime  uint64           `json:"unlockTime"`
+	Inputs      []trpc.KeyInput  `json:"inputs,omitempty"`
+	Outputs     []trpc.KeyOutput `json:"outputs"`
+}
+
+// Store persists the indexed chain. Blocks are only ever added on top
+// of the tip and removed from the tip, so a Store holds a contiguous
+// range of heights. Lookups return ErrNotFound for unknown keys.
+// A Store must be safe for concurrent use.
+type Store interface {
+	// Tip returns the highest block
+	Tip() (*Block, error)
+	// AddBlock adds a block above the tip along with its transactions
+	AddBlock(block *Block, txs []*Transaction) error
+	// RemoveTip removes the highest block and its transactions
+	RemoveTip() (*Block, error)
+
+	// BlockByHeight returns the block at the height
+	BlockByHeight(height int) (*Block, error)
+	// BlocksByTime returns the blocks with a timestamp
+	// from start to end, both included, by height
+	BlocksByTime(start int64, end int64) ([]*Block, error)
+	// Transaction returns the transaction with the hash
+	Transaction(hash string) (*Transaction, error)
+	// TransactionsByPaymentID returns the transactions
+	// with the payment ID, by height
+	TransactionsByPaymentID(paymentID string) ([]*Transaction, error)
+	// KeyImage returns the hash of the transaction spending the key image
+	KeyImage(keyImage string) (string, error)
+	// OutputKey returns the hash of the transaction creating the output key
+	OutputKey(key string) (string, error)
+
+	// Close releases the resources held by the store
+	Close() error
+}
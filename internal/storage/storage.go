@@ -0,0 +1,36 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+// Package storage holds the helpers shared by the
+// stores of the indexer, payouts, sender and
+// confirmations packages
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrNotFound is returned by the lookups of a
+// store when nothing is stored under the given key
+var ErrNotFound = errors.New("Not found")
+
+// PutJSON stores v encoded as JSON under the key of the bucket
+func PutJSON(bucket *bolt.Bucket, key []byte, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, value)
+}
+
+// Uint64Key returns n as a big endian key,
+// so the keys are sorted by value
+func Uint64Key(n uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, n)
+	return key
+}
@@ -0,0 +1,191 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+// Package keys generates and derives the ed25519 keys of TurtleCoin
+// wallets and the addresses made from them, without any RPC. Keys are
+// hex encoded like everywhere else in turtlecoinrpc.
+package keys
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+
+	"filippo.io/edwards25519"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+	"github.com/turtlecoin/turtlecoin-rpc-go/cryptonote"
+	"github.com/turtlecoin/turtlecoin-rpc-go/mnemonic"
+)
+
+// ErrInvalidKey is returned when a key is not a hex encoded scalar or point
+var ErrInvalidKey = errors.New("Invalid key")
+
+// KeyPair is a private scalar and its public point
+type KeyPair struct {
+	PrivateKey string
+	PublicKey  string
+}
+
+// Wallet holds the keys of a wallet and its address
+type Wallet struct {
+	Spend   KeyPair
+	View    KeyPair
+	Address string
+}
+
+// GenerateKeyPair returns a random key pair from crypto/rand
+func GenerateKeyPair() (*KeyPair, error) {
+	var random [64]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return nil, err
+	}
+
+	scalar, err := edwards25519.NewScalar().SetUniformBytes(random[:])
+	if err != nil {
+		return nil, err
+	}
+	return keyPair(scalar), nil
+}
+
+// PublicKey returns the public key of a private key
+func PublicKey(privateKey string) (string, error) {
+	scalar, err := parseScalar(privateKey)
+	if err != nil {
+		return "", err
+	}
+	return keyPair(scalar).PublicKey, nil
+}
+
+// ViewKeyFromSpendKey derives the view key pair of a wallet from its
+// private spend key, the private view key being the keccak hash of
+// the private spend key reduced mod l
+func ViewKeyFromSpendKey(privateSpendKey string) (*KeyPair, error) {
+	spend, err := parseScalar(privateSpendKey)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := cryptonote.Keccak(spend.Bytes())
+
+	var wide [64]byte
+	copy(wide[:], hash[:])
+	view, err := edwards25519.NewScalar().SetUniformBytes(wide[:])
+	if err != nil {
+		return nil, err
+	}
+	return keyPair(view), nil
+}
+
+// Address returns the TRTL address of the public keys
+func Address(publicSpendKey string, publicViewKey string) (string, error) {
+	for _, key := range []string{publicSpendKey, publicViewKey} {
+		if _, err := parsePoint(key); err != nil {
+			return "", err
+		}
+	}
+
+	address := trpc.Address{
+		Prefix:         trpc.AddressPrefix,
+		PublicSpendKey: publicSpendKey,
+		PublicViewKey:  publicViewKey,
+	}
+	return address.String(), nil
+}
+
+// NewWallet generates a new spend key and returns the wallet
+// with its deterministic view key, like the wallets create them
+func NewWallet() (*Wallet, error) {
+	spend, err := GenerateKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	return FromSpendKey(spend.PrivateKey)
+}
+
+// FromSpendKey returns the wallet of a private spend key
+// with its deterministic view key
+func FromSpendKey(privateSpendKey string) (*Wallet, error) {
+	view, err := ViewKeyFromSpendKey(privateSpendKey)
+	if err != nil {
+		return nil, err
+	}
+	return FromKeys(privateSpendKey, view.PrivateKey)
+}
+
+// FromKeys returns the wallet of a private spend key and a private view key
+func FromKeys(privateSpendKey string, privateViewKey string) (*Wallet, error) {
+	spend, err := parseScalar(privateSpendKey)
+	if err != nil {
+		return nil, err
+	}
+	view, err := parseScalar(privateViewKey)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet := &Wallet{Spend: *keyPair(spend), View: *keyPair(view)}
+	wallet.Address, err = Address(wallet.Spend.PublicKey, wallet.View.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// FromSeed returns the wallet of a mnemonic seed in one of the
+// languages, or in one of mnemonic.Languages if none are given
+func FromSeed(seed string, languages ...*mnemonic.Language) (*Wallet, error) {
+	key, _, err := mnemonic.Decode(seed, languages...)
+	if err != nil {
+		return nil, err
+	}
+	return FromSpendKey(hex.EncodeToString(key))
+}
+
+// CheckKeys returns an error unless the private keys belong to the
+// address, such as the keys and address given to ImportKey
+func CheckKeys(privateSpendKey string, privateViewKey string, address string) error {
+	wallet, err := FromKeys(privateSpendKey, privateViewKey)
+	if err != nil {
+		return err
+	}
+	if wallet.Address != address {
+		return errors.New("Keys do not belong to the address")
+	}
+	return nil
+}
+
+// keyPair returns the key pair of a private scalar
+func keyPair(scalar *edwards25519.Scalar) *KeyPair {
+	public := new(edwards25519.Point).ScalarBaseMult(scalar)
+	return &KeyPair{
+		PrivateKey: hex.EncodeToString(scalar.Bytes()),
+		PublicKey:  hex.EncodeToString(public.Bytes()),
+	}
+}
+
+// parseScalar decodes a hex private key which must be reduced mod l
+func parseScalar(key string) (*edwards25519.Scalar, error) {
+	raw, err := hex.DecodeString(key)
+	if err != nil || len(raw) != 32 {
+		return nil, ErrInvalidKey
+	}
+	scalar, err := edwards25519.NewScalar().SetCanonicalBytes(raw)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	return scalar, nil
+}
+
+// parsePoint decodes a hex public key which must be a curve point
+func parsePoint(key string) (*edwards25519.Point, error) {
+	raw, err := hex.DecodeString(key)
+	if err != nil || len(raw) != 32 {
+		return nil, ErrInvalidKey
+	}
+	point, err := new(edwards25519.Point).SetBytes(raw)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	return point, nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package keys
+
+import (
+	"encoding/hex"
+	"testing"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+	"github.com/turtlecoin/turtlecoin-rpc-go/mnemonic"
+)
+
+// Address prefixes of the networks of the test wallets
+const (
+	moneroMainnetPrefix = 18
+	moneroTestnetPrefix = 53
+)
+
+// seedVectors are published test wallets of Monero, which shares the
+// seeds and key derivation of TurtleCoin, along with their addresses
+var seedVectors = []struct {
+	seed    string
+	prefix  uint64
+	address string
+}{
+	{
+		seed:    "velvet lymph giddy number token physics poetry unquoted nibs useful sabotage limits benches lifestyle eden nitrogen anvil fewest avoid batch vials washing fences goat unquoted",
+		prefix:  moneroMainnetPrefix,
+		address: "42ey1afDFnn4886T7196doS9GPMzexD9gXpsZJDwVjeRVdFCSoHnv7KPbBeGpzJBzHRCAs9UxqeoyFQMYbqSWYTfJJQAWDm",
+	},
+	{
+		seed:    "peeled mixture ionic radar utopia puddle buying illness nuns gadget river spout cavernous bounced paradise drunk looking cottage jump tequila melting went winter adjust spout",
+		prefix:  moneroMainnetPrefix,
+		address: "44Kbx4sJ7JDRDV5aAhLJzQCjDz2ViLRduE3ijDZu3osWKBjMGkV1XPk4pfDUMqt1Aiezvephdqm6YD19GKFD9ZcXVUTp6BW",
+	},
+	{
+		seed:    "silk mocked cucumber lettuce hope adrenalin aching lush roles fuel revamp baptism wrist long tender teardrop midst pastry pigment equip frying inbound pinched ravine frying",
+		prefix:  moneroTestnetPrefix,
+		address: "A1y9sbVt8nqhZAVm3me1U18rUVXcjeNKuBd1oE2cTs8biA9cozPMeyYLhe77nPv12JA3ejJN3qprmREriit2fi6tJDi99RR",
+	},
+}
+
+// turtleVector is the test wallet of turtlecoin-utils, whose seed
+// decodes to the private spend key published with it. The other keys
+// and the address were checked with an implementation independent of
+// this package.
+var turtleVector = struct {
+	seed    string
+	spend   KeyPair
+	view    KeyPair
+	address string
+}{
+	seed: "teeming taken piano ramped vegan jazz earth enjoy suture quick lied awkward ferry python often exotic cube hexagon ionic joyous cage abnormal hull jigsaw lied",
+	spend: KeyPair{
+		PrivateKey: "dd0c02d3202634821b4d9d91b63d919725f5c3e97e803f3512e52fb0dc2aab0c",
+		PublicKey:  "f71e440f9a5aab08dbdab0f4f36bba813660a0600f109b1371dc53be33f23c99",
+	},
+	view: KeyPair{
+		PrivateKey: "4d345902b04a1ee6618cb5481c15814cc443084bc2b8bd978ae7e483cc047706",
+		PublicKey:  "f0ba225065e1b9c2e43165b3e41f10fcb768853126dfa7e612a3df2deb332492",
+	},
+	address: "TRTLv3nzumGSpRsZWxkcbDhiVEfy9rAgX3X9b7z8XQAy9gwjB6cwr6BJ3P52a6TQUSfA4eXf3Avwz7W89J4doLuigLjUzQjvRqX",
+}
+
+func TestFromSeedTurtleCoin(t *testing.T) {
+	wallet, err := FromSeed(turtleVector.seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wallet.Spend != turtleVector.spend || wallet.View != turtleVector.view {
+		t.Errorf("keys = %+v, %+v", wallet.Spend, wallet.View)
+	}
+	if wallet.Address != turtleVector.address {
+		t.Errorf("address = %s, want %s", wallet.Address, turtleVector.address)
+	}
+
+	key, _ := hex.DecodeString(turtleVector.spend.PrivateKey)
+	if seed, err := mnemonic.English.Encode(key); err != nil || seed != turtleVector.seed {
+		t.Errorf("seed = %q, %v", seed, err)
+	}
+}
+
+func TestNewWallet(t *testing.T) {
+	wallet, err := NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The wallet comes back from its seed
+	key, _ := hex.DecodeString(wallet.Spend.PrivateKey)
+	seed, err := mnemonic.English.Encode(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := FromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *restored != *wallet {
+		t.Errorf("wallet %+v restored as %+v", wallet, restored)
+	}
+
+	// Its address holds its public keys
+	address, err := trpc.ParseAddress(wallet.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address.PublicSpendKey != wallet.Spend.PublicKey || address.PublicViewKey != wallet.View.PublicKey {
+		t.Errorf("address %s holds the keys %s, %s", wallet.Address, address.PublicSpendKey, address.PublicViewKey)
+	}
+	if err = CheckKeys(wallet.Spend.PrivateKey, wallet.View.PrivateKey, wallet.Address); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFromSeed(t *testing.T) {
+	for _, vector := range seedVectors {
+		wallet, err := FromSeed(vector.seed)
+		if err != nil {
+			t.Errorf("%.20s: %v", vector.seed, err)
+			continue
+		}
+
+		address := trpc.Address{
+			Prefix:         vector.prefix,
+			PublicSpendKey: wallet.Spend.PublicKey,
+			PublicViewKey:  wallet.View.PublicKey,
+		}
+		if address.String() != vector.address {
+			t.Errorf("%.20s: address = %s, want %s", vector.seed, address.String(), vector.address)
+		}
+
+		if err = CheckKeys(wallet.Spend.PrivateKey, wallet.View.PrivateKey, wallet.Address); err != nil {
+			t.Errorf("%.20s: %v", vector.seed, err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+// Package mnemonic converts between 32 byte private spend keys and the
+// 25 word seeds of the CryptoNote electrum scheme used by the TurtleCoin
+// wallets. Every 4 bytes of the key become 3 words and the 25th word is
+// a checksum picked by the CRC32 of the unique prefixes of the others.
+//
+// Only the English word list is bundled, as English, since the TurtleCoin
+// wallets only create English seeds. The word lists of the other electrum
+// languages are not part of this package, so Decode and keys.FromSeed do
+// not recognise such seeds unless the caller builds their Language with
+// NewLanguage from the 1626 words of the list and passes it in.
+package mnemonic
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"strconv"
+	"strings"
+)
+
+// Sizes of the words lists, keys and seeds
+const (
+	WordCount  = 1626
+	KeySize    = 32
+	SeedLength = KeySize/4*3 + 1
+)
+
+// ErrInvalidChecksum is returned when the checksum word of a seed does not match
+var ErrInvalidChecksum = errors.New("Invalid mnemonic seed checksum")
+
+// Language is a word list along with the number of leading
+// characters which tell its words apart
+type Language struct {
+	Name         string
+	PrefixLength int
+
+	words    []string
+	prefixes map[string]int
+}
+
+// NewLanguage checks that the word list has WordCount words
+// with distinct prefixes of prefixLength characters
+func NewLanguage(name string, prefixLength int, words []string) (*Language, error) {
+	if len(words) != WordCount {
+		return nil, errors.New("Word list must have " + strconv.Itoa(WordCount) + " words")
+	}
+	if prefixLength < 1 {
+		return nil, errors.New("Prefix length must be positive")
+	}
+
+	lang := &Language{
+		Name:         name,
+		PrefixLength: prefixLength,
+		words:        append([]string(nil), words...),
+		prefixes:     make(map[string]int, WordCount),
+	}
+	for i, word := range lang.words {
+		prefix := lang.prefix(word)
+		if _, ok := lang.prefixes[prefix]; ok {
+			return nil, errors.New("Word " + word + " shares its prefix with another word")
+		}
+		lang.prefixes[prefix] = i
+	}
+	return lang, nil
+}
+
+// Languages are the bundled languages, tried by Decode
+// when no languages are given
+var Languages = []*Language{English}
+
+// mustLanguage returns the language of a bundled word list
+func mustLanguage(name string, prefixLength int, words []string) *Language {
+	lang, err := NewLanguage(name, prefixLength, words)
+	if err != nil {
+		panic("mnemonic: " + name + ": " + err.Error())
+	}
+	return lang
+}
+
+// Words returns a copy of the word list
+func (lang *Language) Words() []string {
+	return append([]string(nil), lang.words...)
+}
+
+// Encode returns the seed of a private spend key
+func (lang *Language) Encode(key []byte) (string, error) {
+	if len(key) != KeySize {
+		return "", errors.New("Key must be " + strconv.Itoa(KeySize) + " bytes")
+	}
+
+	n := uint32(WordCount)
+	words := make([]string, 0, SeedLength)
+	for i := 0; i < KeySize; i += 4 {
+		x := binary.LittleEndian.Uint32(key[i:])
+		w1 := x % n
+		w2 := (x/n + w1) % n
+		w3 := (x/n/n + w2) % n
+		words = append(words, lang.words[w1], lang.words[w2], lang.words[w3])
+	}
+
+	words = append(words, words[lang.checksumIndex(words)])
+	return strings.Join(words, " "), nil
+}
+
+// Decode returns the private spend key of a seed after checking its
+// checksum. Words are matched on their prefix, like the wallets do.
+func (lang *Language) Decode(seed string) ([]byte, error) {
+	words := strings.Fields(strings.ToLower(seed))
+	if len(words) != SeedLength {
+		return nil, errors.New("Mnemonic seed must have " + strconv.Itoa(SeedLength) + " words")
+	}
+
+	indexes := make([]uint32, 0, SeedLength-1)
+	for _, word := range words[:SeedLength-1] {
+		i, ok := lang.prefixes[lang.prefix(word)]
+		if !ok {
+			return nil, errors.New("Word " + word + " is not in the " + lang.Name + " word list")
+		}
+		indexes = append(indexes, uint32(i))
+	}
+
+	checksum := words[lang.checksumIndex(words[:SeedLength-1])]
+	if lang.prefix(checksum) != lang.prefix(words[SeedLength-1]) {
+		return nil, ErrInvalidChecksum
+	}
+
+	n := uint32(WordCount)
+	key := make([]byte, 0, KeySize)
+	for i := 0; i < len(indexes); i += 3 {
+		w1, w2, w3 := indexes[i], indexes[i+1], indexes[i+2]
+		x := w1 + n*((n-w1+w2)%n) + n*n*((n-w2+w3)%n)
+		if x%n != w1 {
+			return nil, errors.New("Invalid mnemonic seed")
+		}
+		key = binary.LittleEndian.AppendUint32(key, x)
+	}
+	return key, nil
+}
+
+// Contains reports whether every word of the seed is in the word list
+func (lang *Language) Contains(seed string) bool {
+	for _, word := range strings.Fields(strings.ToLower(seed)) {
+		if _, ok := lang.prefixes[lang.prefix(word)]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Decode finds the language of the seed among the given languages,
+// or Languages if none are given, and returns the private spend key
+// of the seed
+func Decode(seed string, languages ...*Language) ([]byte, *Language, error) {
+	if len(languages) == 0 {
+		languages = Languages
+	}
+	for _, lang := range languages {
+		if lang.Contains(seed) {
+			key, err := lang.Decode(seed)
+			return key, lang, err
+		}
+	}
+	return nil, nil, errors.New("Mnemonic seed is in none of the given languages")
+}
+
+// prefix returns the first PrefixLength characters of the word
+func (lang *Language) prefix(word string) string {
+	runes := []rune(word)
+	if len(runes) > lang.PrefixLength {
+		runes = runes[:lang.PrefixLength]
+	}
+	return string(runes)
+}
+
+// checksumIndex returns the index of the word repeated as the checksum
+func (lang *Language) checksumIndex(words []string) int {
+	var prefixes strings.Builder
+	for _, word := range words {
+		prefixes.WriteString(lang.prefix(word))
+	}
+	return int(crc32.ChecksumIEEE([]byte(prefixes.String())) % uint32(len(words)))
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package mnemonic
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// seedVectors are the seeds of published test wallets and their
+// private spend keys. The keys are checked against the addresses of
+// the wallets in keys_test.go.
+var seedVectors = []struct {
+	seed string
+	key  string
+}{
+	{
+		seed: "velvet lymph giddy number token physics poetry unquoted nibs useful sabotage limits benches lifestyle eden nitrogen anvil fewest avoid batch vials washing fences goat unquoted",
+		key:  "148d78d2aba7dbca5cd8f6abcfb0b3c009ffbdbea1ff373d50ed94d78286640e",
+	},
+	{
+		seed: "peeled mixture ionic radar utopia puddle buying illness nuns gadget river spout cavernous bounced paradise drunk looking cottage jump tequila melting went winter adjust spout",
+		key:  "609ae8e228a871c37b61292ff898dd144db5d784804cc4a971bf74aff3acb70a",
+	},
+	{
+		seed: "silk mocked cucumber lettuce hope adrenalin aching lush roles fuel revamp baptism wrist long tender teardrop midst pastry pigment equip frying inbound pinched ravine frying",
+		key:  "930755a1918de1a087e68c37accc8160de9f625712425f1b276e7d0dd305120b",
+	},
+}
+
+func TestEnglish(t *testing.T) {
+	words := English.Words()
+	if len(words) != WordCount {
+		t.Fatalf("%d words, want %d", len(words), WordCount)
+	}
+	for i := 1; i < len(words); i++ {
+		if words[i-1] >= words[i] {
+			t.Errorf("%q is listed before %q", words[i-1], words[i])
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, vector := range seedVectors {
+		key, lang, err := Decode(vector.seed)
+		if err != nil {
+			t.Errorf("%.20s: %v", vector.seed, err)
+			continue
+		}
+		if lang != English {
+			t.Errorf("%.20s: language %s", vector.seed, lang.Name)
+		}
+		if hex.EncodeToString(key) != vector.key {
+			t.Errorf("%.20s: key = %x, want %s", vector.seed, key, vector.key)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	for _, vector := range seedVectors {
+		key, _ := hex.DecodeString(vector.key)
+		seed, err := English.Encode(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seed != vector.seed {
+			t.Errorf("seed = %q, want %q", seed, vector.seed)
+		}
+	}
+}
+
+func TestDecodePrefixes(t *testing.T) {
+	// The wallets only read the unique prefix of the words
+	words := strings.Fields(seedVectors[0].seed)
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:English.PrefixLength])
+	}
+
+	key, err := English.Decode(strings.Join(words, " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(key) != seedVectors[0].key {
+		t.Errorf("key = %x, want %s", key, seedVectors[0].key)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	for _, vector := range seedVectors {
+		words := strings.Fields(vector.seed)
+		checksum := words[SeedLength-1]
+		for _, word := range words[:SeedLength-1] {
+			if word == checksum {
+				continue
+			}
+			words[SeedLength-1] = word
+			if _, err := English.Decode(strings.Join(words, " ")); err != ErrInvalidChecksum {
+				t.Errorf("%.20s with checksum %s: err = %v, want %v", vector.seed, word, err, ErrInvalidChecksum)
+			}
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	words := strings.Fields(seedVectors[0].seed)
+	for _, seed := range []string{
+		"",
+		strings.Join(words[:SeedLength-1], " "),
+		strings.Join(append([]string{"notaword"}, words[1:]...), " "),
+	} {
+		if _, err := English.Decode(seed); err == nil {
+			t.Errorf("%q decoded", seed)
+		}
+	}
+	if _, _, err := Decode("notaword " + seedVectors[0].seed); err == nil {
+		t.Error("seed with an unknown word decoded")
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package payouts
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/turtlecoin/turtlecoin-rpc-go/internal/storage"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	sharesBucket   = []byte("shares")
+	blocksBucket   = []byte("blocks")
+	balancesBucket = []byte("balances")
+	batchesBucket  = []byte("batches")
+)
+
+// BoltStore is a Store kept in a bbolt database file. Shares, blocks
+// and batches are keyed by their sequence number and balances by
+// address.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens the database file at path, creating it if needed
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{sharesBucket, blocksBucket, balancesBucket, batchesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db}, nil
+}
+
+// AddShare appends a share and returns the number of shares
+func (store *BoltStore) AddShare(share Share) (uint64, error) {
+	var count uint64
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		shares := tx.Bucket(sharesBucket)
+		var err error
+		if count, err = shares.NextSequence(); err != nil {
+			return err
+		}
+		return storage.PutJSON(shares, storage.Uint64Key(count-1), share)
+	})
+	return count, err
+}
+
+// ShareCount returns the number of shares
+func (store *BoltStore) ShareCount() (uint64, error) {
+	var count uint64
+	err := store.db.View(func(tx *bolt.Tx) error {
+		count = tx.Bucket(sharesBucket).Sequence()
+		return nil
+	})
+	return count, err
+}
+
+// Shares returns the shares with an index from start to end, end excluded
+func (store *BoltStore) Shares(start uint64, end uint64) ([]Share, error) {
+	var shares []Share
+	err := store.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(sharesBucket).Cursor()
+		for key, value := cursor.Seek(storage.Uint64Key(start)); key != nil; key, value = cursor.Next() {
+			if binary.BigEndian.Uint64(key) >= end {
+				break
+			}
+			var share Share
+			if err := json.Unmarshal(value, &share); err != nil {
+				return err
+			}
+			shares = append(shares, share)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// AddBlock records a found block
+func (store *BoltStore) AddBlock(block *Block) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		blocks := tx.Bucket(blocksBucket)
+		id, err := blocks.NextSequence()
+		if err != nil {
+			return err
+		}
+		return storage.PutJSON(blocks, storage.Uint64Key(id), block)
+	})
+}
+
+// Blocks returns the found blocks in the order they were found
+func (store *BoltStore) Blocks() ([]*Block, error) {
+	var blocks []*Block
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(blocksBucket).ForEach(func(key []byte, value []byte) error {
+			var block Block
+			if err := json.Unmarshal(value, &block); err != nil {
+				return err
+			}
+			blocks = append(blocks, &block)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// SettleBlock stores the new status of a pending block and adds
+// the credits to the pending balances, all at once
+func (store *BoltStore) SettleBlock(block *Block, credits map[string]uint64) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		blocks := tx.Bucket(blocksBucket)
+		var key []byte
+		var stored Block
+		err := blocks.ForEach(func(k []byte, value []byte) error {
+			if key != nil {
+				return nil
+			}
+			if err := json.Unmarshal(value, &stored); err != nil {
+				return err
+			}
+			if stored.Hash == block.Hash {
+				key = append([]byte(nil), k...)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			return ErrNotFound
+		}
+		if err := checkSettle(int(stored.Status), int(block.Status)); err != nil {
+			return err
+		}
+		if err := storage.PutJSON(blocks, key, block); err != nil {
+			return err
+		}
+
+		for address, amount := range credits {
+			balance, err := getBalance(tx, address)
+			if err != nil {
+				return err
+			}
+			balance.Pending += amount
+			if err := storage.PutJSON(tx.Bucket(balancesBucket), []byte(address), balance); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Balance returns the balance of the address, which
+// is empty for addresses without credits
+func (store *BoltStore) Balance(address string) (*Balance, error) {
+	var balance *Balance
+	err := store.db.View(func(tx *bolt.Tx) error {
+		var err error
+		balance, err = getBalance(tx, address)
+		return err
+	})
+	return balance, err
+}
+
+// Balances returns the balances by address
+func (store *BoltStore) Balances() ([]*Balance, error) {
+	var balances []*Balance
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(balancesBucket).ForEach(func(key []byte, value []byte) error {
+			var balance Balance
+			if err := json.Unmarshal(value, &balance); err != nil {
+				return err
+			}
+			balances = append(balances, &balance)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+// AddBatch assigns an ID to a new batch and moves its amounts from
+// the failed and then pending balances to the sending balances
+func (store *BoltStore) AddBatch(batch *Batch) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		for _, payment := range batch.Payments {
+			balance, err := getBalance(tx, payment.Address)
+			if err != nil {
+				return err
+			}
+			if err := balance.debit(payment.Amount); err != nil {
+				return err
+			}
+			if err := storage.PutJSON(tx.Bucket(balancesBucket), []byte(payment.Address), balance); err != nil {
+				return err
+			}
+		}
+
+		batches := tx.Bucket(batchesBucket)
+		id, err := batches.NextSequence()
+		if err != nil {
+			return err
+		}
+		batch.ID = id
+		return storage.PutJSON(batches, storage.Uint64Key(id), batch)
+	})
+}
+
+// Batch returns the batch with the ID
+func (store *BoltStore) Batch(id uint64) (*Batch, error) {
+	var batch *Batch
+	err := store.db.View(func(tx *bolt.Tx) error {
+		var err error
+		batch, err = getBatch(tx, id)
+		return err
+	})
+	return batch, err
+}
+
+// Batches returns the batches by ID
+func (store *BoltStore) Batches() ([]*Batch, error) {
+	var batches []*Batch
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(batchesBucket).ForEach(func(key []byte, value []byte) error {
+			var batch Batch
+			if err := json.Unmarshal(value, &batch); err != nil {
+				return err
+			}
+			batches = append(batches, &batch)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+// SettleBatch stores the new status of a sending batch and moves
+// its amounts from the sending to the paid or failed balances
+func (store *BoltStore) SettleBatch(batch *Batch) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		stored, err := getBatch(tx, batch.ID)
+		if err != nil {
+			return err
+		}
+		if err := checkSettle(int(stored.Status), int(batch.Status)); err != nil {
+			return err
+		}
+
+		for _, payment := range stored.Payments {
+			balance, err := getBalance(tx, payment.Address)
+			if err != nil {
+				return err
+			}
+			if err := balance.settle(payment.Amount, batch.Status); err != nil {
+				return err
+			}
+			if err := storage.PutJSON(tx.Bucket(balancesBucket), []byte(payment.Address), balance); err != nil {
+				return err
+			}
+		}
+
+		stored.Status = batch.Status
+		stored.Hash = batch.Hash
+		stored.Error = batch.Error
+		return storage.PutJSON(tx.Bucket(batchesBucket), storage.Uint64Key(batch.ID), stored)
+	})
+}
+
+// Close closes the database file
+func (store *BoltStore) Close() error {
+	return store.db.Close()
+}
+
+func getBalance(tx *bolt.Tx, address string) (*Balance, error) {
+	if address == "" {
+		return nil, errors.New("Address is required")
+	}
+	balance := &Balance{Address: address}
+	value := tx.Bucket(balancesBucket).Get([]byte(address))
+	if value == nil {
+		return balance, nil
+	}
+	if err := json.Unmarshal(value, balance); err != nil {
+		return nil, err
+	}
+	return balance, nil
+}
+
+func getBatch(tx *bolt.Tx, id uint64) (*Batch, error) {
+	value := tx.Bucket(batchesBucket).Get(storage.Uint64Key(id))
+	if value == nil {
+		return nil, ErrNotFound
+	}
+	var batch Batch
+	if err := json.Unmarshal(value, &batch); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
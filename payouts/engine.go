@@ -0,0 +1,428 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package payouts
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+)
+
+// ErrOptimizing is returned when a transaction was rejected
+// and fusion transactions were sent to optimize the wallet
+var ErrOptimizing = errors.New("Wallet is being optimized, pay again once the fusion transactions are confirmed")
+
+// walletAPITooManyInputs is the error code of the wallet-api for a
+// transaction with too many inputs to fit in a block
+const walletAPITooManyInputs = 31
+
+// Engine pays many destinations through a WalletAPI. The payments are
+// grouped into transactions of up to MaxDestinations destinations and
+// MaxOutputs outputs, each amount making one output per non zero digit.
+// As a transaction holds a single payment ID, each integrated address
+// is paid in its own transaction.
+//
+// Every payout is recorded in the journal under an idempotency ID
+// before anything is sent. Each transaction is prepared by the wallet
+// and its hash recorded before the prepared transaction is sent, so a
+// transaction whose outcome is unknown, because the wallet failed to
+// answer or the process stopped, is looked up in the wallet by hash
+// and the same prepared transaction is sent again. A new transaction
+// is only prepared once the wallet refused the prepared one for
+// ResendAfter without knowing its hash.
+//
+// When the wallet cannot prepare a transaction and NeedsFusion reports
+// the wallet needs optimizing, up to MaxFusions fusion transactions are
+// sent. If the wallet is already optimized, the transaction is split
+// in two instead.
+type Engine struct {
+	Mixin           int
+	Fee             uint64
+	SourceAddresses []string
+	ChangeAddress   string
+	MaxDestinations int
+	MaxOutputs      int
+	MaxFusions      int
+	NeedsFusion     func(err error) bool
+	ResendAfter     time.Duration
+
+	wallet  *trpc.WalletAPI
+	journal Journal
+	mu      sync.Mutex
+}
+
+// NewEngine returns an engine paying through the wallet
+// and recording the payouts in the journal
+func NewEngine(wallet *trpc.WalletAPI, journal Journal) *Engine {
+	return &Engine{
+		Mixin:           3,
+		Fee:             10,
+		MaxDestinations: 50,
+		MaxOutputs:      100,
+		MaxFusions:      10,
+		NeedsFusion:     needsFusion,
+		ResendAfter:     10 * time.Minute,
+		wallet:          wallet,
+		journal:         journal,
+	}
+}
+
+// Journal returns the journal of the engine
+func (engine *Engine) Journal() Journal {
+	return engine.journal
+}
+
+// Pay makes the payments under the idempotency ID. Paying again under
+// the same ID only sends the transactions which were not sent yet, so
+// Pay can be retried after any error, or after a crash, without paying
+// anybody twice. The ID must not be reused for other payments.
+func (engine *Engine) Pay(id string, payments []Payment) (*Payout, error) {
+	if id == "" {
+		return nil, errors.New("Idempotency ID is required")
+	}
+	for _, payment := range payments {
+		if payment.Address == "" || payment.Amount == 0 {
+			return nil, errors.New("Every payment needs an address and an amount")
+		}
+	}
+
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	payout, err := engine.journal.Payout(id)
+	switch {
+	case err == ErrNotFound:
+		payout = &Payout{ID: id, Transactions: engine.split(payments), Created: time.Now()}
+		if err = engine.journal.PutPayout(payout); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case !samePayments(payout, payments):
+		return payout, errors.New("Idempotency ID was used for other payments")
+	}
+	return payout, engine.pay(payout)
+}
+
+// PayBatch pays a batch of the pool and settles it once all its
+// transactions are sent. The batch is paid under an ID made from
+// its own ID, so it can be paid again after any error.
+func (engine *Engine) PayBatch(pool *Pool, batch *Batch) (*Payout, error) {
+	payout, err := engine.Pay("batch/"+strconv.FormatUint(batch.ID, 10), batch.Payments)
+	if err != nil {
+		return payout, err
+	}
+	return payout, pool.Complete(batch, strings.Join(payout.Hashes(), ","))
+}
+
+// Retry pays again the payout with the idempotency ID
+func (engine *Engine) Retry(id string) (*Payout, error) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	payout, err := engine.journal.Payout(id)
+	if err != nil {
+		return nil, err
+	}
+	return payout, engine.pay(payout)
+}
+
+// Reconcile looks up in the wallet the transactions whose outcome is
+// unknown, as it should be done after a crash. It returns the payouts
+// which are not done, which can be completed with Retry.
+func (engine *Engine) Reconcile() ([]*Payout, error) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	payouts, err := engine.journal.Payouts()
+	if err != nil {
+		return nil, err
+	}
+
+	var incomplete []*Payout
+	for _, payout := range payouts {
+		for _, tx := range payout.Transactions {
+			if tx.Status != TransactionPending || tx.Hash == "" {
+				continue
+			}
+			if err := engine.reconcile(payout, tx); err != nil {
+				return incomplete, err
+			}
+		}
+		if !payout.Done() {
+			incomplete = append(incomplete, payout)
+		}
+	}
+	return incomplete, nil
+}
+
+// Optimize sends fusion transactions until the wallet refuses to or
+// MaxFusions are sent, and returns how many were sent. The error
+// refusing the first fusion is returned, which is usually the wallet
+// being fully optimized.
+func (engine *Engine) Optimize() (int, error) {
+	sent := 0
+	for sent < engine.MaxFusions {
+		var err error
+		if len(engine.SourceAddresses) > 0 {
+			destination := engine.ChangeAddress
+			if destination == "" {
+				destination = engine.SourceAddresses[0]
+			}
+			_, err = engine.wallet.SendAdvancedFusion(engine.Mixin, engine.SourceAddresses, destination)
+		} else {
+			_, err = engine.wallet.SendBasicFusion()
+		}
+		if err != nil {
+			if sent == 0 {
+				return 0, err
+			}
+			break
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// pay sends the transactions of the payout which were not sent yet
+func (engine *Engine) pay(payout *Payout) error {
+	for i := 0; i < len(payout.Transactions); i++ {
+		tx := payout.Transactions[i]
+		if tx.Status == TransactionSent {
+			continue
+		}
+		if tx.Status == TransactionPending && tx.Hash != "" {
+			if err := engine.resend(payout, tx); err != nil {
+				return err
+			}
+			if tx.Status == TransactionSent {
+				continue
+			}
+		}
+
+		split, err := engine.send(payout, i)
+		if err != nil {
+			return err
+		}
+		if split {
+			i--
+		}
+	}
+	return nil
+}
+
+// resend sends again the prepared transaction whose outcome is unknown,
+// unless the wallet knows it already. The transaction is dropped, to be
+// prepared again, when the wallet refuses it after ResendAfter.
+func (engine *Engine) resend(payout *Payout, tx *PayoutTransaction) error {
+	if err := engine.reconcile(payout, tx); err != nil || tx.Status == TransactionSent {
+		return err
+	}
+
+	_, err := engine.wallet.SendPreparedTransaction(tx.Hash)
+	if err == nil {
+		tx.Status = TransactionSent
+		tx.Error = ""
+		return engine.journal.PutPayout(payout)
+	}
+
+	if !refused(err) || time.Since(tx.Attempted) < engine.ResendAfter {
+		tx.Error = err.Error()
+		if putErr := engine.journal.PutPayout(payout); putErr != nil {
+			return putErr
+		}
+		return err
+	}
+
+	// The wallet refuses the prepared transaction and still does not
+	// know it in its history, so it is taken as never sent and prepared
+	// again
+	engine.wallet.CancelPreparedTransaction(tx.Hash)
+	tx.Hash = ""
+	tx.Error = err.Error()
+	return engine.journal.PutPayout(payout)
+}
+
+// send prepares and sends the transaction at index i of the payout. It
+// reports whether the transaction was split in two instead of being sent.
+func (engine *Engine) send(payout *Payout, i int) (bool, error) {
+	tx := payout.Transactions[i]
+	prepared, err := engine.wallet.PrepareAdvancedTransaction(
+		destinations(tx.Payments), engine.Mixin, int(engine.Fee),
+		engine.SourceAddresses, "", engine.ChangeAddress, 0)
+	if err == nil && prepared.TransactionHash == "" {
+		err = errors.New("Missing transaction hash in the response")
+	}
+	if err != nil {
+		return engine.prepareFailed(payout, i, err)
+	}
+
+	// Record the hash before sending, so a crash during the
+	// send leaves the transaction to be looked up by hash
+	tx.Status = TransactionPending
+	tx.Hash = prepared.TransactionHash
+	tx.Fee = prepared.Fee
+	tx.NodeFee = prepared.NodeFee
+	tx.Attempted = time.Now()
+	tx.Error = ""
+	if err := engine.journal.PutPayout(payout); err != nil {
+		return false, err
+	}
+
+	if _, err = engine.wallet.SendPreparedTransaction(tx.Hash); err != nil {
+		// The outcome is unknown, the next attempt resends it
+		tx.Error = err.Error()
+		if putErr := engine.journal.PutPayout(payout); putErr != nil {
+			return false, putErr
+		}
+		return false, err
+	}
+	tx.Status = TransactionSent
+	return false, engine.journal.PutPayout(payout)
+}
+
+// prepareFailed records the failure of the wallet to prepare the
+// transaction at index i of the payout, which sent nothing. It
+// optimizes the wallet or splits the transaction in two when
+// NeedsFusion reports the wallet needs optimizing.
+func (engine *Engine) prepareFailed(payout *Payout, i int, err error) (bool, error) {
+	tx := payout.Transactions[i]
+	tx.Status = TransactionFailed
+	tx.Error = err.Error()
+
+	if engine.NeedsFusion == nil || !engine.NeedsFusion(err) {
+		if putErr := engine.journal.PutPayout(payout); putErr != nil {
+			return false, putErr
+		}
+		return false, err
+	}
+
+	fusions, _ := engine.Optimize()
+	if fusions == 0 && len(tx.Payments) > 1 {
+		half := len(tx.Payments) / 2
+		first := &PayoutTransaction{Payments: tx.Payments[:half]}
+		second := &PayoutTransaction{Payments: tx.Payments[half:]}
+		transactions := append([]*PayoutTransaction{}, payout.Transactions[:i]...)
+		transactions = append(transactions, first, second)
+		payout.Transactions = append(transactions, payout.Transactions[i+1:]...)
+		return true, engine.journal.PutPayout(payout)
+	}
+
+	if putErr := engine.journal.PutPayout(payout); putErr != nil {
+		return false, putErr
+	}
+	if fusions > 0 {
+		return false, ErrOptimizing
+	}
+	return false, err
+}
+
+// reconcile marks the transaction as sent when the wallet
+// knows its hash, confirmed or not
+func (engine *Engine) reconcile(payout *Payout, tx *PayoutTransaction) error {
+	known, err := engine.wallet.HasTransaction(tx.Hash)
+	if err != nil || !known {
+		return err
+	}
+	tx.Status = TransactionSent
+	tx.Error = ""
+	return engine.journal.PutPayout(payout)
+}
+
+// refused reports whether the wallet answered the request with a
+// client error, so the request had no effect
+func refused(err error) bool {
+	var statusError *trpc.StatusError
+	return errors.As(err, &statusError) && statusError.StatusCode < http.StatusInternalServerError
+}
+
+// split groups the payments into transactions
+func (engine *Engine) split(payments []Payment) []*PayoutTransaction {
+	var transactions []*PayoutTransaction
+	var current []Payment
+	outputs := 0
+	flush := func() {
+		if len(current) > 0 {
+			transactions = append(transactions, &PayoutTransaction{Payments: current})
+		}
+		current = nil
+		outputs = 0
+	}
+
+	for _, payment := range payments {
+		if len(payment.Address) == trpc.IntegratedAddressLength {
+			transactions = append(transactions, &PayoutTransaction{Payments: []Payment{payment}})
+			continue
+		}
+
+		count := outputCount(payment.Amount)
+		if len(current) == engine.MaxDestinations || (len(current) > 0 && outputs+count > engine.MaxOutputs) {
+			flush()
+		}
+		current = append(current, payment)
+		outputs += count
+	}
+	flush()
+	return transactions
+}
+
+// outputCount returns the number of outputs paying the amount,
+// one per non zero digit as amounts are split in denominations
+func outputCount(amount uint64) int {
+	count := 0
+	for ; amount > 0; amount /= 10 {
+		if amount%10 != 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// samePayments reports whether the payout pays the same
+// amounts to the same addresses as the payments
+func samePayments(payout *Payout, payments []Payment) bool {
+	amounts := make(map[string]uint64)
+	for _, payment := range payments {
+		amounts[payment.Address] += payment.Amount
+	}
+	for _, tx := range payout.Transactions {
+		for _, payment := range tx.Payments {
+			if amounts[payment.Address] < payment.Amount {
+				return false
+			}
+			amounts[payment.Address] -= payment.Amount
+		}
+	}
+	for _, amount := range amounts {
+		if amount != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// needsFusion reports whether the wallet rejected a transaction
+// because it has too many inputs to fit in a block
+func needsFusion(err error) bool {
+	var statusError *trpc.StatusError
+	return errors.As(err, &statusError) && statusError.ErrorCode == walletAPITooManyInputs
+}
+
+// destinations returns the payments as taken
+// by WalletAPI.SendAdvancedTransaction
+func destinations(payments []Payment) []map[string]interface{} {
+	destinations := make([]map[string]interface{}, len(payments))
+	for i, payment := range payments {
+		destinations[i] = map[string]interface{}{
+			"address": payment.Address,
+			"amount":  int(payment.Amount),
+		}
+	}
+	return destinations
+}
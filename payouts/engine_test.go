@@ -0,0 +1,199 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package payouts
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+	"github.com/turtlecoin/turtlecoin-rpc-go/rpctest"
+)
+
+func newTestEngine(t *testing.T) (*rpctest.WalletAPI, *trpc.WalletAPI, *Engine) {
+	fake := rpctest.NewWalletAPI("secret")
+	t.Cleanup(fake.Close)
+	fake.Credit(1000000)
+
+	client := fake.Client()
+	return fake, client, NewEngine(client, NewMemoryJournal())
+}
+
+func dropPrepared(client *trpc.WalletAPI, afterSend bool) {
+	client.Transport = rpctest.NewFaultInjector(nil, 1, rpctest.Fault{
+		Kind:        rpctest.DropConnection,
+		Probability: 1,
+		Methods:     []string{"transactions/send/prepared"},
+		AfterSend:   afterSend,
+	})
+}
+
+var testPayments = []Payment{
+	{Address: "TRTLa", Amount: 1000},
+	{Address: "TRTLb", Amount: 2000},
+}
+
+func TestEnginePaysNodeFee(t *testing.T) {
+	fake, _, engine := newTestEngine(t)
+
+	payout, err := engine.Pay("p1", testPayments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !payout.Done() || len(payout.Transactions) != 1 {
+		t.Fatalf("payout not done: %+v", payout)
+	}
+	tx := payout.Transactions[0]
+	if tx.Fee != 10 || tx.NodeFee != fake.NodeFee {
+		t.Errorf("fees = %d, %d, want 10, %d", tx.Fee, tx.NodeFee, fake.NodeFee)
+	}
+	if balance := fake.Balance(); balance != 1000000-3000-10-fake.NodeFee {
+		t.Errorf("balance = %d", balance)
+	}
+
+	if _, err = engine.Pay("p1", testPayments); err != nil {
+		t.Fatal(err)
+	}
+	if outgoing := fake.Outgoing(); len(outgoing) != 1 || outgoing[0].Hash != tx.Hash {
+		t.Errorf("outgoing = %+v, want the single transaction %s", outgoing, tx.Hash)
+	}
+}
+
+func TestEngineLostResponse(t *testing.T) {
+	fake, client, engine := newTestEngine(t)
+
+	dropPrepared(client, true)
+	if _, err := engine.Pay("p1", testPayments); err == nil {
+		t.Fatal("expected the dropped response to fail the payout")
+	}
+	// A second payout of the same total must not be taken for the first
+	client.Transport = nil
+	other := []Payment{{Address: "TRTLc", Amount: 3000}}
+	if _, err := engine.Pay("p2", other); err != nil {
+		t.Fatal(err)
+	}
+
+	incomplete, err := NewEngine(client, engine.Journal()).Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(incomplete) != 0 {
+		t.Fatalf("incomplete payouts after reconcile: %+v", incomplete)
+	}
+
+	first, _ := engine.Journal().Payout("p1")
+	second, _ := engine.Journal().Payout("p2")
+	if first.Transactions[0].Hash == second.Transactions[0].Hash {
+		t.Error("both payouts claimed the same transaction")
+	}
+	if outgoing := fake.Outgoing(); len(outgoing) != 2 {
+		t.Errorf("%d outgoing transactions, want 2", len(outgoing))
+	}
+	if relayed := fake.Relayed(first.Transactions[0].Hash); relayed != 1 {
+		t.Errorf("first transaction relayed %d times, want 1", relayed)
+	}
+}
+
+func TestEngineResendsPrepared(t *testing.T) {
+	fake, client, engine := newTestEngine(t)
+
+	dropPrepared(client, false)
+	payout, err := engine.Pay("p1", testPayments)
+	if err == nil {
+		t.Fatal("expected the dropped request to fail the payout")
+	}
+	hash := payout.Transactions[0].Hash
+	if hash == "" || len(fake.Outgoing()) != 0 {
+		t.Fatalf("hash %q recorded, %d outgoing", hash, len(fake.Outgoing()))
+	}
+
+	client.Transport = nil
+	if _, err = engine.Pay("p1", testPayments); err != nil {
+		t.Fatal(err)
+	}
+	if outgoing := fake.Outgoing(); len(outgoing) != 1 || outgoing[0].Hash != hash {
+		t.Errorf("outgoing = %+v, want the prepared transaction %s", outgoing, hash)
+	}
+}
+
+func TestEnginePreparesAgainAfterRestart(t *testing.T) {
+	fake, client, engine := newTestEngine(t)
+
+	dropPrepared(client, false)
+	if _, err := engine.Pay("p1", testPayments); err == nil {
+		t.Fatal("expected the dropped request to fail the payout")
+	}
+	client.Transport = nil
+	fake.Restart()
+
+	// The lost prepared transaction is only replaced after ResendAfter
+	if _, err := engine.Pay("p1", testPayments); err == nil {
+		t.Fatal("expected the lost prepared transaction to fail the payout")
+	}
+	engine.ResendAfter = 0
+	payout, err := engine.Pay("p1", testPayments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outgoing := fake.Outgoing(); len(outgoing) != 1 || outgoing[0].Hash != payout.Transactions[0].Hash {
+		t.Errorf("outgoing = %+v, want a single transaction", outgoing)
+	}
+}
+
+// tooLarge is the message of the wallet-api for walletAPITooManyInputs
+const tooLarge = "The transaction is too large (in BYTES, not AMOUNT) to fit in a block. Either decrease the amount you are sending, perform fusion transactions, or decrease mixin (if possible)."
+
+func TestNeedsFusion(t *testing.T) {
+	tests := []struct {
+		err   error
+		fuses bool
+	}{
+		{&trpc.StatusError{StatusCode: 400, ErrorCode: walletAPITooManyInputs, Message: tooLarge}, true},
+		{fmt.Errorf("prepare: %w", &trpc.StatusError{StatusCode: 400, ErrorCode: walletAPITooManyInputs}), true},
+		{&trpc.StatusError{StatusCode: 400, ErrorCode: 10, Message: "Not enough balance, perform fusion transactions"}, false},
+		{&trpc.StatusError{StatusCode: 500, Message: "An exception was thrown whilst processing the request"}, false},
+		{errors.New(tooLarge), false},
+	}
+	for _, test := range tests {
+		if needsFusion(test.err) != test.fuses {
+			t.Errorf("needsFusion(%v) = %v", test.err, !test.fuses)
+		}
+	}
+}
+
+func TestEngineSplitsTooLarge(t *testing.T) {
+	tests := []struct {
+		code         int
+		transactions int
+	}{
+		{walletAPITooManyInputs, 2},
+		{10, 1},
+	}
+	for _, test := range tests {
+		_, client, engine := newTestEngine(t)
+		client.Transport = rpctest.NewFaultInjector(nil, 1, rpctest.Fault{
+			Kind:        rpctest.RPCError,
+			Probability: 1,
+			Methods:     []string{"transactions/prepare"},
+			Code:        test.code,
+			Message:     tooLarge,
+		})
+
+		// The fake wallet is already optimized, so a transaction too
+		// large is split down to a payment per transaction, and the
+		// payout stops at the first one failing
+		payout, err := engine.Pay("p1", testPayments)
+		var statusError *trpc.StatusError
+		if !errors.As(err, &statusError) || statusError.ErrorCode != test.code {
+			t.Fatalf("code %d: err = %v", test.code, err)
+		}
+		if len(payout.Transactions) != test.transactions {
+			t.Errorf("code %d: %d transactions, want %d", test.code, len(payout.Transactions), test.transactions)
+		}
+		if tx := payout.Transactions[0]; tx.Status != TransactionFailed || tx.Error != tooLarge {
+			t.Errorf("code %d: transaction %+v", test.code, tx)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package payouts
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/turtlecoin/turtlecoin-rpc-go/internal/storage"
+	bolt "go.etcd.io/bbolt"
+)
+
+var payoutsBucket = []byte("payouts")
+
+// TransactionStatus is the outcome of a transaction of a payout
+type TransactionStatus int
+
+const (
+	// TransactionPending is a transaction which was not sent yet,
+	// or whose outcome is unknown if Hash is set
+	TransactionPending TransactionStatus = iota
+	// TransactionSent is a transaction accepted by the wallet
+	TransactionSent
+	// TransactionFailed is a transaction the wallet could not
+	// prepare, which is prepared again by the next attempt
+	TransactionFailed
+)
+
+// PayoutTransaction is a transaction of a payout. Hash, Fee and
+// NodeFee are recorded once the wallet prepared the transaction,
+// before it is sent, and Attempted is the time of its first send.
+type PayoutTransaction struct {
+	Payments  []Payment         `json:"payments"`
+	Status    TransactionStatus `json:"status"`
+	Hash      string            `json:"hash,omitempty"`
+	Fee       uint64            `json:"fee"`
+	NodeFee   uint64            `json:"nodeFee"`
+	Error     string            `json:"error,omitempty"`
+	Attempted time.Time         `json:"attempted"`
+}
+
+// Payout is a set of payments made under an idempotency ID
+// along with the transactions paying them
+type Payout struct {
+	ID           string               `json:"id"`
+	Transactions []*PayoutTransaction `json:"transactions"`
+	Created      time.Time            `json:"created"`
+}
+
+// Journal persists the payouts of an Engine. Payout returns
+// ErrNotFound for unknown IDs. A Journal must be safe for
+// concurrent use.
+type Journal interface {
+	// Payout returns the payout with the idempotency ID
+	Payout(id string) (*Payout, error)
+	// PutPayout stores the payout, replacing the one with the same ID
+	PutPayout(payout *Payout) error
+	// Payouts returns the payouts by ID
+	Payouts() ([]*Payout, error)
+	// Close releases the resources held by the journal
+	Close() error
+}
+
+// Done reports whether every transaction of the payout was sent
+func (payout *Payout) Done() bool {
+	for _, tx := range payout.Transactions {
+		if tx.Status != TransactionSent {
+			return false
+		}
+	}
+	return true
+}
+
+// Hashes returns the hashes of the sent transactions of the payout
+func (payout *Payout) Hashes() []string {
+	var hashes []string
+	for _, tx := range payout.Transactions {
+		if tx.Status == TransactionSent {
+			hashes = append(hashes, tx.Hash)
+		}
+	}
+	return hashes
+}
+
+// MemoryJournal is a Journal keeping the payouts in a map,
+// it is lost when the process exits
+type MemoryJournal struct {
+	mutex   sync.RWMutex
+	payouts map[string][]byte
+}
+
+// NewMemoryJournal returns an empty MemoryJournal
+func NewMemoryJournal() *MemoryJournal {
+	return &MemoryJournal{payouts: make(map[string][]byte)}
+}
+
+// Payout returns the payout with the idempotency ID
+func (journal *MemoryJournal) Payout(id string) (*Payout, error) {
+	journal.mutex.RLock()
+	defer journal.mutex.RUnlock()
+
+	value, ok := journal.payouts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var payout Payout
+	if err := json.Unmarshal(value, &payout); err != nil {
+		return nil, err
+	}
+	return &payout, nil
+}
+
+// PutPayout stores the payout, replacing the one with the same ID
+func (journal *MemoryJournal) PutPayout(payout *Payout) error {
+	// The payout is stored encoded so later changes
+	// by the caller do not reach the journal
+	value, err := json.Marshal(payout)
+	if err != nil {
+		return err
+	}
+
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	journal.payouts[payout.ID] = value
+	return nil
+}
+
+// Payouts returns the payouts by ID
+func (journal *MemoryJournal) Payouts() ([]*Payout, error) {
+	journal.mutex.RLock()
+	defer journal.mutex.RUnlock()
+
+	payouts := make([]*Payout, 0, len(journal.payouts))
+	for _, value := range journal.payouts {
+		var payout Payout
+		if err := json.Unmarshal(value, &payout); err != nil {
+			return nil, err
+		}
+		payouts = append(payouts, &payout)
+	}
+	sort.Slice(payouts, func(i, j int) bool {
+		return payouts[i].ID < payouts[j].ID
+	})
+	return payouts, nil
+}
+
+// Close does nothing for a MemoryJournal
+func (journal *MemoryJournal) Close() error {
+	return nil
+}
+
+// BoltJournal is a Journal kept in a bbolt database
+// file, with the payouts keyed by ID
+type BoltJournal struct {
+	db *bolt.DB
+}
+
+// OpenBoltJournal opens the database file at path, creating it if needed
+func OpenBoltJournal(path string) (*BoltJournal, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(payoutsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltJournal{db}, nil
+}
+
+// Payout returns the payout with the idempotency ID
+func (journal *BoltJournal) Payout(id string) (*Payout, error) {
+	var payout *Payout
+	err := journal.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(payoutsBucket).Get([]byte(id))
+		if value == nil {
+			return ErrNotFound
+		}
+		payout = new(Payout)
+		return json.Unmarshal(value, payout)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payout, nil
+}
+
+// PutPayout stores the payout, replacing the one with the same ID.
+// The payout is synced to the file before PutPayout returns.
+func (journal *BoltJournal) PutPayout(payout *Payout) error {
+	return journal.db.Update(func(tx *bolt.Tx) error {
+		return storage.PutJSON(tx.Bucket(payoutsBucket), []byte(payout.ID), payout)
+	})
+}
+
+// Payouts returns the payouts by ID
+func (journal *BoltJournal) Payouts() ([]*Payout, error) {
+	var payouts []*Payout
+	err := journal.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(payoutsBucket).ForEach(func(key []byte, value []byte) error {
+			var payout Payout
+			if err := json.Unmarshal(value, &payout); err != nil {
+				return err
+			}
+			payouts = append(payouts, &payout)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payouts, nil
+}
+
+// Close closes the database file
+func (journal *BoltJournal) Close() error {
+	return journal.db.Close()
+}
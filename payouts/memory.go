@@ -0,0 +1,237 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package payouts
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemoryStore is a Store keeping everything in memory,
+// it is lost when the process exits
+type MemoryStore struct {
+	mutex    sync.RWMutex
+	shares   []Share
+	blocks   []*Block
+	balances map[string]*Balance
+	batches  []*Batch
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{balances: make(map[string]*Balance)}
+}
+
+// AddShare appends a share and returns the number of shares
+func (store *MemoryStore) AddShare(share Share) (uint64, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.shares = append(store.shares, share)
+	return uint64(len(store.shares)), nil
+}
+
+// ShareCount returns the number of shares
+func (store *MemoryStore) ShareCount() (uint64, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return uint64(len(store.shares)), nil
+}
+
+// Shares returns the shares with an index from start to end, end excluded
+func (store *MemoryStore) Shares(start uint64, end uint64) ([]Share, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	if end > uint64(len(store.shares)) {
+		end = uint64(len(store.shares))
+	}
+	if start >= end {
+		return nil, nil
+	}
+	return append([]Share(nil), store.shares[start:end]...), nil
+}
+
+// AddBlock records a found block
+func (store *MemoryStore) AddBlock(block *Block) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	copied := *block
+	store.blocks = append(store.blocks, &copied)
+	return nil
+}
+
+// Blocks returns the found blocks in the order they were found
+func (store *MemoryStore) Blocks() ([]*Block, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	blocks := make([]*Block, len(store.blocks))
+	for i, block := range store.blocks {
+		copied := *block
+		blocks[i] = &copied
+	}
+	return blocks, nil
+}
+
+// SettleBlock stores the new status of a pending block and adds
+// the credits to the pending balances, all at once
+func (store *MemoryStore) SettleBlock(block *Block, credits map[string]uint64) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var stored *Block
+	for _, b := range store.blocks {
+		if b.Hash == block.Hash {
+			stored = b
+		}
+	}
+	if stored == nil {
+		return ErrNotFound
+	}
+	if err := checkSettle(int(stored.Status), int(block.Status)); err != nil {
+		return err
+	}
+
+	*stored = *block
+	for address, amount := range credits {
+		store.balance(address).Pending += amount
+	}
+	return nil
+}
+
+// Balance returns the balance of the address, which
+// is empty for addresses without credits
+func (store *MemoryStore) Balance(address string) (*Balance, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	if balance, ok := store.balances[address]; ok {
+		copied := *balance
+		return &copied, nil
+	}
+	return &Balance{Address: address}, nil
+}
+
+// Balances returns the balances by address
+func (store *MemoryStore) Balances() ([]*Balance, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	balances := make([]*Balance, 0, len(store.balances))
+	for _, balance := range store.balances {
+		copied := *balance
+		balances = append(balances, &copied)
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		return balances[i].Address < balances[j].Address
+	})
+	return balances, nil
+}
+
+// AddBatch assigns an ID to a new batch and moves its amounts from
+// the failed and then pending balances to the sending balances
+func (store *MemoryStore) AddBatch(batch *Batch) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	// Check every payment before touching the balances
+	debited := make(map[string]Balance)
+	for _, payment := range batch.Payments {
+		balance, ok := debited[payment.Address]
+		if !ok {
+			balance = *store.balance(payment.Address)
+		}
+		if err := balance.debit(payment.Amount); err != nil {
+			return err
+		}
+		debited[payment.Address] = balance
+	}
+	for address, balance := range debited {
+		*store.balance(address) = balance
+	}
+
+	batch.ID = uint64(len(store.batches)) + 1
+	copied := *batch
+	store.batches = append(store.batches, &copied)
+	return nil
+}
+
+// Batch returns the batch with the ID
+func (store *MemoryStore) Batch(id uint64) (*Batch, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	if id == 0 || id > uint64(len(store.batches)) {
+		return nil, ErrNotFound
+	}
+	copied := *store.batches[id-1]
+	return &copied, nil
+}
+
+// Batches returns the batches by ID
+func (store *MemoryStore) Batches() ([]*Batch, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	batches := make([]*Batch, len(store.batches))
+	for i, batch := range store.batches {
+		copied := *batch
+		batches[i] = &copied
+	}
+	return batches, nil
+}
+
+// SettleBatch stores the new status of a sending batch and moves
+// its amounts from the sending to the paid or failed balances
+func (store *MemoryStore) SettleBatch(batch *Batch) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if batch.ID == 0 || batch.ID > uint64(len(store.batches)) {
+		return ErrNotFound
+	}
+	stored := store.batches[batch.ID-1]
+	if err := checkSettle(int(stored.Status), int(batch.Status)); err != nil {
+		return err
+	}
+
+	settled := make(map[string]Balance)
+	for _, payment := range stored.Payments {
+		balance, ok := settled[payment.Address]
+		if !ok {
+			balance = *store.balance(payment.Address)
+		}
+		if err := balance.settle(payment.Amount, batch.Status); err != nil {
+			return err
+		}
+		settled[payment.Address] = balance
+	}
+	for address, balance := range settled {
+		*store.balance(address) = balance
+	}
+
+	stored.Status = batch.Status
+	stored.Hash = batch.Hash
+	stored.Error = batch.Error
+	return nil
+}
+
+// Close does nothing for a MemoryStore
+func (store *MemoryStore) Close() error {
+	return nil
+}
+
+// balance returns the balance of the address, creating it if needed.
+// It must be called with the lock held for writing.
+func (store *MemoryStore) balance(address string) *Balance {
+	balance, ok := store.balances[address]
+	if !ok {
+		balance = &Balance{Address: address}
+		store.balances[address] = balance
+	}
+	return balance
+}
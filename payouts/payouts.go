@@ -0,0 +1,373 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+// Package payouts keeps the accounts of a mining pool. Accepted shares
+// are recorded per miner address, the rewards of found blocks are split
+// with PPLNS or PROP once the blocks mature, and the credited balances
+// are paid in batches sent with WalletAPI.SendAdvancedTransaction.
+// An Engine sends the batches, recording them in a Journal so no
+// payment is made twice when the wallet or the process fails.
+package payouts
+
+import (
+	"errors"
+	"math/bits"
+	"sync"
+	"time"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+	"github.com/turtlecoin/turtlecoin-rpc-go/stratum"
+)
+
+// MaturityDepth is the number of blocks on top of a found
+// block before its reward can be spent
+const MaturityDepth = 40
+
+// sharesPerRead is the number of shares read at once from the store
+const sharesPerRead = 1000
+
+// daemonInternalError is the error code of getblockheaderbyhash
+// for blocks unknown to the daemon
+const daemonInternalError = -5
+
+// Scheme is the way the reward of a block is split between the miners
+type Scheme int
+
+const (
+	// PPLNS pays the last shares up to Window times
+	// the difficulty of the block
+	PPLNS Scheme = iota
+	// PROP pays the shares of the round ended by the block
+	PROP
+)
+
+// Pool splits the rewards of the blocks found by a pool. Fee is the
+// part of every reward kept by the pool, between 0 and 1.
+//
+// Balances of at least MinPayout are paid in batches of up to
+// MaxPayments standard addresses. As a transaction holds a single
+// payment ID, each integrated address is paid in its own batch.
+type Pool struct {
+	Scheme        Scheme
+	Window        float64
+	Fee           float64
+	MaturityDepth int
+	MinPayout     uint64
+	MaxPayments   int
+
+	daemon *trpc.TurtleCoind
+	store  Store
+	mu     sync.Mutex
+}
+
+// New returns a pool keeping its accounts in the store and
+// checking found blocks with the daemon. It pays with PPLNS
+// over twice the difficulty of the blocks by default.
+func New(daemon *trpc.TurtleCoind, store Store) *Pool {
+	return &Pool{
+		Scheme:        PPLNS,
+		Window:        2,
+		MaturityDepth: MaturityDepth,
+		MinPayout:     100,
+		MaxPayments:   20,
+		daemon:        daemon,
+		store:         store,
+	}
+}
+
+// Store returns the store of the pool
+func (pool *Pool) Store() Store {
+	return pool.store
+}
+
+// AddShare records an accepted share of the miner at the address
+func (pool *Pool) AddShare(address string, difficulty uint64, at time.Time) error {
+	if address == "" {
+		return errors.New("Address is required")
+	}
+	_, err := pool.store.AddShare(Share{Address: address, Difficulty: difficulty, Time: at})
+	return err
+}
+
+// AddBlock records a block found by the pool. It ends the current round,
+// so it must be called right after the share which found the block.
+func (pool *Pool) AddBlock(hash string, height int) error {
+	count, err := pool.store.ShareCount()
+	if err != nil {
+		return err
+	}
+	return pool.addBlock(hash, height, count)
+}
+
+// addBlock records a block ending the round after count shares
+func (pool *Pool) addBlock(hash string, height int, count uint64) error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	blocks, err := pool.store.Blocks()
+	if err != nil {
+		return err
+	}
+
+	block := &Block{Hash: hash, Height: height, Found: time.Now(), Shares: count}
+	if len(blocks) > 0 {
+		block.RoundStart = blocks[len(blocks)-1].Shares
+	}
+	return pool.store.AddBlock(block)
+}
+
+// AddStratumShare records a share accepted by a stratum server,
+// along with the block it found if any
+func (pool *Pool) AddStratumShare(share stratum.Share) error {
+	if share.Login == "" {
+		return errors.New("Address is required")
+	}
+	count, err := pool.store.AddShare(Share{Address: share.Login, Difficulty: share.Difficulty, Time: share.Time})
+	if err != nil {
+		return err
+	}
+	if share.BlockHash != "" {
+		return pool.addBlock(share.BlockHash, share.Height, count)
+	}
+	return nil
+}
+
+// Update checks the pending blocks with the daemon. Orphaned blocks
+// are dropped and the rewards of matured blocks are credited to the
+// pending balances of the miners. Blocks unknown to the daemon stay
+// pending and are checked again by the next update. It returns the
+// settled blocks.
+func (pool *Pool) Update() ([]*Block, error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	blocks, err := pool.store.Blocks()
+	if err != nil {
+		return nil, err
+	}
+
+	var settled []*Block
+	for _, block := range blocks {
+		if block.Status != BlockPending {
+			continue
+		}
+
+		header, err := pool.blockHeader(block.Hash)
+		var rpcError *trpc.RPCError
+		if errors.As(err, &rpcError) && rpcError.Code == daemonInternalError {
+			continue
+		}
+		if err != nil {
+			return settled, err
+		}
+
+		var credits map[string]uint64
+		switch {
+		case header.OrphanStatus:
+			block.Status = BlockOrphaned
+		case header.Depth >= pool.MaturityDepth:
+			block.Status = BlockMatured
+			block.Reward = header.Reward
+			block.Difficulty = header.Difficulty
+			if credits, err = pool.Credits(block); err != nil {
+				return settled, err
+			}
+			block.Fee = block.Reward
+			for _, credit := range credits {
+				block.Fee -= credit
+			}
+		default:
+			continue
+		}
+
+		if err = pool.store.SettleBlock(block, credits); err != nil {
+			return settled, err
+		}
+		settled = append(settled, block)
+	}
+	return settled, nil
+}
+
+// Credits returns the split of the reward of a block, once the fee of
+// the pool is taken. Each credit is rounded down, and the rounding
+// dust, less than one atomic unit per address, goes to the pool along
+// with its fee. Update records both in Block.Fee.
+func (pool *Pool) Credits(block *Block) (map[string]uint64, error) {
+	weights, total, err := pool.weights(block)
+	if err != nil {
+		return nil, err
+	}
+
+	credits := make(map[string]uint64)
+	if total == 0 {
+		return credits, nil
+	}
+	reward := block.Reward - uint64(float64(block.Reward)*pool.Fee)
+	for address, weight := range weights {
+		if credit := mulDiv(reward, weight, total); credit > 0 {
+			credits[address] = credit
+		}
+	}
+	return credits, nil
+}
+
+// weights returns the difficulty of the shares of each
+// address paid by the block, along with their sum
+func (pool *Pool) weights(block *Block) (map[string]uint64, uint64, error) {
+	weights := make(map[string]uint64)
+	var total uint64
+
+	if pool.Scheme == PROP {
+		for start := block.RoundStart; start < block.Shares; start += sharesPerRead {
+			end := start + sharesPerRead
+			if end > block.Shares {
+				end = block.Shares
+			}
+			shares, err := pool.store.Shares(start, end)
+			if err != nil {
+				return nil, 0, err
+			}
+			for _, share := range shares {
+				weights[share.Address] += share.Difficulty
+				total += share.Difficulty
+			}
+		}
+		return weights, total, nil
+	}
+
+	// PPLNS goes back from the block until the window is full,
+	// counting part of the oldest share if needed
+	window := uint64(pool.Window * float64(block.Difficulty))
+	for end := block.Shares; end > 0 && total < window; {
+		start := uint64(0)
+		if end > sharesPerRead {
+			start = end - sharesPerRead
+		}
+		shares, err := pool.store.Shares(start, end)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i := len(shares) - 1; i >= 0 && total < window; i-- {
+			weight := shares[i].Difficulty
+			if weight > window-total {
+				weight = window - total
+			}
+			weights[shares[i].Address] += weight
+			total += weight
+		}
+		end = start
+	}
+	return weights, total, nil
+}
+
+// NextBatches creates batches paying every balance of at least
+// MinPayout. The batches are stored as sending, and must be settled
+// with Complete or Fail once their transaction is sent.
+func (pool *Pool) NextBatches() ([]*Batch, error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	balances, err := pool.store.Balances()
+	if err != nil {
+		return nil, err
+	}
+
+	var groups [][]Payment
+	var standard []Payment
+	for _, balance := range balances {
+		due := balance.Pending + balance.Failed
+		if due == 0 || due < pool.MinPayout {
+			continue
+		}
+
+		payment := Payment{Address: balance.Address, Amount: due}
+		if len(balance.Address) == trpc.IntegratedAddressLength {
+			groups = append(groups, []Payment{payment})
+			continue
+		}
+		standard = append(standard, payment)
+		if len(standard) == pool.MaxPayments {
+			groups = append(groups, standard)
+			standard = nil
+		}
+	}
+	if len(standard) > 0 {
+		groups = append(groups, standard)
+	}
+
+	var batches []*Batch
+	for _, payments := range groups {
+		batch := &Batch{Payments: payments, Status: BatchSending, Created: time.Now()}
+		if err := pool.store.AddBatch(batch); err != nil {
+			return batches, err
+		}
+		batches = append(batches, batch)
+	}
+	return batches, nil
+}
+
+// Complete settles a batch sent in the transaction with the hash
+func (pool *Pool) Complete(batch *Batch, hash string) error {
+	batch.Status = BatchPaid
+	batch.Hash = hash
+	return pool.store.SettleBatch(batch)
+}
+
+// Fail settles a batch whose transaction failed, its
+// payments are made again by the next batches
+func (pool *Pool) Fail(batch *Batch, reason error) error {
+	batch.Status = BatchFailed
+	if reason != nil {
+		batch.Error = reason.Error()
+	}
+	return pool.store.SettleBatch(batch)
+}
+
+// Destinations returns the payments of the batch as taken
+// by WalletAPI.SendAdvancedTransaction
+func (batch *Batch) Destinations() []map[string]interface{} {
+	return destinations(batch.Payments)
+}
+
+// Total returns the sum of the payments of the batch
+func (batch *Batch) Total() uint64 {
+	var total uint64
+	for _, payment := range batch.Payments {
+		total += payment.Amount
+	}
+	return total
+}
+
+// blockHeader is the part of a block header used to settle a block
+type blockHeader struct {
+	Depth        int    `json:"depth"`
+	OrphanStatus bool   `json:"orphan_status"`
+	Reward       uint64 `json:"reward"`
+	Difficulty   uint64 `json:"difficulty"`
+}
+
+func (pool *Pool) blockHeader(hash string) (*blockHeader, error) {
+	resp, err := pool.daemon.GetBlockHeaderByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	header, err := trpc.RPCResult(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		BlockHeader blockHeader `json:"block_header"`
+	}
+	if err = trpc.ConvertResponse(header, &result); err != nil {
+		return nil, err
+	}
+	return &result.BlockHeader, nil
+}
+
+// mulDiv returns a * b / c without overflowing, b must not exceed c
+func mulDiv(a uint64, b uint64, c uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	quotient, _ := bits.Div64(hi, lo, c)
+	return quotient
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package payouts
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turtlecoin/turtlecoin-rpc-go/rpctest"
+)
+
+// testShare is a share of the address "a", "b" or "c"
+type testShare struct {
+	address    string
+	difficulty uint64
+}
+
+func newTestPool(t *testing.T, shares []testShare) *Pool {
+	pool := New(nil, NewMemoryStore())
+	for _, share := range shares {
+		if err := pool.AddShare(share.address, share.difficulty, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return pool
+}
+
+func TestPPLNSWeights(t *testing.T) {
+	many := make([]testShare, 2500)
+	for i := range many {
+		many[i] = testShare{"a", 1}
+		if i >= 2000 {
+			many[i].address = "b"
+		}
+	}
+
+	tests := []struct {
+		name       string
+		shares     []testShare
+		window     float64
+		difficulty uint64
+		weights    map[string]uint64
+	}{
+		{
+			name:       "partial oldest share",
+			shares:     []testShare{{"a", 100}, {"b", 100}, {"c", 150}},
+			window:     2,
+			difficulty: 100,
+			weights:    map[string]uint64{"b": 50, "c": 150},
+		},
+		{
+			name:       "window ends on a share",
+			shares:     []testShare{{"a", 100}, {"b", 100}, {"c", 100}},
+			window:     2,
+			difficulty: 100,
+			weights:    map[string]uint64{"b": 100, "c": 100},
+		},
+		{
+			name:       "window beyond the first share",
+			shares:     []testShare{{"a", 10}, {"b", 20}, {"a", 30}},
+			window:     2,
+			difficulty: 100,
+			weights:    map[string]uint64{"a": 40, "b": 20},
+		},
+		{
+			name:       "fractional window",
+			shares:     []testShare{{"a", 100}, {"b", 100}},
+			window:     0.5,
+			difficulty: 101,
+			weights:    map[string]uint64{"b": 50},
+		},
+		{
+			name:       "window over several reads",
+			shares:     many,
+			window:     1.2,
+			difficulty: 1000,
+			weights:    map[string]uint64{"a": 700, "b": 500},
+		},
+	}
+	for _, test := range tests {
+		pool := newTestPool(t, test.shares)
+		pool.Window = test.window
+		block := &Block{Shares: uint64(len(test.shares)), Difficulty: test.difficulty}
+		weights, total, err := pool.weights(block)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		var sum uint64
+		for _, weight := range test.weights {
+			sum += weight
+		}
+		if !reflect.DeepEqual(weights, test.weights) || total != sum {
+			t.Errorf("%s: weights %v, total %d, want %v", test.name, weights, total, test.weights)
+		}
+	}
+}
+
+func TestPROPCredits(t *testing.T) {
+	shares := []testShare{{"a", 100}, {"b", 300}, {"a", 200}, {"c", 100}, {"b", 300}}
+	tests := []struct {
+		name    string
+		block   Block
+		fee     float64
+		credits map[string]uint64
+	}{
+		{
+			name:    "first round",
+			block:   Block{RoundStart: 0, Shares: 2, Reward: 1000},
+			credits: map[string]uint64{"a": 250, "b": 750},
+		},
+		{
+			name:    "later round with a fee",
+			block:   Block{RoundStart: 2, Shares: 5, Reward: 1000},
+			fee:     0.1,
+			credits: map[string]uint64{"a": 300, "b": 450, "c": 150},
+		},
+		{
+			name:    "rounded down",
+			block:   Block{RoundStart: 1, Shares: 4, Reward: 100},
+			credits: map[string]uint64{"a": 33, "b": 50, "c": 16},
+		},
+		{
+			name:    "round without shares",
+			block:   Block{RoundStart: 5, Shares: 5, Reward: 1000},
+			credits: map[string]uint64{},
+		},
+	}
+	for _, test := range tests {
+		pool := newTestPool(t, shares)
+		pool.Scheme = PROP
+		pool.Fee = test.fee
+		credits, err := pool.Credits(&test.block)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(credits, test.credits) {
+			t.Errorf("%s: credits %v, want %v", test.name, credits, test.credits)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	daemon := rpctest.NewTurtleCoind()
+	defer daemon.Close()
+	pool := New(daemon.Client(), NewMemoryStore())
+	pool.Fee = 0.02
+
+	// Three equal shares leave dust, which goes to the pool
+	for _, address := range []string{"a", "b", "c"} {
+		pool.AddShare(address, 10, time.Now())
+	}
+	if err := pool.AddBlock(strings.Repeat("00", 32), 1); err != nil {
+		t.Fatal(err)
+	}
+	hash := daemon.MineBlock()
+	if err := pool.AddBlock(hash, daemon.Height()-1); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < pool.MaturityDepth; i++ {
+		daemon.MineBlock()
+	}
+
+	// The block unknown to the daemon is left pending
+	settled, err := pool.Update()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(settled) != 1 || settled[0].Hash != hash || settled[0].Status != BlockMatured {
+		t.Fatalf("settled = %+v, want %s matured", settled, hash)
+	}
+	reward := daemon.Reward
+	fee := reward * 2 / 100
+	credit := (reward - fee) / 3
+	if block := settled[0]; block.Reward != reward || block.Fee != reward-3*credit || block.Fee == fee {
+		t.Errorf("reward %d with fee %d, want %d with fee %d", block.Reward, block.Fee, reward, reward-3*credit)
+	}
+	balances, _ := pool.Store().Balances()
+	for _, balance := range balances {
+		if balance.Pending != credit {
+			t.Errorf("%s credited %d, want %d", balance.Address, balance.Pending, credit)
+		}
+	}
+	blocks, _ := pool.Store().Blocks()
+	if blocks[0].Status != BlockPending {
+		t.Errorf("unknown block %+v", blocks[0])
+	}
+
+	// Other errors of the daemon stop the update
+	daemon.Close()
+	if _, err = pool.Update(); err == nil {
+		t.Error("update without a daemon succeeded")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package payouts
+
+import (
+	"errors"
+	"time"
+
+	"github.com/turtlecoin/turtlecoin-rpc-go/internal/storage"
+)
+
+// ErrNotFound is returned by the lookups of a Store
+// when nothing is stored under the given key
+var ErrNotFound = storage.ErrNotFound
+
+// Share is an accepted share of a miner
+type Share struct {
+	Address    string    `json:"address"`
+	Difficulty uint64    `json:"difficulty"`
+	Time       time.Time `json:"time"`
+}
+
+// BlockStatus tells whether the reward of a block was credited
+type BlockStatus int
+
+const (
+	// BlockPending is a block waiting for maturity
+	BlockPending BlockStatus = iota
+	// BlockMatured is a block whose reward was credited
+	BlockMatured
+	// BlockOrphaned is a block which left the main chain
+	BlockOrphaned
+)
+
+// Block is a block found by the pool. Shares is the number of shares
+// recorded when it was found, and RoundStart the number recorded when
+// the previous block was found. Reward and Difficulty are filled in
+// once the block matures, along with Fee, the part of the reward kept
+// by the pool: its fee and the rounding dust of the credits.
+type Block struct {
+	Hash       string      `json:"hash"`
+	Height     int         `json:"height"`
+	Found      time.Time   `json:"found"`
+	Shares     uint64      `json:"shares"`
+	RoundStart uint64      `json:"roundStart"`
+	Status     BlockStatus `json:"status"`
+	Reward     uint64      `json:"reward"`
+	Difficulty uint64      `json:"difficulty"`
+	Fee        uint64      `json:"fee"`
+}
+
+// Balance is the ledger of an address. Pending is credited and not
+// yet in a batch, Sending is in batches whose outcome is not known
+// yet, and Failed is in failed batches and is paid by the next batch.
+type Balance struct {
+	Address string `json:"address"`
+	Pending uint64 `json:"pending"`
+	Sending uint64 `json:"sending"`
+	Paid    uint64 `json:"paid"`
+	Failed  uint64 `json:"failed"`
+}
+
+// BatchStatus is the outcome of a batch
+type BatchStatus int
+
+const (
+	// BatchSending is a batch which is not settled yet
+	BatchSending BatchStatus = iota
+	// BatchPaid is a batch sent in the transaction Hash
+	BatchPaid
+	// BatchFailed is a batch whose transaction failed
+	BatchFailed
+)
+
+// Payment is the amount paid to an address by a batch
+type Payment struct {
+	Address string `json:"address"`
+	Amount  uint64 `json:"amount"`
+}
+
+// Batch is a set of payments sent in a single transaction
+type Batch struct {
+	ID       uint64      `json:"id"`
+	Payments []Payment   `json:"payments"`
+	Status   BatchStatus `json:"status"`
+	Hash     string      `json:"hash,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Created  time.Time   `json:"created"`
+}
+
+// Store persists the shares, found blocks and ledger of a Pool.
+// Lookups return ErrNotFound for unknown keys. A Store must be
+// safe for concurrent use.
+type Store interface {
+	// AddShare appends a share and returns the number of shares
+	AddShare(share Share) (uint64, error)
+	// ShareCount returns the number of shares
+	ShareCount() (uint64, error)
+	// Shares returns the shares with an index from start to end, end excluded
+	Shares(start uint64, end uint64) ([]Share, error)
+
+	// AddBlock records a found block
+	AddBlock(block *Block) error
+	// Blocks returns the found blocks in the order they were found
+	Blocks() ([]*Block, error)
+	// SettleBlock stores the new status of a pending block and adds
+	// the credits to the pending balances, all at once
+	SettleBlock(block *Block, credits map[string]uint64) error
+
+	// Balance returns the balance of the address, which
+	// is empty for addresses without credits
+	Balance(address string) (*Balance, error)
+	// Balances returns the balances by address
+	Balances() ([]*Balance, error)
+
+	// AddBatch assigns an ID to a new batch and moves its amounts from
+	// the failed and then pending balances to the sending balances
+	AddBatch(batch *Batch) error
+	// Batch returns the batch with the ID
+	Batch(id uint64) (*Batch, error)
+	// Batches returns the batches by ID
+	Batches() ([]*Batch, error)
+	// SettleBatch stores the new status of a sending batch and moves
+	// its amounts from the sending to the paid or failed balances
+	SettleBatch(batch *Batch) error
+
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// debit moves amount from the failed and then pending balance to the
+// sending balance, as done for the payments of a new batch
+func (balance *Balance) debit(amount uint64) error {
+	if amount > balance.Failed+balance.Pending {
+		return errors.New("Balance of " + balance.Address + " is lower than its payment")
+	}
+	fromFailed := amount
+	if fromFailed > balance.Failed {
+		fromFailed = balance.Failed
+	}
+	balance.Failed -= fromFailed
+	balance.Pending -= amount - fromFailed
+	balance.Sending += amount
+	return nil
+}
+
+// settle moves amount from the sending balance to the paid
+// or failed balance according to the status of its batch
+func (balance *Balance) settle(amount uint64, status BatchStatus) error {
+	if amount > balance.Sending {
+		return errors.New("Balance of " + balance.Address + " is not sending its payment")
+	}
+	balance.Sending -= amount
+	if status == BatchPaid {
+		balance.Paid += amount
+	} else {
+		balance.Failed += amount
+	}
+	return nil
+}
+
+// checkSettle checks a block or batch leaves its first status
+// for a final one
+func checkSettle(stored int, settled int) error {
+	if stored != 0 {
+		return errors.New("Already settled")
+	}
+	if settled == 0 {
+		return errors.New("Settled status must be final")
+	}
+	return nil
+}
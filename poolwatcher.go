@@ -0,0 +1,239 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package turtlecoinrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// PoolChanges is the response of GetPoolChangesLite
+type PoolChanges struct {
+	IsTailBlockActual bool                    `json:"isTailBlockActual"`
+	AddedTxs          []TransactionPrefixInfo `json:"addedTxs"`
+	DeletedTxsIds     []string                `json:"deletedTxsIds"`
+	Status            string                  `json:"status"`
+}
+
+/*
+GetPoolChangesLite method returns the transactions added to and removed from the pool
+compared to the given known transactions. IsTailBlockActual is false if the given tail
+block is no longer the top block of the chain.
+*/
+func (daemon *TurtleCoind) GetPoolChangesLite(ctx context.Context, tailBlockID string, knownTxsIds []string) (*PoolChanges, error) {
+	daemon.check()
+	if knownTxsIds == nil {
+		knownTxsIds = []string{}
+	}
+	params := make(map[string]interface{})
+	params["tailBlockId"] = tailBlockID
+	params["knownTxsIds"] = knownTxsIds
+	resp, err := daemon.makeJSONRequest(ctx, "get_pool_changes_lite", params)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	var changes PoolChanges
+	if err = ConvertResponse(resp, &changes); err != nil {
+		return nil, err
+	}
+	return &changes, nil
+}
+
+// PoolEventType tells whether a transaction entered or left the pool
+type PoolEventType int
+
+const (
+	// PoolTransactionAdded is sent when a transaction enters the pool
+	PoolTransactionAdded PoolEventType = iota
+	// PoolTransactionRemoved is sent when a transaction leaves the pool,
+	// either mined into a block or dropped
+	PoolTransactionRemoved
+)
+
+// PoolEvent is a change of the transaction pool. Prefix holds the
+// transaction prefix for additions reported by GetPoolChangesLite
+// and is empty otherwise.
+type PoolEvent struct {
+	Type   PoolEventType
+	Hash   string
+	Prefix json.RawMessage
+}
+
+// PoolWatcher follows the transaction pool of a daemon. Events
+// is closed once the watcher stops, after which Err returns the
+// reason it stopped.
+type PoolWatcher struct {
+	Events <-chan PoolEvent
+
+	daemon *TurtleCoind
+	events chan PoolEvent
+	known  map[string]bool
+	tail   string
+	err    error
+}
+
+/*
+WatchPool method starts a PoolWatcher which polls GetPoolChangesLite for the changes of
+the pool since the given tail block and known transactions, as saved by a previous
+watcher. With an empty tail block the whole pool is fetched first, and an Added event is
+sent for every transaction in the pool which is not known. When the top block changes the
+whole pool is fetched again and compared to the known transactions. The watcher stops when
+the context is cancelled or a request fails.
+*/
+func (daemon *TurtleCoind) WatchPool(ctx context.Context, tailBlockID string, knownTxsIds []string) *PoolWatcher {
+	events := make(chan PoolEvent, 100)
+	watcher := &PoolWatcher{
+		Events: events,
+		daemon: daemon,
+		events: events,
+		known:  make(map[string]bool, len(knownTxsIds)),
+		tail:   tailBlockID,
+	}
+	for _, hash := range knownTxsIds {
+		watcher.known[hash] = true
+	}
+
+	go watcher.run(ctx)
+	return watcher
+}
+
+// Err returns the error which stopped the watcher. It must
+// only be called once Events is closed.
+func (watcher *PoolWatcher) Err() error {
+	return watcher.err
+}
+
+func (watcher *PoolWatcher) run(ctx context.Context) {
+	defer close(watcher.events)
+
+	var err error
+	if watcher.tail == "" {
+		err = watcher.resync(ctx)
+	} else {
+		err = watcher.poll(ctx)
+	}
+	for err == nil {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			continue
+		case <-time.After(watcher.daemon.pollInterval()):
+		}
+
+		err = watcher.poll(ctx)
+	}
+
+	// Requests cut short by the context fail with it
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	watcher.err = err
+}
+
+func (watcher *PoolWatcher) poll(ctx context.Context) error {
+	known := make([]string, 0, len(watcher.known))
+	for hash := range watcher.known {
+		known = append(known, hash)
+	}
+
+	changes, err := watcher.daemon.GetPoolChangesLite(ctx, watcher.tail, known)
+	if err != nil {
+		return err
+	}
+	if !changes.IsTailBlockActual {
+		return watcher.resync(ctx)
+	}
+
+	for _, tx := range changes.AddedTxs {
+		if err = watcher.add(ctx, tx.TxHash, tx.TxPrefix); err != nil {
+			return err
+		}
+	}
+	for _, hash := range changes.DeletedTxsIds {
+		if err = watcher.remove(ctx, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resync fetches the top block and the whole pool and
+// sends the differences with the known transactions
+func (watcher *PoolWatcher) resync(ctx context.Context) error {
+	tail, err := watcher.daemon.blockHeaderByHeight(ctx, -1)
+	if err != nil {
+		return err
+	}
+	if tail.Hash == "" {
+		return errors.New("Missing block hash in the response")
+	}
+
+	resp, err := watcher.daemon.makePostRequest(ctx, "f_on_transactions_pool_json", make(map[string]interface{}))
+	if err != nil {
+		return err
+	}
+	obj, _ := resp.(map[string]interface{})
+	result, err := RPCResult(obj)
+	if err != nil {
+		return err
+	}
+
+	var pool struct {
+		Transactions []struct {
+			Hash string `json:"hash"`
+		} `json:"transactions"`
+	}
+	if err = ConvertResponse(result, &pool); err != nil {
+		return err
+	}
+
+	current := make(map[string]bool, len(pool.Transactions))
+	for _, tx := range pool.Transactions {
+		current[tx.Hash] = true
+		if err = watcher.add(ctx, tx.Hash, nil); err != nil {
+			return err
+		}
+	}
+	for hash := range watcher.known {
+		if !current[hash] {
+			if err = watcher.remove(ctx, hash); err != nil {
+				return err
+			}
+		}
+	}
+
+	watcher.tail = tail.Hash
+	return nil
+}
+
+func (watcher *PoolWatcher) add(ctx context.Context, hash string, prefix json.RawMessage) error {
+	if watcher.known[hash] {
+		return nil
+	}
+	watcher.known[hash] = true
+	return watcher.send(ctx, PoolEvent{Type: PoolTransactionAdded, Hash: hash, Prefix: prefix})
+}
+
+func (watcher *PoolWatcher) remove(ctx context.Context, hash string) error {
+	if !watcher.known[hash] {
+		return nil
+	}
+	delete(watcher.known, hash)
+	return watcher.send(ctx, PoolEvent{Type: PoolTransactionRemoved, Hash: hash})
+}
+
+func (watcher *PoolWatcher) send(ctx context.Context, event PoolEvent) error {
+	select {
+	case watcher.events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
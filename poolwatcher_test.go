@@ -0,0 +1,197 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package turtlecoinrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testPool is the pool and top block of a daemon answering the
+// requests of a PoolWatcher, counting the full pool fetches
+type testPool struct {
+	mu      sync.Mutex
+	tail    string
+	txs     []string
+	fetches int
+}
+
+func (pool *testPool) set(tail string, txs ...string) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.tail = tail
+	pool.txs = txs
+}
+
+func (pool *testPool) fullFetches() int {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	return pool.fetches
+}
+
+func (pool *testPool) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Method      string   `json:"method"`
+		TailBlockID string   `json:"tailBlockId"`
+		KnownTxsIds []string `json:"knownTxsIds"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if r.URL.Path == "/get_pool_changes_lite" {
+		known := make(map[string]bool)
+		for _, hash := range req.KnownTxsIds {
+			known[hash] = true
+		}
+		changes := PoolChanges{
+			IsTailBlockActual: req.TailBlockID == pool.tail,
+			AddedTxs:          []TransactionPrefixInfo{},
+			DeletedTxsIds:     []string{},
+			Status:            "OK",
+		}
+		for _, hash := range pool.txs {
+			if !known[hash] {
+				changes.AddedTxs = append(changes.AddedTxs, TransactionPrefixInfo{hash, json.RawMessage(`{"version":1}`)})
+			}
+			delete(known, hash)
+		}
+		for hash := range known {
+			changes.DeletedTxsIds = append(changes.DeletedTxsIds, hash)
+		}
+		json.NewEncoder(w).Encode(changes)
+		return
+	}
+
+	var result interface{}
+	switch req.Method {
+	case "getlastblockheader":
+		result = map[string]interface{}{"block_header": map[string]interface{}{"hash": pool.tail}, "status": "OK"}
+	case "f_on_transactions_pool_json":
+		pool.fetches++
+		var txs []map[string]interface{}
+		for _, hash := range pool.txs {
+			txs = append(txs, map[string]interface{}{"hash": hash})
+		}
+		result = map[string]interface{}{"transactions": txs, "status": "OK"}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "result": result})
+}
+
+// expectPoolEvents reads as many events as wanted from the watcher
+// and compares them regardless of their order, as "+hash" for the
+// additions and "-hash" for the removals
+func expectPoolEvents(t *testing.T, step string, watcher *PoolWatcher, want ...string) {
+	t.Helper()
+	var got []string
+	for range want {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				t.Fatalf("%s: watcher stopped: %v", step, watcher.Err())
+			}
+			if event.Type == PoolTransactionAdded {
+				got = append(got, "+"+event.Hash+string(event.Prefix))
+			} else {
+				got = append(got, "-"+event.Hash)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: events %v, want %v", step, got, want)
+		}
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("%s: events %v, want %v", step, got, want)
+	}
+}
+
+// expectPoolStop waits for the watcher to stop and returns its error
+func expectPoolStop(t *testing.T, watcher *PoolWatcher) error {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return watcher.Err()
+			}
+			t.Errorf("unexpected event %+v", event)
+		case <-timeout:
+			t.Fatal("watcher did not stop")
+		}
+	}
+}
+
+func TestWatchPool(t *testing.T) {
+	pool := &testPool{}
+	pool.set("t1", "a", "b")
+	daemon := testDaemon(t, pool.serveHTTP)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The transactions already in the pool come without prefix
+	watcher := daemon.WatchPool(ctx, "", nil)
+	expectPoolEvents(t, "start", watcher, "+a", "+b")
+
+	pool.set("t1", "b", "c")
+	expectPoolEvents(t, "changes", watcher, `+c{"version":1}`, "-a")
+
+	// A new top block makes the watcher fetch the whole pool again
+	pool.set("t2", "c", "d")
+	expectPoolEvents(t, "new top block", watcher, "+d", "-b")
+	if fetches := pool.fullFetches(); fetches != 2 {
+		t.Errorf("pool fetched %d times, want 2", fetches)
+	}
+
+	cancel()
+	if err := expectPoolStop(t, watcher); err != context.Canceled {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWatchPoolResume(t *testing.T) {
+	pool := &testPool{}
+	pool.set("t1", "a", "b")
+	daemon := testDaemon(t, pool.serveHTTP)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Only the changes since the state saved are sent
+	watcher := daemon.WatchPool(ctx, "t1", []string{"a", "x"})
+	expectPoolEvents(t, "resumed", watcher, `+b{"version":1}`, "-x")
+	if fetches := pool.fullFetches(); fetches != 0 {
+		t.Errorf("pool fetched %d times, want 0", fetches)
+	}
+	cancel()
+	expectPoolStop(t, watcher)
+
+	// A saved tail block which is no longer the top one is resynced
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	watcher = daemon.WatchPool(ctx, "t0", []string{"a", "x"})
+	expectPoolEvents(t, "stale tail block", watcher, "+b", "-x")
+	if fetches := pool.fullFetches(); fetches != 1 {
+		t.Errorf("pool fetched %d times, want 1", fetches)
+	}
+}
+
+func TestWatchPoolError(t *testing.T) {
+	daemon := testDaemon(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"error":   map[string]interface{}{"code": -9, "message": "Core is busy"},
+		})
+	})
+	watcher := daemon.WatchPool(context.Background(), "", nil)
+	if err := expectPoolStop(t, watcher); err == nil || err.Error() != "Core is busy" {
+		t.Errorf("err = %v, want Core is busy", err)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+// Package pow computes the Chukwa proof of work of TurtleCoin blocks,
+// so shares of miners can be checked before calling SubmitBlock.
+package pow
+
+import (
+	"errors"
+	"math/bits"
+	"strconv"
+
+	"github.com/turtlecoin/turtlecoin-rpc-go/cryptonote"
+	"golang.org/x/crypto/argon2"
+)
+
+// Block major versions switching to the Chukwa variants
+const (
+	ChukwaMajorVersion   = 6
+	ChukwaV2MajorVersion = 7
+)
+
+// ErrInsufficientWork is returned by VerifyBlock when the proof
+// of work of the block does not meet the difficulty
+var ErrInsufficientWork = errors.New("Block hash does not meet the difficulty")
+
+// Variant holds the Argon2id parameters of a Chukwa variant. The salt
+// is the first SaltLength bytes of the hashed blob.
+type Variant struct {
+	Iterations  uint32
+	Memory      uint32 // in KiB
+	Parallelism uint8
+	SaltLength  int
+}
+
+// Chukwa variants used by the network
+var (
+	Chukwa   = Variant{Iterations: 3, Memory: 512, Parallelism: 1, SaltLength: 16}
+	ChukwaV2 = Variant{Iterations: 4, Memory: 1024, Parallelism: 1, SaltLength: 16}
+)
+
+// Hash returns the proof of work hash of the blob
+func (variant Variant) Hash(blob []byte) (cryptonote.Key, error) {
+	var hash cryptonote.Key
+	if len(blob) < variant.SaltLength {
+		return hash, errors.New("Blob is shorter than the salt")
+	}
+	key := argon2.IDKey(blob, blob[:variant.SaltLength], variant.Iterations, variant.Memory, variant.Parallelism, uint32(len(hash)))
+	copy(hash[:], key)
+	return hash, nil
+}
+
+// VariantForVersion returns the Chukwa variant of blocks of the major
+// version. Older blocks use CryptoNight variants, which are not supported.
+func VariantForVersion(majorVersion uint64) (Variant, error) {
+	switch {
+	case majorVersion >= ChukwaV2MajorVersion:
+		return ChukwaV2, nil
+	case majorVersion >= ChukwaMajorVersion:
+		return Chukwa, nil
+	}
+	return Variant{}, errors.New("Unsupported block major version " + strconv.FormatUint(majorVersion, 10))
+}
+
+// CheckHash reports whether the hash, read as a little endian 256 bit
+// number, times the difficulty does not overflow 256 bits
+func CheckHash(hash cryptonote.Key, difficulty uint64) bool {
+	var carry uint64
+	for i := 0; i < len(hash); i += 8 {
+		var word uint64
+		for j := 7; j >= 0; j-- {
+			word = word<<8 | uint64(hash[i+j])
+		}
+		hi, lo := bits.Mul64(word, difficulty)
+		var c uint64
+		_, c = bits.Add64(lo, carry, 0)
+		carry = hi + c
+	}
+	return carry == 0
+}
+
+// VerifyBlock checks the proof of work of a block, such as a block
+// template with the nonce of a miner, against the difficulty returned
+// by GetBlockTemplate. It returns the proof of work hash of the block.
+func VerifyBlock(block *cryptonote.Block, difficulty uint64) (cryptonote.Key, error) {
+	variant, err := VariantForVersion(block.MajorVersion)
+	if err != nil {
+		return cryptonote.Key{}, err
+	}
+	hash, err := variant.Hash(block.HashingBlob())
+	if err != nil {
+		return hash, err
+	}
+	if !CheckHash(hash, difficulty) {
+		return hash, ErrInsufficientWork
+	}
+	return hash, nil
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package pow
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// vectors are the reference vectors of the Chukwa variants
+var vectors = []struct {
+	name    string
+	variant Variant
+	input   string
+	hash    string
+}{
+	{
+		name:    "chukwa",
+		variant: Chukwa,
+		input:   "0100fb8e8ac805899323371bb790db19218afd8db8e3755d8b90f39b3d5506a9abce4fa912244500000000ee8146d49fa93ee724deb57d12cbc6c6f3b924d946127c7a97418f9348828f0f02",
+		hash:    "c0dad0eeb9c52e92a1c3aa5b76a3cb90bd7376c28dce191ceeb1096e3a390d2e",
+	},
+	{
+		name:    "chukwa v2",
+		variant: ChukwaV2,
+		input:   "0100fb8e8ac805899323371bb790db19218afd8db8e3755d8b90f39b3d5506a9abce4fa912244500000000ee8146d49fa93ee724deb57d12cbc6c6f3b924d946127c7a97418f9348828f0f02",
+		hash:    "3578c135261366a7bac407b8c0ff50f3ad96f096ec2813e9644e6e77a43f803d",
+	},
+}
+
+func TestVectors(t *testing.T) {
+	for _, vector := range vectors {
+		input, err := hex.DecodeString(vector.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash, err := vector.variant.Hash(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hash.String() != vector.hash {
+			t.Errorf("%s: hash = %s, want %s", vector.name, hash, vector.hash)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package rpctest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FaultKind selects what a Fault does to a request
+type FaultKind int
+
+const (
+	// Latency delays the request before it is forwarded
+	Latency FaultKind = iota
+	// DropConnection fails the request with ErrConnectionDropped
+	DropConnection
+	// ServerError answers with the given HTTP status
+	ServerError
+	// TruncateBody forwards the request and cuts the response body in half
+	TruncateBody
+	// RPCError answers with an error object carrying the given code and message
+	RPCError
+)
+
+// ErrConnectionDropped is returned for requests hit by a DropConnection fault
+var ErrConnectionDropped = errors.New("rpctest: connection dropped by fault injector")
+
+// Fault describes a failure injected with the given probability
+// into the requests for the selected methods. Methods are JSON-RPC
+// method names such as "getblocktemplate" or request paths such
+// as "getinfo" and "transactions/send"; paths match their children.
+// An empty Methods list selects every request.
+type Fault struct {
+	Kind        FaultKind
+	Probability float64
+	Methods     []string
+
+	// Delay is the added latency of a Latency fault
+	Delay time.Duration
+
+	// AfterSend makes a DropConnection fault forward the request
+	// and drop the response, so the server sees the request
+	AfterSend bool
+
+	// Status is the HTTP status of a ServerError fault,
+	// http.StatusInternalServerError is used if it is 0
+	Status int
+
+	// Code and Message make up the error object of a RPCError fault
+	Code    int
+	Message string
+}
+
+func (fault *Fault) matches(method string) bool {
+	if len(fault.Methods) == 0 {
+		return true
+	}
+	for _, m := range fault.Methods {
+		if method == m || strings.HasPrefix(method, m+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// FaultInjector is a http.RoundTripper which injects the configured
+// faults into requests before forwarding them to the next transport.
+// Faults are rolled in order for every request; latency adds up and
+// the first other fault which fires decides the outcome. The random
+// source is seeded so a sequence of requests fails the same way on
+// every run.
+type FaultInjector struct {
+	Next   http.RoundTripper
+	Faults []Fault
+
+	mu       sync.Mutex
+	rng      *rand.Rand
+	injected map[FaultKind]int
+}
+
+// NewFaultInjector returns a FaultInjector forwarding to next,
+// http.DefaultTransport is used if next is nil
+func NewFaultInjector(next http.RoundTripper, seed int64, faults ...Fault) *FaultInjector {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &FaultInjector{
+		Next:     next,
+		Faults:   faults,
+		rng:      rand.New(rand.NewSource(seed)),
+		injected: make(map[FaultKind]int),
+	}
+}
+
+// Injected returns the number of times each kind of fault fired
+func (injector *FaultInjector) Injected() map[FaultKind]int {
+	injector.mu.Lock()
+	defer injector.mu.Unlock()
+
+	injected := make(map[FaultKind]int, len(injector.injected))
+	for kind, count := range injector.injected {
+		injected[kind] = count
+	}
+	return injected
+}
+
+// roll picks the faults which fire for the request
+func (injector *FaultInjector) roll(method string) (time.Duration, *Fault) {
+	injector.mu.Lock()
+	defer injector.mu.Unlock()
+
+	var delay time.Duration
+	for i := range injector.Faults {
+		fault := &injector.Faults[i]
+		if !fault.matches(method) || injector.rng.Float64() >= fault.Probability {
+			continue
+		}
+
+		injector.injected[fault.Kind]++
+		if fault.Kind == Latency {
+			delay += fault.Delay
+			continue
+		}
+		return delay, fault
+	}
+	return delay, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (injector *FaultInjector) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcID, method := requestMethod(req, body)
+	delay, fault := injector.roll(method)
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	if fault == nil {
+		return injector.Next.RoundTrip(req)
+	}
+
+	switch fault.Kind {
+	case DropConnection:
+		if fault.AfterSend {
+			resp, err := injector.Next.RoundTrip(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}
+		return nil, ErrConnectionDropped
+	case ServerError:
+		status := fault.Status
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		return newResponse(req, status, nil), nil
+	case TruncateBody:
+		resp, err := injector.Next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		respBody, err := readBody(&resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(respBody[:len(respBody)/2]))
+		resp.ContentLength = int64(len(respBody) / 2)
+		resp.Header.Del("Content-Length")
+		return resp, nil
+	case RPCError:
+		// JSON-RPC requests get an error object in a 200 response,
+		// wallet-api requests get a 400 with an errorMessage
+		if req.URL.Path == "/json_rpc" {
+			return newResponse(req, http.StatusOK, marshal(rpcResponse{
+				JSONRPC: "2.0",
+				ID:      rpcID,
+				Error:   newError(fault.Code, fault.Message),
+			})), nil
+		}
+		return newResponse(req, http.StatusBadRequest, marshal(map[string]interface{}{
+			"errorCode":    fault.Code,
+			"errorMessage": fault.Message,
+		})), nil
+	}
+
+	return injector.Next.RoundTrip(req)
+}
+
+// requestMethod returns the JSON-RPC id and method of a
+// request, or the request path for plain HTTP requests
+func requestMethod(req *http.Request, body []byte) (interface{}, string) {
+	if req.URL.Path == "/json_rpc" {
+		var rpc struct {
+			ID     interface{} `json:"id"`
+			Method string      `json:"method"`
+		}
+		if json.Unmarshal(body, &rpc) == nil {
+			return rpc.ID, rpc.Method
+		}
+	}
+	return nil, strings.TrimPrefix(req.URL.Path, "/")
+}
+
+func newResponse(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package rpctest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// Scrubbed replaces every secret value written to a golden file
+const Scrubbed = "<scrubbed>"
+
+// ScrubbedFields are the JSON fields whose values are replaced by
+// Scrubbed in recorded requests and responses. Fields are matched
+// at any depth of the body.
+var ScrubbedFields = map[string]bool{
+	"password":              true,
+	"spendSecretKey":        true,
+	"viewSecretKey":         true,
+	"privateSpendKey":       true,
+	"privateViewKey":        true,
+	"mnemonicSeed":          true,
+	"transactionPrivateKey": true,
+}
+
+// Interaction is a single request/response pair of a golden file
+type Interaction struct {
+	Method       string          `json:"method"`
+	Path         string          `json:"path"`
+	RequestBody  json.RawMessage `json:"requestBody,omitempty"`
+	Status       int             `json:"status"`
+	ResponseBody json.RawMessage `json:"responseBody,omitempty"`
+}
+
+// scrub replaces the values of the scrubbed fields of a
+// JSON body and returns it in a canonical form. Bodies
+// which are not JSON are returned as a JSON string.
+func scrub(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return marshal(string(body))
+	}
+
+	return marshal(scrubValue(v))
+}
+
+// marshal encodes v without escaping HTML characters
+// so the golden files stay readable
+func marshal(v interface{}) json.RawMessage {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(v)
+	return bytes.TrimRight(buf.Bytes(), "\n")
+}
+
+func scrubValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if ScrubbedFields[key] {
+				value[key] = Scrubbed
+			} else {
+				value[key] = scrubValue(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = scrubValue(item)
+		}
+	}
+	return v
+}
+
+// readBody reads the body and replaces it with
+// a fresh reader so it can be consumed again
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil {
+		return nil, nil
+	}
+
+	buf, err := ioutil.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+
+	*body = ioutil.NopCloser(bytes.NewReader(buf))
+	return buf, nil
+}
+
+// Recorder is a http.RoundTripper which forwards requests to the
+// next transport and captures every request/response pair. The
+// X-API-KEY header is never captured and the ScrubbedFields are
+// replaced so golden files are safe to commit.
+type Recorder struct {
+	Next http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+// NewRecorder returns a Recorder forwarding to next,
+// http.DefaultTransport is used if next is nil
+func NewRecorder(next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{Next: next}
+}
+
+// RoundTrip implements http.RoundTripper
+func (recorder *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := recorder.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder.mu.Lock()
+	recorder.interactions = append(recorder.interactions, Interaction{
+		Method:       req.Method,
+		Path:         req.URL.Path,
+		RequestBody:  scrub(reqBody),
+		Status:       resp.StatusCode,
+		ResponseBody: scrub(respBody),
+	})
+	recorder.mu.Unlock()
+
+	return resp, nil
+}
+
+// Interactions returns the pairs captured so far
+func (recorder *Recorder) Interactions() []Interaction {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	return append([]Interaction(nil), recorder.interactions...)
+}
+
+// Save writes the captured pairs to the golden file at path
+func (recorder *Recorder) Save(path string) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(recorder.Interactions()); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// Replayer is a http.RoundTripper which answers requests from the
+// pairs of a golden file without touching the network. A request
+// matches a pair when its method, path and scrubbed body are equal,
+// and each pair is served once in recorded order. Unmatched requests
+// fail with an error.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+	unmatched    []string
+}
+
+// NewReplayer returns a Replayer serving the given pairs
+func NewReplayer(interactions []Interaction) *Replayer {
+	// Bodies loaded from indented golden files are
+	// brought back to the form produced by scrub
+	for i := range interactions {
+		interactions[i].RequestBody = scrub(interactions[i].RequestBody)
+		interactions[i].ResponseBody = scrub(interactions[i].ResponseBody)
+	}
+	return &Replayer{
+		interactions: interactions,
+		used:         make([]bool, len(interactions)),
+	}
+}
+
+// LoadReplayer returns a Replayer serving the
+// pairs of the golden file at path
+func LoadReplayer(path string) (*Replayer, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var interactions []Interaction
+	if err = json.Unmarshal(data, &interactions); err != nil {
+		return nil, err
+	}
+
+	return NewReplayer(interactions), nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (replayer *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	body := scrub(reqBody)
+
+	replayer.mu.Lock()
+	defer replayer.mu.Unlock()
+
+	for i, interaction := range replayer.interactions {
+		if replayer.used[i] ||
+			interaction.Method != req.Method ||
+			interaction.Path != req.URL.Path ||
+			!bytes.Equal(interaction.RequestBody, body) {
+			continue
+		}
+
+		replayer.used[i] = true
+		return newResponse(req, interaction.Status, interaction.ResponseBody), nil
+	}
+
+	request := req.Method + " " + req.URL.Path + " " + string(body)
+	replayer.unmatched = append(replayer.unmatched, request)
+	return nil, errors.New("rpctest: no recorded interaction for " + request)
+}
+
+// Unmatched returns the requests which had no recorded pair
+func (replayer *Replayer) Unmatched() []string {
+	replayer.mu.Lock()
+	defer replayer.mu.Unlock()
+
+	return append([]string(nil), replayer.unmatched...)
+}
+
+// Remaining returns the recorded pairs which were never served
+func (replayer *Replayer) Remaining() []Interaction {
+	replayer.mu.Lock()
+	defer replayer.mu.Unlock()
+
+	var remaining []Interaction
+	for i, interaction := range replayer.interactions {
+		if !replayer.used[i] {
+			remaining = append(remaining, interaction)
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package rpctest
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+)
+
+var update = flag.Bool("update", false, "record the golden files of the tests again")
+
+// walletdGolden is a session recorded with a fake walletd by
+// TestRecordGolden, replayed by TestReplayGolden
+const walletdGolden = "testdata/walletd.json"
+
+// walletdSession makes the requests of walletdGolden and
+// returns the status and the view key answered
+func walletdSession(t *testing.T, client *trpc.Walletd) (map[string]interface{}, string) {
+	t.Helper()
+	resp, err := client.GetStatus()
+	status := walletdResult(t, resp, err)
+	resp, err = client.GetViewKey()
+	viewKey, _ := walletdResult(t, resp, err)["viewSecretKey"].(string)
+	return status, viewKey
+}
+
+// checkGolden fails if any of the secrets is in the golden file
+func checkGolden(t *testing.T, path string, secrets ...string) {
+	t.Helper()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range secrets {
+		if secret != "" && strings.Contains(string(data), secret) {
+			t.Errorf("%s holds the secret %q", path, secret)
+		}
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	fake := NewWalletd("walletd-password")
+	client := fake.Client()
+	recorder := NewRecorder(nil)
+	client.Transport = recorder
+
+	status, viewKey := walletdSession(t, client)
+	address := fake.Addresses()[0]
+	resp, err := client.GetSpendKeys(address)
+	spendKey, _ := walletdResult(t, resp, err)["spendSecretKey"].(string)
+	resp, err = client.GetMnemonicSeed(address)
+	seed, _ := walletdResult(t, resp, err)["mnemonicSeed"].(string)
+	fake.Close()
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err = recorder.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, path, "walletd-password", viewKey, spendKey, seed)
+	if len(recorder.Interactions()) != 4 {
+		t.Fatalf("%d interactions recorded, want 4", len(recorder.Interactions()))
+	}
+
+	// The replayed session needs neither the wallet nor its password
+	replayer, err := LoadReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &trpc.Walletd{URL: "127.0.0.1", Port: 1, RPCPassword: "other", Transport: replayer}
+	replayedStatus, replayedViewKey := walletdSession(t, client)
+	if replayedStatus["blockCount"] != status["blockCount"] || replayedStatus["peerCount"] != status["peerCount"] {
+		t.Errorf("replayed status %v, recorded %v", replayedStatus, status)
+	}
+	if replayedViewKey != Scrubbed {
+		t.Errorf("replayed view key %q", replayedViewKey)
+	}
+	if len(replayer.Remaining()) != 2 || len(replayer.Unmatched()) != 0 {
+		t.Errorf("%d interactions remaining, %d requests unmatched", len(replayer.Remaining()), len(replayer.Unmatched()))
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	replayer, err := LoadReplayer(walletdGolden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &trpc.Walletd{URL: "127.0.0.1", Port: 1, RPCPassword: "other", Transport: replayer}
+
+	// Each interaction is served once, in the order recorded
+	walletdSession(t, client)
+	if _, err = client.GetStatus(); err == nil || !strings.Contains(err.Error(), "rpctest: no recorded interaction for POST /json_rpc") {
+		t.Errorf("status asked again: err = %v", err)
+	}
+	// Requests differing from the recording in their parameters fail
+	replayer, _ = LoadReplayer(walletdGolden)
+	client.Transport = replayer
+	if _, err = client.GetMnemonicSeed("TRTL"); err == nil {
+		t.Error("request never recorded was answered")
+	}
+	if unmatched := replayer.Unmatched(); len(unmatched) != 1 || !strings.Contains(unmatched[0], `"getMnemonicSeed"`) {
+		t.Errorf("unmatched = %q", unmatched)
+	}
+	if len(replayer.Remaining()) != 2 {
+		t.Errorf("%d interactions remaining, want 2", len(replayer.Remaining()))
+	}
+}
+
+func TestRecordAPIKey(t *testing.T) {
+	fake := NewWalletAPI("wallet-api-key")
+	defer fake.Close()
+	client := fake.Client()
+	recorder := NewRecorder(nil)
+	client.Transport = recorder
+	if _, err := client.Status(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := recorder.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, path, "wallet-api-key", "X-API-KEY")
+}
+
+func TestRecordScrubbedFields(t *testing.T) {
+	// Every scrubbed field, at the top of the body,
+	// in a nested object and in an array
+	body := map[string]interface{}{}
+	var secrets []string
+	for field := range ScrubbedFields {
+		secret := "secret-" + field
+		secrets = append(secrets, secret, secret+"-nested", secret+"-item")
+		body[field] = secret
+		body["nested"+field] = map[string]interface{}{"object": map[string]interface{}{field: secret + "-nested"}}
+		body["items"+field] = []interface{}{map[string]interface{}{field: secret + "-item", "kept": 1}}
+	}
+	data, _ := json.Marshal(body)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder(nil)
+	client := &http.Client{Transport: recorder}
+	resp, err := client.Post(server.URL+"/keys", "application/json", strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err = recorder.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, path, secrets...)
+
+	golden, _ := ioutil.ReadFile(path)
+	if count := strings.Count(string(golden), Scrubbed); count != 2*len(secrets) {
+		t.Errorf("%d scrubbed values, want %d", count, 2*len(secrets))
+	}
+	if !strings.Contains(string(golden), `"kept": 1`) {
+		t.Error("field which is not secret scrubbed")
+	}
+}
+
+// TestRecordGolden records walletdGolden again with -update
+func TestRecordGolden(t *testing.T) {
+	if !*update {
+		t.Skip("run with -update to record the golden file")
+	}
+	fake := NewWalletd("secret")
+	defer fake.Close()
+	client := fake.Client()
+	recorder := NewRecorder(nil)
+	client.Transport = recorder
+	walletdSession(t, client)
+	if err := recorder.Save(walletdGolden); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplayGolden(t *testing.T) {
+	checkGolden(t, walletdGolden, `"secret"`)
+	replayer, err := LoadReplayer(walletdGolden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &trpc.Walletd{URL: "127.0.0.1", Port: 1, RPCPassword: "secret", Transport: replayer}
+	status, viewKey := walletdSession(t, client)
+	if status["blockCount"] == nil || viewKey != Scrubbed {
+		t.Errorf("status %v, view key %q", status, viewKey)
+	}
+	if len(replayer.Remaining()) != 0 {
+		t.Errorf("%d interactions not replayed", len(replayer.Remaining()))
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+// Package rpctest provides in-process fake servers which speak the
+// same RPC protocols as the TurtleCoin services, so code built on
+// top of turtlecoinrpc can be exercised without a running node.
+package rpctest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"github.com/turtlecoin/turtlecoin-rpc-go/keys"
+)
+
+// Standard JSON-RPC error codes along with the invalid
+// password code used by the TurtleCoin services
+const (
+	ErrParseError      = -32700
+	ErrInvalidRequest  = -32600
+	ErrMethodNotFound  = -32601
+	ErrInvalidParams   = -32602
+	ErrInternalError   = -32603
+	ErrInvalidPassword = -32604
+	ErrApplication     = -32000
+)
+
+// ErrDaemonInternalError is the code of the internal errors of the
+// daemon, such as getblockheaderbyhash for an unknown block
+const ErrDaemonInternalError = -5
+
+// rpcError is the error object of a JSON-RPC response
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err *rpcError) Error() string {
+	return err.Message
+}
+
+func newError(code int, message string) *rpcError {
+	return &rpcError{Code: code, Message: message}
+}
+
+// rpcRequest is the envelope of an incoming JSON-RPC request
+type rpcRequest struct {
+	JSONRPC  string          `json:"jsonrpc"`
+	ID       interface{}     `json:"id"`
+	Password *string         `json:"password"`
+	Method   string          `json:"method"`
+	Params   json.RawMessage `json:"params"`
+}
+
+// rpcResponse is the envelope of an outgoing JSON-RPC response
+type rpcResponse struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      interface{} `json:"id"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   *rpcError   `json:"error,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeRPC(w http.ResponseWriter, id interface{}, result interface{}, err *rpcError) {
+	resp := rpcResponse{JSONRPC: "2.0", ID: id}
+	if err != nil {
+		resp.Error = err
+	} else {
+		resp.Result = result
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// decodeParams unmarshals the request params into v,
+// treating missing params as an empty object
+func decodeParams(params json.RawMessage, v interface{}) *rpcError {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return newError(ErrInvalidParams, "Invalid params: "+err.Error())
+	}
+	return nil
+}
+
+// hostPort splits the address of a test server
+// into the URL and Port expected by the clients
+func hostPort(server *httptest.Server) (string, int) {
+	addr := strings.TrimPrefix(server.URL, "http://")
+	i := strings.LastIndex(addr, ":")
+	port, _ := strconv.Atoi(addr[i+1:])
+	return addr[:i], port
+}
+
+// randomHex returns n random bytes from crypto/rand as hex. Like
+// httptest, the fakes panic when the system cannot serve them, here
+// when it has no randomness.
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic("rpctest: failed to read random bytes: " + err.Error())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// randomAddress returns the address of a new wallet,
+// panicking like randomHex if no key can be generated
+func randomAddress() string {
+	wallet, err := keys.NewWallet()
+	if err != nil {
+		panic("rpctest: failed to generate a wallet: " + err.Error())
+	}
+	return wallet.Address
+}
+
+func isHex(s string, size int) bool {
+	if len(s) != size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
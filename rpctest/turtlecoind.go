@@ -0,0 +1,533 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package rpctest
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+	"github.com/turtlecoin/turtlecoin-rpc-go/cryptonote"
+	"github.com/turtlecoin/turtlecoin-rpc-go/pow"
+)
+
+// ErrBlockNotAccepted is the error code of a rejected submitblock
+const ErrBlockNotAccepted = -7
+
+// DaemonBlock is a block of the chain of the fake daemon
+type DaemonBlock struct {
+	Height       int    `json:"height"`
+	Hash         string `json:"hash"`
+	PrevHash     string `json:"prev_hash"`
+	Timestamp    int64  `json:"timestamp"`
+	MajorVersion uint64 `json:"major_version"`
+	MinorVersion uint64 `json:"minor_version"`
+	Nonce        uint32 `json:"nonce"`
+	Difficulty   uint64 `json:"difficulty"`
+	Reward       uint64 `json:"reward"`
+	NumTxes      int    `json:"num_txes"`
+	Depth        int    `json:"depth"`
+	OrphanStatus bool   `json:"orphan_status"`
+
+	coinbase *cryptonote.Transaction
+}
+
+// TurtleCoind is an in-process fake of the TurtleCoind JSON-RPC
+// server answering the mining, block header and f_block methods,
+// along with the getwalletsyncdata endpoint. Block templates are
+// real block blobs of MajorVersion, and submitted blocks are checked
+// against Difficulty with the pow package.
+//
+// Blocks found by other miners are added by MineBlock.
+type TurtleCoind struct {
+	*httptest.Server
+
+	MajorVersion uint64
+	Difficulty   uint64
+	Reward       uint64
+
+	mu        sync.Mutex
+	blocks    []*DaemonBlock
+	orphans   map[string]*DaemonBlock
+	submitted []string
+}
+
+// NewTurtleCoind starts a fake daemon whose chain holds the genesis
+// block. Templates use Chukwa v2 and a difficulty low enough to be
+// mined in a few hundred hashes. Close must be called when done.
+func NewTurtleCoind() *TurtleCoind {
+	daemon := &TurtleCoind{
+		MajorVersion: pow.ChukwaV2MajorVersion,
+		Difficulty:   100,
+		Reward:       2900000,
+		orphans:      make(map[string]*DaemonBlock),
+	}
+	daemon.blocks = []*DaemonBlock{{
+		Hash:     randomHex(32),
+		PrevHash: hex.EncodeToString(make([]byte, 32)),
+		NumTxes:  1,
+		coinbase: daemon.coinbase(0, 0),
+	}}
+	daemon.Server = httptest.NewServer(http.HandlerFunc(daemon.serveHTTP))
+	return daemon
+}
+
+// Client returns a turtlecoinrpc.TurtleCoind configured
+// to talk to the fake daemon
+func (daemon *TurtleCoind) Client() *trpc.TurtleCoind {
+	url, port := hostPort(daemon.Server)
+	return &trpc.TurtleCoind{URL: url, Port: port}
+}
+
+// MineBlock adds a block found elsewhere on top of the chain
+// and returns its hash
+func (daemon *TurtleCoind) MineBlock() string {
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+
+	return daemon.addBlock(randomHex(32), 0, nil).Hash
+}
+
+// Orphan replaces the blocks from height with new blocks found
+// elsewhere, as a reorganization does. The replaced blocks are
+// still reported by getblockheaderbyhash with orphan_status set.
+func (daemon *TurtleCoind) Orphan(height int) {
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+
+	if height < 1 || height >= len(daemon.blocks) {
+		return
+	}
+	replaced := daemon.blocks[height:]
+	daemon.blocks = daemon.blocks[:height]
+	for _, block := range replaced {
+		block.OrphanStatus = true
+		daemon.orphans[block.Hash] = block
+	}
+	for range replaced {
+		daemon.addBlock(randomHex(32), 0, nil)
+	}
+}
+
+// Submitted returns the blobs of the blocks accepted by submitblock
+func (daemon *TurtleCoind) Submitted() []string {
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+
+	return append([]string(nil), daemon.submitted...)
+}
+
+// Height returns the number of blocks of the chain
+func (daemon *TurtleCoind) Height() int {
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+
+	return len(daemon.blocks)
+}
+
+// Blocks returns the blocks of the chain, by height
+func (daemon *TurtleCoind) Blocks() []DaemonBlock {
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+
+	blocks := make([]DaemonBlock, len(daemon.blocks))
+	for i, block := range daemon.blocks {
+		blocks[i] = *block
+	}
+	return blocks
+}
+
+// addBlock adds a block on top of the chain. A coinbase
+// transaction is made up for blocks found elsewhere.
+func (daemon *TurtleCoind) addBlock(hash string, nonce uint32, coinbase *cryptonote.Transaction) *DaemonBlock {
+	if coinbase == nil {
+		coinbase = daemon.coinbase(len(daemon.blocks), 0)
+	}
+	top := daemon.blocks[len(daemon.blocks)-1]
+	block := &DaemonBlock{
+		Height:       len(daemon.blocks),
+		Hash:         hash,
+		PrevHash:     top.Hash,
+		Timestamp:    time.Now().Unix(),
+		MajorVersion: daemon.MajorVersion,
+		Nonce:        nonce,
+		Difficulty:   daemon.Difficulty,
+		Reward:       daemon.Reward,
+		NumTxes:      1,
+		coinbase:     coinbase,
+	}
+	daemon.blocks = append(daemon.blocks, block)
+	return block
+}
+
+// coinbase returns a coinbase transaction of the block at height
+// paying the reward to a random key. Its extra holds a random
+// transaction public key and a nonce of reserveSize bytes.
+func (daemon *TurtleCoind) coinbase(height int, reserveSize int) *cryptonote.Transaction {
+	var txKey, outputKey cryptonote.Key
+	hex.Decode(txKey[:], []byte(randomHex(32)))
+	hex.Decode(outputKey[:], []byte(randomHex(32)))
+
+	// reserveSize is checked by getblocktemplate, so the extra encodes
+	nonce := cryptonote.ExtraNonce{Data: make([]byte, reserveSize)}
+	extra, _ := cryptonote.Extra{cryptonote.ExtraPublicKey{Key: txKey}, nonce}.Bytes()
+	return &cryptonote.Transaction{TransactionPrefix: cryptonote.TransactionPrefix{
+		Version:    1,
+		UnlockTime: uint64(height + 40),
+		Inputs:     []cryptonote.Input{cryptonote.BaseInput{BlockIndex: uint64(height)}},
+		Outputs:    []cryptonote.Output{{Amount: daemon.Reward, Key: outputKey}},
+		Extra:      extra,
+	}}
+}
+
+func (daemon *TurtleCoind) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost && r.URL.Path == "/getwalletsyncdata" {
+		daemon.serveWalletSyncData(w, r)
+		return
+	}
+	if r.Method != http.MethodPost || r.URL.Path != "/json_rpc" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeRPC(w, nil, nil, newError(ErrParseError, "Parse error"))
+		return
+	}
+
+	handler, ok := daemonMethods[req.Method]
+	if !ok {
+		writeRPC(w, req.ID, nil, newError(ErrMethodNotFound, "Method not found"))
+		return
+	}
+
+	daemon.mu.Lock()
+	result, err := handler(daemon, req.Params)
+	daemon.mu.Unlock()
+
+	writeRPC(w, req.ID, result, err)
+}
+
+type daemonHandler func(daemon *TurtleCoind, params json.RawMessage) (interface{}, *rpcError)
+
+var daemonMethods map[string]daemonHandler
+
+func init() {
+	daemonMethods = map[string]daemonHandler{
+		"getblocktemplate":       (*TurtleCoind).getBlockTemplate,
+		"submitblock":            (*TurtleCoind).submitBlock,
+		"getlastblockheader":     (*TurtleCoind).getLastBlockHeader,
+		"getblockheaderbyhash":   (*TurtleCoind).getBlockHeaderByHash,
+		"getblockheaderbyheight": (*TurtleCoind).getBlockHeaderByHeight,
+		"on_getblockhash":        (*TurtleCoind).getBlockHash,
+		"f_blocks_list_json":     (*TurtleCoind).getBlocksList,
+		"f_block_json":           (*TurtleCoind).getBlockJSON,
+	}
+}
+
+func (daemon *TurtleCoind) getBlockTemplate(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		ReserveSize   int    `json:"reserve_size"`
+		WalletAddress string `json:"wallet_address"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.WalletAddress == "" {
+		return nil, newError(ErrInvalidParams, "Failed to parse wallet address")
+	}
+	if p.ReserveSize < 0 || p.ReserveSize > cryptonote.MaxExtraNonceSize {
+		return nil, newError(ErrInvalidParams, "Too big reserved size, maximum 255")
+	}
+
+	top := daemon.blocks[len(daemon.blocks)-1]
+	prev, _ := cryptonote.ParseKey(top.Hash)
+	block := &cryptonote.Block{
+		BlockHeader: cryptonote.BlockHeader{
+			MajorVersion:      daemon.MajorVersion,
+			Timestamp:         uint64(time.Now().Unix()),
+			PreviousBlockHash: prev,
+		},
+		BaseTransaction: *daemon.coinbase(len(daemon.blocks), p.ReserveSize),
+	}
+	if block.MajorVersion >= cryptonote.MergeMiningMajorVersion {
+		tag, _ := cryptonote.Extra{cryptonote.ExtraMergeMiningTag{}}.Bytes()
+		block.ParentBlock = cryptonote.ParentBlock{
+			MajorVersion:     1,
+			TransactionCount: 1,
+			BaseTransaction: cryptonote.Transaction{TransactionPrefix: cryptonote.TransactionPrefix{
+				Version: 1,
+				Extra:   tag,
+			}},
+		}
+		block.UpdateMergeMiningTag()
+	}
+
+	// The coinbase transaction has no signatures, so its
+	// extra ends where the transaction ends
+	blob := block.Bytes()
+	coinbaseEnd := len(blob) - len(cryptonote.AppendVarint(nil, 0))
+	extraStart := coinbaseEnd - len(block.BaseTransaction.Extra)
+	// The reserved area follows the tagged public key,
+	// the nonce tag and the single byte size of the nonce
+	offset := extraStart + 1 + len(cryptonote.ExtraPublicKey{}.Key) + 2
+
+	return map[string]interface{}{
+		"blocktemplate_blob": hex.EncodeToString(blob),
+		"difficulty":         daemon.Difficulty,
+		"height":             len(daemon.blocks),
+		"reserved_offset":    offset,
+		"status":             "OK",
+	}, nil
+}
+
+func (daemon *TurtleCoind) submitBlock(params json.RawMessage) (interface{}, *rpcError) {
+	var p []string
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p) != 1 {
+		return nil, newError(ErrInvalidParams, "Wrong param")
+	}
+
+	block, err := cryptonote.ParseBlockHex(p[0])
+	if err != nil {
+		return nil, newError(ErrBlockNotAccepted, "Wrong block blob")
+	}
+	top := daemon.blocks[len(daemon.blocks)-1]
+	if block.PreviousBlockHash.String() != top.Hash {
+		return nil, newError(ErrBlockNotAccepted, "Block not accepted")
+	}
+	if _, err := pow.VerifyBlock(block, daemon.Difficulty); err != nil {
+		return nil, newError(ErrBlockNotAccepted, "Block not accepted")
+	}
+
+	daemon.addBlock(block.Hash().String(), block.Nonce, &block.BaseTransaction)
+	daemon.submitted = append(daemon.submitted, p[0])
+	return map[string]interface{}{"status": "OK"}, nil
+}
+
+// header returns the block header response of the block
+func (daemon *TurtleCoind) header(block *DaemonBlock) interface{} {
+	return map[string]interface{}{
+		"block_header": daemon.withDepth(block),
+		"status":       "OK",
+	}
+}
+
+// withDepth returns a copy of the block with its depth set
+// when it is in the main chain
+func (daemon *TurtleCoind) withDepth(block *DaemonBlock) DaemonBlock {
+	header := *block
+	if !header.OrphanStatus {
+		header.Depth = len(daemon.blocks) - 1 - header.Height
+	}
+	return header
+}
+
+func (daemon *TurtleCoind) getLastBlockHeader(params json.RawMessage) (interface{}, *rpcError) {
+	return daemon.header(daemon.blocks[len(daemon.blocks)-1]), nil
+}
+
+func (daemon *TurtleCoind) getBlockHeaderByHash(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Hash string `json:"hash"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	for _, block := range daemon.blocks {
+		if block.Hash == p.Hash {
+			return daemon.header(block), nil
+		}
+	}
+	if block, ok := daemon.orphans[p.Hash]; ok {
+		return daemon.header(block), nil
+	}
+	return nil, newError(ErrDaemonInternalError, "Internal error: can't get block by hash. Hash = "+p.Hash+".")
+}
+
+func (daemon *TurtleCoind) getBlockHeaderByHeight(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Height int `json:"height"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Height < 0 || p.Height >= len(daemon.blocks) {
+		return nil, newError(ErrInvalidParams, "Height too big")
+	}
+	return daemon.header(daemon.blocks[p.Height]), nil
+}
+
+// getBlockHash answers on_getblockhash, which counts
+// heights from 1 for the genesis block
+func (daemon *TurtleCoind) getBlockHash(params json.RawMessage) (interface{}, *rpcError) {
+	var p []int
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p) != 1 {
+		return nil, newError(ErrInvalidParams, "Wrong parameters, expected height")
+	}
+	if p[0] < 1 || p[0] > len(daemon.blocks) {
+		return nil, newError(ErrInvalidParams, "Height too big")
+	}
+	return daemon.blocks[p[0]-1].Hash, nil
+}
+
+// getBlocksList answers f_blocks_list_json with the
+// blocks from height down to 30 blocks below it
+func (daemon *TurtleCoind) getBlocksList(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Height int `json:"height"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Height < 0 || p.Height >= len(daemon.blocks) {
+		return nil, newError(ErrInvalidParams, "Height too big")
+	}
+
+	var blocks []map[string]interface{}
+	for height := p.Height; height >= 0 && height > p.Height-30; height-- {
+		block := daemon.blocks[height]
+		blocks = append(blocks, map[string]interface{}{
+			"height":     block.Height,
+			"hash":       block.Hash,
+			"timestamp":  block.Timestamp,
+			"difficulty": block.Difficulty,
+			"tx_count":   block.NumTxes,
+		})
+	}
+	return map[string]interface{}{"blocks": blocks, "status": "OK"}, nil
+}
+
+// getBlockJSON answers f_block_json with the header of the
+// block and its coinbase transaction, orphans included
+func (daemon *TurtleCoind) getBlockJSON(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Hash string `json:"hash"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	block, ok := daemon.orphans[p.Hash]
+	for _, b := range daemon.blocks {
+		if b.Hash == p.Hash {
+			block, ok = b, true
+		}
+	}
+	if !ok {
+		return nil, newError(ErrDaemonInternalError, "Internal error: can't get block by hash. Hash = "+p.Hash+".")
+	}
+
+	coinbase := map[string]interface{}{
+		"hash":       block.coinbase.Hash().String(),
+		"fee":        0,
+		"amount_out": block.Reward,
+	}
+	return map[string]interface{}{
+		"block": struct {
+			DaemonBlock
+			Transactions []map[string]interface{} `json:"transactions"`
+		}{daemon.withDepth(block), []map[string]interface{}{coinbase}},
+		"status": "OK",
+	}, nil
+}
+
+// serveWalletSyncData answers getwalletsyncdata. The blocks start at the
+// newest checkpoint of the main chain, which is sent again so the caller
+// can tell it still holds, or at startHeight and startTimestamp when no
+// checkpoint is known.
+func (daemon *TurtleCoind) serveWalletSyncData(w http.ResponseWriter, r *http.Request) {
+	var p struct {
+		Checkpoints    []string `json:"blockHashCheckpoints"`
+		StartHeight    int      `json:"startHeight"`
+		StartTimestamp int64    `json:"startTimestamp"`
+		BlockCount     int      `json:"blockCount"`
+		SkipCoinbase   bool     `json:"skipCoinbaseTransactions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"status": "Failed to parse request"})
+		return
+	}
+	if p.BlockCount <= 0 || p.BlockCount > 100 {
+		p.BlockCount = 100
+	}
+
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+
+	start, known := daemon.checkpointHeight(p.Checkpoints)
+	if !known {
+		start = p.StartHeight
+		for start < len(daemon.blocks) && daemon.blocks[start].Timestamp < p.StartTimestamp {
+			start++
+		}
+	}
+	end := start + p.BlockCount
+	if end > len(daemon.blocks) {
+		end = len(daemon.blocks)
+	}
+
+	items := []trpc.WalletBlockInfo{}
+	for height := start; height < end; height++ {
+		block := daemon.blocks[height]
+		item := trpc.WalletBlockInfo{
+			BlockHash:      block.Hash,
+			BlockHeight:    block.Height,
+			BlockTimestamp: block.Timestamp,
+			Transactions:   []trpc.RawTransaction{},
+		}
+		if !p.SkipCoinbase {
+			item.CoinbaseTX = walletCoinbase(block.coinbase)
+		}
+		items = append(items, item)
+	}
+
+	top := daemon.blocks[len(daemon.blocks)-1]
+	writeJSON(w, http.StatusOK, trpc.WalletSyncData{
+		Items:    items,
+		Synced:   end >= len(daemon.blocks),
+		TopBlock: &trpc.TopBlock{Hash: top.Hash, Height: top.Height},
+		Status:   "OK",
+	})
+}
+
+// checkpointHeight returns the height of the first of the
+// checkpoints found in the main chain
+func (daemon *TurtleCoind) checkpointHeight(checkpoints []string) (int, bool) {
+	for _, hash := range checkpoints {
+		for _, block := range daemon.blocks {
+			if block.Hash == hash {
+				return block.Height, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// walletCoinbase returns the wallet sync data of a coinbase transaction
+func walletCoinbase(tx *cryptonote.Transaction) *trpc.RawCoinbaseTransaction {
+	coinbase := &trpc.RawCoinbaseTransaction{
+		Hash:       tx.Hash().String(),
+		UnlockTime: tx.UnlockTime,
+		Outputs:    []trpc.KeyOutput{},
+	}
+	if extra, err := cryptonote.ParseExtra(tx.Extra); err == nil {
+		if key, ok := extra.PublicKey(); ok {
+			coinbase.TxPublicKey = key.String()
+		}
+	}
+	for _, output := range tx.Outputs {
+		coinbase.Outputs = append(coinbase.Outputs, trpc.KeyOutput{Key: output.Key.String(), Amount: output.Amount})
+	}
+	return coinbase
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package rpctest
+
+import "testing"
+
+func TestTurtleCoindCheckpoints(t *testing.T) {
+	fake := NewTurtleCoind()
+	defer fake.Close()
+	for i := 0; i < 40; i++ {
+		fake.MineBlock()
+	}
+	client := fake.Client()
+	blocks := fake.Blocks()
+
+	// Checkpoints run from the given height down to the genesis block
+	checkpoints, err := client.BlockHashCheckpoints(len(blocks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoints[0] != blocks[len(blocks)-1].Hash || checkpoints[len(checkpoints)-1] != blocks[0].Hash {
+		t.Errorf("checkpoints run from %s to %s", checkpoints[0], checkpoints[len(checkpoints)-1])
+	}
+
+	// The daemon starts at the newest checkpoint it knows
+	fake.Orphan(35)
+	data, err := client.GetWalletSyncData(checkpoints, 0, 0, 3, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Items) != 3 || data.Items[0].BlockHash != blocks[34].Hash {
+		t.Errorf("sync data = %+v, want 3 blocks from %s", data.Items, blocks[34].Hash)
+	}
+	if data.Synced {
+		t.Error("sync data of 3 blocks out of 7 is synced")
+	}
+
+	// Without a known checkpoint it starts at the height,
+	// and is synced once it reaches the top block
+	data, err = client.GetWalletSyncData([]string{"00"}, 38, 0, 100, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Items) != 3 || data.Items[0].BlockHeight != 38 || !data.Synced {
+		t.Fatalf("sync data from 38 holds %d blocks, synced %v", len(data.Items), data.Synced)
+	}
+	if data.TopBlock == nil || data.TopBlock.Height != 40 || data.TopBlock.Hash != fake.Blocks()[40].Hash {
+		t.Errorf("top block = %+v", data.TopBlock)
+	}
+	if coinbase := data.Items[0].CoinbaseTX; coinbase == nil || len(coinbase.TxPublicKey) != 64 || len(coinbase.Outputs) != 1 {
+		t.Errorf("coinbase transaction = %+v", coinbase)
+	}
+}
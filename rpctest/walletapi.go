@@ -0,0 +1,391 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package rpctest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+)
+
+// WalletAPITransfer is a transfer of a wallet-api transaction,
+// which only holds the addresses of the wallet
+type WalletAPITransfer struct {
+	Address string `json:"address"`
+	Amount  int64  `json:"amount"`
+}
+
+// WalletAPITransaction is a transaction as reported by wallet-api.
+// BlockHeight is 0 for transactions which are not yet in a block.
+type WalletAPITransaction struct {
+	Hash        string              `json:"hash"`
+	Fee         uint64              `json:"fee"`
+	PaymentID   string              `json:"paymentID"`
+	BlockHeight int                 `json:"blockHeight"`
+	Timestamp   int64               `json:"timestamp"`
+	UnlockTime  uint64              `json:"unlockTime"`
+	IsCoinbase  bool                `json:"isCoinbaseTransaction"`
+	Transfers   []WalletAPITransfer `json:"transfers"`
+
+	// Destinations are the addresses paid by an outgoing transaction,
+	// which wallet-api does not report
+	Destinations []WalletAPITransfer `json:"-"`
+}
+
+// walletAPIError is the body of a wallet-api error response
+type walletAPIError struct {
+	ErrorCode    int    `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// WalletAPI is an in-process fake of the wallet-api REST server with
+// an open wallet holding a single address. Every transaction pays
+// NodeFee to the node on top of its fee, as wallet-api does.
+//
+// Funds enter the wallet through Credit and unconfirmed transactions
+// are confirmed by MineBlock. Prepared transactions are kept until
+// they are sent or cancelled, or until Restart.
+type WalletAPI struct {
+	*httptest.Server
+
+	RPCPassword string
+	NodeFee     uint64
+
+	mu           sync.Mutex
+	address      string
+	balance      uint64
+	height       int
+	transactions []*WalletAPITransaction
+	prepared     map[string]*WalletAPITransaction
+	relayed      map[string]int
+}
+
+// NewWalletAPI starts a fake wallet-api which accepts requests
+// carrying the given API key. Close must be called when done.
+func NewWalletAPI(rpcPassword string) *WalletAPI {
+	wallet := &WalletAPI{
+		RPCPassword: rpcPassword,
+		NodeFee:     5,
+		address:     randomAddress(),
+		height:      1,
+		prepared:    make(map[string]*WalletAPITransaction),
+		relayed:     make(map[string]int),
+	}
+	wallet.Server = httptest.NewServer(http.HandlerFunc(wallet.serveHTTP))
+	return wallet
+}
+
+// Client returns a turtlecoinrpc.WalletAPI configured
+// to talk to the fake wallet-api
+func (wallet *WalletAPI) Client() *trpc.WalletAPI {
+	url, port := hostPort(wallet.Server)
+	return &trpc.WalletAPI{
+		URL:         url,
+		Port:        port,
+		RPCPassword: wallet.RPCPassword,
+	}
+}
+
+// Address returns the address of the wallet
+func (wallet *WalletAPI) Address() string {
+	return wallet.address
+}
+
+// Balance returns the unlocked balance of the wallet
+func (wallet *WalletAPI) Balance() uint64 {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	return wallet.balance
+}
+
+// Credit adds a confirmed incoming transaction of
+// the given amount in a new block and returns its hash
+func (wallet *WalletAPI) Credit(amount uint64) string {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	wallet.height++
+	wallet.balance += amount
+	tx := &WalletAPITransaction{
+		Hash:        randomHex(32),
+		BlockHeight: wallet.height,
+		Timestamp:   time.Now().Unix(),
+		Transfers:   []WalletAPITransfer{{Address: wallet.address, Amount: int64(amount)}},
+	}
+	wallet.transactions = append(wallet.transactions, tx)
+	return tx.Hash
+}
+
+// MineBlock adds a block which confirms every
+// unconfirmed transaction of the wallet
+func (wallet *WalletAPI) MineBlock() {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	wallet.height++
+	for _, tx := range wallet.transactions {
+		if tx.BlockHeight == 0 {
+			tx.BlockHeight = wallet.height
+			tx.Timestamp = time.Now().Unix()
+		}
+	}
+}
+
+// Restart drops the prepared transactions,
+// as a restart of wallet-api does
+func (wallet *WalletAPI) Restart() {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	wallet.prepared = make(map[string]*WalletAPITransaction)
+}
+
+// Outgoing returns the transactions sent by the wallet in order
+func (wallet *WalletAPI) Outgoing() []WalletAPITransaction {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	var outgoing []WalletAPITransaction
+	for _, tx := range wallet.transactions {
+		if len(tx.Destinations) > 0 {
+			outgoing = append(outgoing, *tx)
+		}
+	}
+	return outgoing
+}
+
+// Relayed returns how many times the transaction was relayed
+func (wallet *WalletAPI) Relayed(hash string) int {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	return wallet.relayed[hash]
+}
+
+func (wallet *WalletAPI) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-API-KEY") != wallet.RPCPassword {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var params map[string]json.RawMessage
+	if r.Method == http.MethodPost && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil && err != io.EOF {
+			writeJSON(w, http.StatusBadRequest, walletAPIError{1, "Invalid JSON: " + err.Error()})
+			return
+		}
+	}
+
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	route := r.Method + " " + path[0]
+	if len(path) > 1 {
+		route += "/" + path[1]
+	}
+
+	switch {
+	case route == "GET status":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"walletBlockCount":      wallet.height,
+			"localDaemonBlockCount": wallet.height,
+			"networkBlockCount":     wallet.height,
+			"peerCount":             8,
+			"hashrate":              0,
+			"isViewWallet":          false,
+			"subWalletCount":        1,
+		})
+	case route == "GET node":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"daemonHost":  "127.0.0.1",
+			"daemonPort":  11898,
+			"daemonSSL":   false,
+			"nodeFee":     wallet.NodeFee,
+			"nodeAddress": "",
+		})
+	case route == "GET addresses" && len(path) == 1:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"addresses": []string{wallet.address}})
+	case route == "GET balance":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"unlocked": wallet.balance, "locked": 0})
+	case route == "POST transactions/prepare" || route == "POST transactions/send":
+		wallet.send(w, path, params)
+	case route == "DELETE transactions/prepared" && len(path) == 3:
+		delete(wallet.prepared, path[2])
+		w.WriteHeader(http.StatusOK)
+	case route == "GET transactions/hash" && len(path) == 3:
+		for _, tx := range wallet.transactions {
+			if tx.Hash == path[2] {
+				writeJSON(w, http.StatusOK, map[string]interface{}{"transaction": tx})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	case route == "GET transactions/unconfirmed":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"transactions": wallet.inRange(0, 0)})
+	case route == "GET transactions" || (strings.HasPrefix(route, "GET transactions/") && isNumber(path[1])):
+		start, end := 1, wallet.height+1
+		if len(path) > 1 {
+			start, _ = strconv.Atoi(path[1])
+			end = start + 1000
+		}
+		if len(path) > 2 {
+			end, _ = strconv.Atoi(path[2])
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"transactions": wallet.inRange(start, end)})
+	default:
+		writeJSON(w, http.StatusBadRequest, walletAPIError{1, "Unsupported request " + r.Method + " " + r.URL.Path})
+	}
+}
+
+// send answers the prepare and send requests. A prepared
+// transaction is only relayed by transactions/send/prepared.
+func (wallet *WalletAPI) send(w http.ResponseWriter, path []string, params map[string]json.RawMessage) {
+	kind := strings.Join(path[2:], "/")
+	if path[1] == "send" && kind == "prepared" {
+		var hash string
+		json.Unmarshal(params["transactionHash"], &hash)
+		tx, ok := wallet.prepared[hash]
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, walletAPIError{2, "The prepared transaction was not found"})
+			return
+		}
+		if err := wallet.relay(tx); err != nil {
+			writeJSON(w, http.StatusBadRequest, *err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"transactionHash": tx.Hash})
+		return
+	}
+	if strings.HasPrefix(kind, "fusion") {
+		writeJSON(w, http.StatusBadRequest, walletAPIError{3, "Wallet is already fully optimized"})
+		return
+	}
+
+	var destinations []WalletAPITransfer
+	var fee uint64 = MinimumFee
+	var paymentID string
+	switch kind {
+	case "basic":
+		var destination WalletAPITransfer
+		json.Unmarshal(params["destination"], &destination.Address)
+		json.Unmarshal(params["amount"], &destination.Amount)
+		destinations = append(destinations, destination)
+	case "advanced":
+		json.Unmarshal(params["destinations"], &destinations)
+		if _, ok := params["fee"]; ok {
+			json.Unmarshal(params["fee"], &fee)
+		}
+	default:
+		writeJSON(w, http.StatusBadRequest, walletAPIError{1, "Unsupported request " + strings.Join(path, "/")})
+		return
+	}
+	json.Unmarshal(params["paymentID"], &paymentID)
+
+	var total uint64
+	for i, destination := range destinations {
+		if !strings.HasPrefix(destination.Address, "TRTL") || destination.Amount <= 0 {
+			writeJSON(w, http.StatusBadRequest, walletAPIError{4, "The destination is invalid"})
+			return
+		}
+		if len(destination.Address) == trpc.IntegratedAddressLength {
+			address, err := trpc.ParseIntegratedAddress(destination.Address)
+			if err != nil || (paymentID != "" && paymentID != address.PaymentID) {
+				writeJSON(w, http.StatusBadRequest, walletAPIError{4, "The destination is invalid"})
+				return
+			}
+			paymentID = address.PaymentID
+			address.PaymentID = ""
+			destinations[i].Address = address.String()
+		}
+		total += uint64(destination.Amount)
+	}
+	if len(destinations) == 0 {
+		writeJSON(w, http.StatusBadRequest, walletAPIError{4, "The destination is invalid"})
+		return
+	}
+	if fee < MinimumFee {
+		writeJSON(w, http.StatusBadRequest, walletAPIError{5, "The fee is too small"})
+		return
+	}
+
+	total += fee + wallet.NodeFee
+	tx := &WalletAPITransaction{
+		Hash:         randomHex(32),
+		Fee:          fee,
+		PaymentID:    paymentID,
+		Transfers:    []WalletAPITransfer{{Address: wallet.address, Amount: -int64(total)}},
+		Destinations: destinations,
+	}
+	if total > wallet.balance {
+		writeJSON(w, http.StatusBadRequest, walletAPIError{6, "Not enough balance to cover the transaction"})
+		return
+	}
+
+	if path[1] == "prepare" {
+		wallet.prepared[tx.Hash] = tx
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"transactionHash":  tx.Hash,
+			"fee":              fee,
+			"relayedToNetwork": false,
+		})
+		return
+	}
+	if err := wallet.relay(tx); err != nil {
+		writeJSON(w, http.StatusBadRequest, *err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"transactionHash": tx.Hash})
+}
+
+// relay sends the transaction, which is then in the
+// wallet history until it is mined in the next block
+func (wallet *WalletAPI) relay(tx *WalletAPITransaction) *walletAPIError {
+	delete(wallet.prepared, tx.Hash)
+	for _, known := range wallet.transactions {
+		if known.Hash == tx.Hash {
+			wallet.relayed[tx.Hash]++
+			return nil
+		}
+	}
+
+	total := uint64(-tx.Transfers[0].Amount)
+	if total > wallet.balance {
+		return &walletAPIError{6, "Not enough balance to cover the transaction"}
+	}
+	wallet.balance -= total
+	wallet.relayed[tx.Hash]++
+	wallet.transactions = append(wallet.transactions, tx)
+	return nil
+}
+
+// inRange returns the transactions with a height in [start, end),
+// or the unconfirmed transactions when both are 0
+func (wallet *WalletAPI) inRange(start int, end int) []*WalletAPITransaction {
+	found := []*WalletAPITransaction{}
+	for _, tx := range wallet.transactions {
+		if (start == 0 && end == 0 && tx.BlockHeight == 0) ||
+			(tx.BlockHeight > 0 && tx.BlockHeight >= start && tx.BlockHeight < end) {
+			found = append(found, tx)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].BlockHeight < found[j].BlockHeight
+	})
+	return found
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
@@ -0,0 +1,1068 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package rpctest
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+	"github.com/turtlecoin/turtlecoin-rpc-go/keys"
+	"github.com/turtlecoin/turtlecoin-rpc-go/mnemonic"
+)
+
+// Transaction states reported by turtle-service
+const (
+	TransactionSucceeded = 0
+	TransactionFailed    = 1
+	TransactionCancelled = 2
+	TransactionCreated   = 3
+	TransactionDeleted   = 4
+)
+
+// UnconfirmedBlockIndex is the blockIndex reported
+// for transactions which are not yet in a block
+const UnconfirmedBlockIndex uint32 = 4294967295
+
+// MinimumFee is the lowest fee accepted by the fake service
+const MinimumFee = 10
+
+// FusionMinInputCount is the number of optimizable outputs
+// required before a fusion transaction can be sent
+const FusionMinInputCount = 12
+
+// WalletdTransfer is a single transfer of a transaction
+type WalletdTransfer struct {
+	Type    int    `json:"type"`
+	Address string `json:"address"`
+	Amount  int    `json:"amount"`
+}
+
+// WalletdTransaction is a transaction as reported by turtle-service
+type WalletdTransaction struct {
+	TransactionHash string            `json:"transactionHash"`
+	BlockIndex      uint32            `json:"blockIndex"`
+	Timestamp       int64             `json:"timestamp"`
+	IsBase          bool              `json:"isBase"`
+	UnlockTime      int               `json:"unlockTime"`
+	Amount          int               `json:"amount"`
+	Fee             int               `json:"fee"`
+	Extra           string            `json:"extra"`
+	PaymentID       string            `json:"paymentId"`
+	State           int               `json:"state"`
+	Transfers       []WalletdTransfer `json:"transfers"`
+}
+
+type walletdOutput struct {
+	amount     int
+	blockIndex uint32
+}
+
+type walletdAddress struct {
+	address        string
+	spendSecretKey string
+	spendPublicKey string
+	outputs        []walletdOutput
+}
+
+type walletdSpend struct {
+	tx    *WalletdTransaction
+	spent map[string][]walletdOutput
+}
+
+// Walletd is an in-process fake of the turtle-service (walletd)
+// JSON-RPC server. The wallet container is kept in memory and
+// every method wrapped by turtlecoinrpc.Walletd is answered.
+//
+// Funds enter the container through Credit and unconfirmed
+// transactions are confirmed by MineBlock.
+type Walletd struct {
+	*httptest.Server
+
+	RPCPassword string
+	FeeAddress  string
+	FeeAmount   int
+	PeerCount   int
+
+	mu            sync.Mutex
+	viewSecretKey string
+	viewPublicKey string
+	addresses     map[string]*walletdAddress
+	order         []string
+	blocks        []string
+	transactions  []*WalletdTransaction
+	delayed       map[string]*walletdSpend
+}
+
+// NewWalletd starts a fake turtle-service which accepts requests
+// carrying the given password. The container starts with a single
+// address and the genesis block. Close must be called when done.
+func NewWalletd(rpcPassword string) *Walletd {
+	// The first address is deterministic, its spend key
+	// gives the view key of the container and the seed
+	first, err := keys.NewWallet()
+	if err != nil {
+		panic("rpctest: failed to generate a wallet: " + err.Error())
+	}
+
+	wallet := &Walletd{
+		RPCPassword:   rpcPassword,
+		PeerCount:     8,
+		viewSecretKey: first.View.PrivateKey,
+		viewPublicKey: first.View.PublicKey,
+		addresses:     make(map[string]*walletdAddress),
+		blocks:        []string{randomHex(32)},
+		delayed:       make(map[string]*walletdSpend),
+	}
+	wallet.addAddress(first.Spend.PrivateKey, first.Spend.PublicKey)
+	wallet.Server = httptest.NewServer(http.HandlerFunc(wallet.serveHTTP))
+	return wallet
+}
+
+// Client returns a turtlecoinrpc.Walletd configured
+// to talk to the fake service
+func (wallet *Walletd) Client() *trpc.Walletd {
+	url, port := hostPort(wallet.Server)
+	return &trpc.Walletd{
+		URL:         url,
+		Port:        port,
+		RPCPassword: wallet.RPCPassword,
+	}
+}
+
+// Addresses returns the addresses of the container in creation order
+func (wallet *Walletd) Addresses() []string {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	return append([]string(nil), wallet.order...)
+}
+
+// Credit adds a confirmed incoming transaction of the given amount
+// to the address in a new block and returns its hash
+func (wallet *Walletd) Credit(address string, amount int, paymentID string) string {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	addr, ok := wallet.addresses[address]
+	if !ok {
+		return ""
+	}
+
+	wallet.blocks = append(wallet.blocks, randomHex(32))
+	height := uint32(len(wallet.blocks) - 1)
+	addr.outputs = append(addr.outputs, walletdOutput{amount, height})
+
+	tx := &WalletdTransaction{
+		TransactionHash: randomHex(32),
+		BlockIndex:      height,
+		Timestamp:       time.Now().Unix(),
+		Amount:          amount,
+		PaymentID:       paymentID,
+		State:           TransactionSucceeded,
+		Transfers:       []WalletdTransfer{{Address: address, Amount: amount}},
+	}
+	wallet.transactions = append(wallet.transactions, tx)
+	return tx.TransactionHash
+}
+
+// MineBlock adds a block to the chain which confirms every
+// unconfirmed transaction and output of the container
+func (wallet *Walletd) MineBlock() string {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+
+	hash := randomHex(32)
+	wallet.blocks = append(wallet.blocks, hash)
+	height := uint32(len(wallet.blocks) - 1)
+
+	for _, tx := range wallet.transactions {
+		if tx.State == TransactionSucceeded && tx.BlockIndex == UnconfirmedBlockIndex {
+			tx.BlockIndex = height
+			tx.Timestamp = time.Now().Unix()
+		}
+	}
+	for _, addr := range wallet.addresses {
+		for i := range addr.outputs {
+			if addr.outputs[i].blockIndex == UnconfirmedBlockIndex {
+				addr.outputs[i].blockIndex = height
+			}
+		}
+	}
+	return hash
+}
+
+// addAddress adds the address of the spend keys to the container,
+// failing if the public spend key is not a valid key
+func (wallet *Walletd) addAddress(spendSecretKey string, spendPublicKey string) (*walletdAddress, error) {
+	address, err := keys.Address(spendPublicKey, wallet.viewPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := &walletdAddress{
+		address:        address,
+		spendSecretKey: spendSecretKey,
+		spendPublicKey: spendPublicKey,
+	}
+	wallet.addresses[addr.address] = addr
+	wallet.order = append(wallet.order, addr.address)
+	return addr, nil
+}
+
+func (wallet *Walletd) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/json_rpc" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeRPC(w, nil, nil, newError(ErrParseError, "Parse error"))
+		return
+	}
+	if req.Password == nil || *req.Password != wallet.RPCPassword {
+		writeRPC(w, req.ID, nil, newError(ErrInvalidPassword, "Invalid or no rpc password"))
+		return
+	}
+
+	handler, ok := walletdMethods[req.Method]
+	if !ok {
+		writeRPC(w, req.ID, nil, newError(ErrMethodNotFound, "Method not found"))
+		return
+	}
+
+	wallet.mu.Lock()
+	result, err := handler(wallet, req.Params)
+	wallet.mu.Unlock()
+
+	writeRPC(w, req.ID, result, err)
+}
+
+type walletdHandler func(wallet *Walletd, params json.RawMessage) (interface{}, *rpcError)
+
+var walletdMethods map[string]walletdHandler
+
+func init() {
+	walletdMethods = map[string]walletdHandler{
+		"save":                            (*Walletd).save,
+		"reset":                           (*Walletd).reset,
+		"createAddress":                   (*Walletd).createAddress,
+		"deleteAddress":                   (*Walletd).deleteAddress,
+		"getSpendKeys":                    (*Walletd).getSpendKeys,
+		"getBalance":                      (*Walletd).getBalance,
+		"getBlockHashes":                  (*Walletd).getBlockHashes,
+		"getTransactionHashes":            (*Walletd).getTransactionHashes,
+		"getTransactions":                 (*Walletd).getTransactions,
+		"getUnconfirmedTransactionHashes": (*Walletd).getUnconfirmedTransactionHashes,
+		"getTransaction":                  (*Walletd).getTransaction,
+		"sendTransaction":                 (*Walletd).sendTransaction,
+		"createDelayedTransaction":        (*Walletd).createDelayedTransaction,
+		"getDelayedTransactionHashes":     (*Walletd).getDelayedTransactionHashes,
+		"deleteDelayedTransaction":        (*Walletd).deleteDelayedTransaction,
+		"sendDelayedTransaction":          (*Walletd).sendDelayedTransaction,
+		"getViewKey":                      (*Walletd).getViewKey,
+		"getMnemonicSeed":                 (*Walletd).getMnemonicSeed,
+		"getStatus":                       (*Walletd).getStatus,
+		"getAddresses":                    (*Walletd).getAddresses,
+		"sendFusionTransaction":           (*Walletd).sendFusionTransaction,
+		"estimateFusion":                  (*Walletd).estimateFusion,
+		"createIntegratedAddress":         (*Walletd).createIntegratedAddress,
+		"getFeeInfo":                      (*Walletd).getFeeInfo,
+	}
+}
+
+var emptyResult = struct{}{}
+
+func (wallet *Walletd) lookup(address string) (*walletdAddress, *rpcError) {
+	if address == "" {
+		return nil, newError(ErrApplication, "Address is required")
+	}
+	addr, ok := wallet.addresses[address]
+	if !ok {
+		return nil, newError(ErrApplication, "Object not found")
+	}
+	return addr, nil
+}
+
+func (wallet *Walletd) save(params json.RawMessage) (interface{}, *rpcError) {
+	return emptyResult, nil
+}
+
+func (wallet *Walletd) reset(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		ViewSecretKey string `json:"viewSecretKey"`
+		ScanHeight    uint32 `json:"scanHeight"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if p.ViewSecretKey != "" {
+		publicKey, err := keys.PublicKey(p.ViewSecretKey)
+		if err != nil {
+			return nil, newError(ErrApplication, "Wrong key format")
+		}
+		wallet.viewSecretKey = p.ViewSecretKey
+		wallet.viewPublicKey = publicKey
+		wallet.addresses = make(map[string]*walletdAddress)
+		wallet.order = nil
+		wallet.transactions = nil
+		wallet.delayed = make(map[string]*walletdSpend)
+		return emptyResult, nil
+	}
+
+	// A rescan only finds the transactions at or above the scan height
+	kept := wallet.transactions[:0]
+	for _, tx := range wallet.transactions {
+		if tx.BlockIndex >= p.ScanHeight {
+			kept = append(kept, tx)
+		}
+	}
+	wallet.transactions = kept
+	return emptyResult, nil
+}
+
+func (wallet *Walletd) createAddress(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		SpendSecretKey string `json:"spendSecretKey"`
+		SpendPublicKey string `json:"spendPublicKey"`
+		ScanHeight     int    `json:"scanHeight"`
+		NewAddress     bool   `json:"newAddress"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	// Addresses made from a public spend key are view only,
+	// their secret spend key is reported as zeros
+	var secretKey, publicKey string
+	switch {
+	case p.SpendSecretKey != "" && p.SpendPublicKey != "":
+		return nil, newError(ErrInvalidParams, "Cannot specify both spendSecretKey and spendPublicKey")
+	case p.SpendSecretKey != "":
+		key, err := keys.PublicKey(p.SpendSecretKey)
+		if err != nil {
+			return nil, newError(ErrApplication, "Wrong key format")
+		}
+		secretKey, publicKey = p.SpendSecretKey, key
+	case p.SpendPublicKey != "":
+		secretKey, publicKey = strings.Repeat("0", 64), p.SpendPublicKey
+	default:
+		pair, err := keys.GenerateKeyPair()
+		if err != nil {
+			return nil, newError(ErrInternalError, err.Error())
+		}
+		secretKey, publicKey = pair.PrivateKey, pair.PublicKey
+	}
+
+	for _, addr := range wallet.addresses {
+		if addr.spendPublicKey == publicKey {
+			return nil, newError(ErrApplication, "Address already exists")
+		}
+	}
+
+	addr, err := wallet.addAddress(secretKey, publicKey)
+	if err != nil {
+		return nil, newError(ErrApplication, "Wrong key format")
+	}
+	return map[string]interface{}{"address": addr.address}, nil
+}
+
+func (wallet *Walletd) deleteAddress(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Address string `json:"address"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if _, err := wallet.lookup(p.Address); err != nil {
+		return nil, err
+	}
+
+	delete(wallet.addresses, p.Address)
+	for i, address := range wallet.order {
+		if address == p.Address {
+			wallet.order = append(wallet.order[:i], wallet.order[i+1:]...)
+			break
+		}
+	}
+	return emptyResult, nil
+}
+
+func (wallet *Walletd) getSpendKeys(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Address string `json:"address"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	addr, err := wallet.lookup(p.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"spendSecretKey": addr.spendSecretKey,
+		"spendPublicKey": addr.spendPublicKey,
+	}, nil
+}
+
+func (wallet *Walletd) getBalance(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Address string `json:"address"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	addresses := wallet.order
+	if p.Address != "" {
+		if _, err := wallet.lookup(p.Address); err != nil {
+			return nil, err
+		}
+		addresses = []string{p.Address}
+	}
+
+	available, locked := 0, 0
+	for _, address := range addresses {
+		for _, output := range wallet.addresses[address].outputs {
+			if output.blockIndex == UnconfirmedBlockIndex {
+				locked += output.amount
+			} else {
+				available += output.amount
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"availableBalance": available,
+		"lockedAmount":     locked,
+	}, nil
+}
+
+func (wallet *Walletd) getBlockHashes(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		FirstBlockIndex int `json:"firstBlockIndex"`
+		BlockCount      int `json:"blockCount"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.FirstBlockIndex < 0 || p.FirstBlockIndex >= len(wallet.blocks) || p.BlockCount < 0 {
+		return nil, newError(ErrApplication, "Block index out of range")
+	}
+
+	end := p.FirstBlockIndex + p.BlockCount
+	if end > len(wallet.blocks) {
+		end = len(wallet.blocks)
+	}
+	return map[string]interface{}{
+		"blockHashes": append([]string{}, wallet.blocks[p.FirstBlockIndex:end]...),
+	}, nil
+}
+
+type walletdRangeParams struct {
+	Addresses       []string `json:"addresses"`
+	BlockHash       string   `json:"blockHash"`
+	FirstBlockIndex int      `json:"firstBlockIndex"`
+	BlockCount      int      `json:"blockCount"`
+	PaymentID       string   `json:"paymentId"`
+}
+
+// transactionsInRange groups the transactions matching
+// the range params by the block they are included in
+func (wallet *Walletd) transactionsInRange(params json.RawMessage) ([]string, map[string][]*WalletdTransaction, *rpcError) {
+	var p walletdRangeParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, nil, err
+	}
+
+	first := p.FirstBlockIndex
+	if p.BlockHash != "" {
+		first = -1
+		for i, hash := range wallet.blocks {
+			if hash == p.BlockHash {
+				first = i
+				break
+			}
+		}
+		if first < 0 {
+			return nil, nil, newError(ErrApplication, "Object not found")
+		}
+	}
+	if p.BlockCount <= 0 {
+		return nil, nil, newError(ErrInvalidParams, "blockCount must be greater than 0")
+	}
+	if p.PaymentID != "" && !isHex(p.PaymentID, 32) {
+		return nil, nil, newError(ErrApplication, "Wrong payment id format")
+	}
+	for _, address := range p.Addresses {
+		if _, err := wallet.lookup(address); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	end := first + p.BlockCount
+	if end > len(wallet.blocks) {
+		end = len(wallet.blocks)
+	}
+
+	var hashes []string
+	grouped := make(map[string][]*WalletdTransaction)
+	for i := first; i < end && i >= 0; i++ {
+		hashes = append(hashes, wallet.blocks[i])
+	}
+	for _, tx := range wallet.transactions {
+		if tx.BlockIndex == UnconfirmedBlockIndex || int(tx.BlockIndex) < first || int(tx.BlockIndex) >= end {
+			continue
+		}
+		if p.PaymentID != "" && tx.PaymentID != p.PaymentID {
+			continue
+		}
+		if len(p.Addresses) != 0 && !tx.involves(p.Addresses) {
+			continue
+		}
+		hash := wallet.blocks[tx.BlockIndex]
+		grouped[hash] = append(grouped[hash], tx)
+	}
+	return hashes, grouped, nil
+}
+
+func (tx *WalletdTransaction) involves(addresses []string) bool {
+	for _, transfer := range tx.Transfers {
+		for _, address := range addresses {
+			if transfer.Address == address {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (wallet *Walletd) getTransactionHashes(params json.RawMessage) (interface{}, *rpcError) {
+	blocks, grouped, err := wallet.transactionsInRange(params)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []map[string]interface{}{}
+	for _, block := range blocks {
+		hashes := []string{}
+		for _, tx := range grouped[block] {
+			hashes = append(hashes, tx.TransactionHash)
+		}
+		items = append(items, map[string]interface{}{
+			"blockHash":         block,
+			"transactionHashes": hashes,
+		})
+	}
+	return map[string]interface{}{"items": items}, nil
+}
+
+func (wallet *Walletd) getTransactions(params json.RawMessage) (interface{}, *rpcError) {
+	blocks, grouped, err := wallet.transactionsInRange(params)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []map[string]interface{}{}
+	for _, block := range blocks {
+		transactions := []*WalletdTransaction{}
+		transactions = append(transactions, grouped[block]...)
+		items = append(items, map[string]interface{}{
+			"blockHash":    block,
+			"transactions": transactions,
+		})
+	}
+	return map[string]interface{}{"items": items}, nil
+}
+
+func (wallet *Walletd) getUnconfirmedTransactionHashes(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Addresses []string `json:"addresses"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	for _, address := range p.Addresses {
+		if _, err := wallet.lookup(address); err != nil {
+			return nil, err
+		}
+	}
+
+	hashes := []string{}
+	for _, tx := range wallet.transactions {
+		if tx.State != TransactionSucceeded || tx.BlockIndex != UnconfirmedBlockIndex {
+			continue
+		}
+		if len(p.Addresses) != 0 && !tx.involves(p.Addresses) {
+			continue
+		}
+		hashes = append(hashes, tx.TransactionHash)
+	}
+	return map[string]interface{}{"transactionHashes": hashes}, nil
+}
+
+func (wallet *Walletd) findTransaction(hash string) *WalletdTransaction {
+	for _, tx := range wallet.transactions {
+		if tx.TransactionHash == hash {
+			return tx
+		}
+	}
+	return nil
+}
+
+func (wallet *Walletd) getTransaction(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		TransactionHash string `json:"transactionHash"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if !isHex(p.TransactionHash, 32) {
+		return nil, newError(ErrApplication, "Wrong hash format")
+	}
+
+	tx := wallet.findTransaction(p.TransactionHash)
+	if tx == nil {
+		return nil, newError(ErrApplication, "Object not found")
+	}
+	return map[string]interface{}{"transaction": tx}, nil
+}
+
+type walletdSendParams struct {
+	Addresses     []string          `json:"addresses"`
+	Transfers     []WalletdTransfer `json:"transfers"`
+	Fee           int               `json:"fee"`
+	UnlockTime    int               `json:"unlockTime"`
+	Extra         string            `json:"extra"`
+	PaymentID     string            `json:"paymentId"`
+	ChangeAddress string            `json:"changeAddress"`
+}
+
+// buildTransaction validates the send params, spends the required
+// outputs from the source addresses and creates the resulting
+// transaction without adding it to the container
+func (wallet *Walletd) buildTransaction(params json.RawMessage, state int) (*walletdSpend, *rpcError) {
+	var p walletdSendParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if len(p.Transfers) == 0 {
+		return nil, newError(ErrInvalidParams, "At least one transfer is required")
+	}
+	if p.Fee < MinimumFee {
+		return nil, newError(ErrApplication, "Fee is too small")
+	}
+	if p.Extra != "" && p.PaymentID != "" {
+		return nil, newError(ErrInvalidParams, "Can't set paymentId and extra together")
+	}
+	if p.PaymentID != "" && !isHex(p.PaymentID, 32) {
+		return nil, newError(ErrApplication, "Wrong payment id format")
+	}
+	if _, err := hex.DecodeString(p.Extra); err != nil {
+		return nil, newError(ErrApplication, "Wrong extra format")
+	}
+
+	total := p.Fee
+	for _, transfer := range p.Transfers {
+		if !strings.HasPrefix(transfer.Address, "TRTL") {
+			return nil, newError(ErrApplication, "Bad address")
+		}
+		if transfer.Amount <= 0 {
+			return nil, newError(ErrApplication, "Wrong amount")
+		}
+		total += transfer.Amount
+	}
+
+	sources := p.Addresses
+	if len(sources) == 0 {
+		sources = wallet.order
+	}
+	for _, address := range sources {
+		if _, err := wallet.lookup(address); err != nil {
+			return nil, err
+		}
+	}
+
+	changeAddress := p.ChangeAddress
+	if changeAddress == "" {
+		if len(sources) != 1 && len(wallet.order) != 1 {
+			return nil, newError(ErrApplication, "Change address is required")
+		}
+		changeAddress = sources[0]
+	}
+	if _, err := wallet.lookup(changeAddress); err != nil {
+		return nil, err
+	}
+
+	// Pick the largest confirmed outputs first until the total is covered
+	type candidate struct {
+		address string
+		index   int
+		amount  int
+	}
+	var candidates []candidate
+	for _, address := range sources {
+		for i, output := range wallet.addresses[address].outputs {
+			if output.blockIndex != UnconfirmedBlockIndex {
+				candidates = append(candidates, candidate{address, i, output.amount})
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].amount > candidates[j].amount
+	})
+
+	selected := make(map[string]map[int]bool)
+	collected := 0
+	for _, c := range candidates {
+		if collected >= total {
+			break
+		}
+		if selected[c.address] == nil {
+			selected[c.address] = make(map[int]bool)
+		}
+		selected[c.address][c.index] = true
+		collected += c.amount
+	}
+	if collected < total {
+		return nil, newError(ErrApplication, "Wrong amount")
+	}
+
+	delayed := &walletdSpend{
+		spent: make(map[string][]walletdOutput),
+	}
+	tx := &WalletdTransaction{
+		TransactionHash: randomHex(32),
+		BlockIndex:      UnconfirmedBlockIndex,
+		Timestamp:       0,
+		UnlockTime:      p.UnlockTime,
+		Fee:             p.Fee,
+		Extra:           p.Extra,
+		PaymentID:       p.PaymentID,
+		State:           state,
+	}
+	for address, indexes := range selected {
+		spent := 0
+		for i := range indexes {
+			output := wallet.addresses[address].outputs[i]
+			delayed.spent[address] = append(delayed.spent[address], output)
+			spent += output.amount
+		}
+		tx.Transfers = append(tx.Transfers, WalletdTransfer{Type: 0, Address: address, Amount: -spent})
+	}
+	for _, transfer := range p.Transfers {
+		tx.Transfers = append(tx.Transfers, WalletdTransfer{Type: 0, Address: transfer.Address, Amount: transfer.Amount})
+	}
+	if change := collected - total; change > 0 {
+		tx.Transfers = append(tx.Transfers, WalletdTransfer{Type: 2, Address: changeAddress, Amount: change})
+	}
+	for _, transfer := range tx.Transfers {
+		if _, ok := wallet.addresses[transfer.Address]; ok {
+			tx.Amount += transfer.Amount
+		}
+	}
+
+	delayed.tx = tx
+	return delayed, nil
+}
+
+// spend removes the outputs spent by the transaction from the container
+func (wallet *Walletd) spend(delayed *walletdSpend) {
+	for address, spent := range delayed.spent {
+		addr := wallet.addresses[address]
+		for _, output := range spent {
+			for i, o := range addr.outputs {
+				if o == output {
+					addr.outputs = append(addr.outputs[:i], addr.outputs[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+}
+
+// credit adds the unconfirmed outputs created by the
+// transaction to the addresses of the container
+func (wallet *Walletd) credit(delayed *walletdSpend) {
+	for _, transfer := range delayed.tx.Transfers {
+		if transfer.Amount <= 0 {
+			continue
+		}
+		if addr, ok := wallet.addresses[transfer.Address]; ok {
+			addr.outputs = append(addr.outputs, walletdOutput{transfer.Amount, UnconfirmedBlockIndex})
+		}
+	}
+}
+
+func (wallet *Walletd) sendTransaction(params json.RawMessage) (interface{}, *rpcError) {
+	delayed, err := wallet.buildTransaction(params, TransactionSucceeded)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet.spend(delayed)
+	wallet.credit(delayed)
+	wallet.transactions = append(wallet.transactions, delayed.tx)
+	return map[string]interface{}{"transactionHash": delayed.tx.TransactionHash}, nil
+}
+
+func (wallet *Walletd) createDelayedTransaction(params json.RawMessage) (interface{}, *rpcError) {
+	delayed, err := wallet.buildTransaction(params, TransactionCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	// The outputs are reserved until the transaction is sent or deleted
+	wallet.spend(delayed)
+	wallet.transactions = append(wallet.transactions, delayed.tx)
+	wallet.delayed[delayed.tx.TransactionHash] = delayed
+	return map[string]interface{}{"transactionHash": delayed.tx.TransactionHash}, nil
+}
+
+func (wallet *Walletd) getDelayedTransactionHashes(params json.RawMessage) (interface{}, *rpcError) {
+	hashes := []string{}
+	for _, tx := range wallet.transactions {
+		if _, ok := wallet.delayed[tx.TransactionHash]; ok {
+			hashes = append(hashes, tx.TransactionHash)
+		}
+	}
+	return map[string]interface{}{"transactionHashes": hashes}, nil
+}
+
+func (wallet *Walletd) delayedTransaction(params json.RawMessage) (*walletdSpend, *rpcError) {
+	var p struct {
+		TransactionHash string `json:"transactionHash"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if !isHex(p.TransactionHash, 32) {
+		return nil, newError(ErrApplication, "Wrong hash format")
+	}
+
+	delayed, ok := wallet.delayed[p.TransactionHash]
+	if !ok {
+		return nil, newError(ErrApplication, "Object not found")
+	}
+	return delayed, nil
+}
+
+func (wallet *Walletd) deleteDelayedTransaction(params json.RawMessage) (interface{}, *rpcError) {
+	delayed, err := wallet.delayedTransaction(params)
+	if err != nil {
+		return nil, err
+	}
+
+	for address, spent := range delayed.spent {
+		if addr, ok := wallet.addresses[address]; ok {
+			addr.outputs = append(addr.outputs, spent...)
+		}
+	}
+	delayed.tx.State = TransactionDeleted
+	delete(wallet.delayed, delayed.tx.TransactionHash)
+	return emptyResult, nil
+}
+
+func (wallet *Walletd) sendDelayedTransaction(params json.RawMessage) (interface{}, *rpcError) {
+	delayed, err := wallet.delayedTransaction(params)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet.credit(delayed)
+	delayed.tx.State = TransactionSucceeded
+	delete(wallet.delayed, delayed.tx.TransactionHash)
+	return emptyResult, nil
+}
+
+func (wallet *Walletd) getViewKey(params json.RawMessage) (interface{}, *rpcError) {
+	return map[string]interface{}{"viewSecretKey": wallet.viewSecretKey}, nil
+}
+
+func (wallet *Walletd) getMnemonicSeed(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Address string `json:"address"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	addr, err := wallet.lookup(p.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the keys whose view key is derived from the
+	// spend key, like the first address, have a seed
+	view, viewErr := keys.ViewKeyFromSpendKey(addr.spendSecretKey)
+	if viewErr != nil || view.PrivateKey != wallet.viewSecretKey {
+		return nil, newError(ErrApplication, "Keys are not deterministic")
+	}
+	key, _ := hex.DecodeString(addr.spendSecretKey)
+	seed, seedErr := mnemonic.English.Encode(key)
+	if seedErr != nil {
+		return nil, newError(ErrInternalError, seedErr.Error())
+	}
+	return map[string]interface{}{"mnemonicSeed": seed}, nil
+}
+
+func (wallet *Walletd) getStatus(params json.RawMessage) (interface{}, *rpcError) {
+	height := len(wallet.blocks)
+	return map[string]interface{}{
+		"blockCount":            height,
+		"knownBlockCount":       height,
+		"localDaemonBlockCount": height,
+		"lastBlockHash":         wallet.blocks[height-1],
+		"peerCount":             wallet.PeerCount,
+	}, nil
+}
+
+func (wallet *Walletd) getAddresses(params json.RawMessage) (interface{}, *rpcError) {
+	return map[string]interface{}{
+		"addresses": append([]string{}, wallet.order...),
+	}, nil
+}
+
+type walletdFusionParams struct {
+	Threshold          int      `json:"threshold"`
+	Addresses          []string `json:"addresses"`
+	DestinationAddress string   `json:"destinationAddress"`
+}
+
+// fusionOutputs returns the confirmed outputs of the given
+// addresses which are below the threshold, and the total
+// number of outputs held by those addresses
+func (wallet *Walletd) fusionOutputs(p walletdFusionParams) (map[string][]walletdOutput, int, int, *rpcError) {
+	if p.Threshold <= 0 {
+		return nil, 0, 0, newError(ErrApplication, "Threshold is too low")
+	}
+
+	addresses := p.Addresses
+	if len(addresses) == 0 {
+		addresses = wallet.order
+	}
+
+	ready := make(map[string][]walletdOutput)
+	readyCount, total := 0, 0
+	for _, address := range addresses {
+		addr, err := wallet.lookup(address)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		for _, output := range addr.outputs {
+			total++
+			if output.blockIndex != UnconfirmedBlockIndex && output.amount < p.Threshold {
+				ready[address] = append(ready[address], output)
+				readyCount++
+			}
+		}
+	}
+	return ready, readyCount, total, nil
+}
+
+func (wallet *Walletd) estimateFusion(params json.RawMessage) (interface{}, *rpcError) {
+	var p walletdFusionParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	_, ready, total, err := wallet.fusionOutputs(p)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"totalOutputCount": total,
+		"fusionReadyCount": ready,
+	}, nil
+}
+
+func (wallet *Walletd) sendFusionTransaction(params json.RawMessage) (interface{}, *rpcError) {
+	var p walletdFusionParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	outputs, ready, _, err := wallet.fusionOutputs(p)
+	if err != nil {
+		return nil, err
+	}
+	if ready < FusionMinInputCount {
+		return nil, newError(ErrApplication, "Fusion transaction cannot be created")
+	}
+
+	destination := p.DestinationAddress
+	if destination == "" {
+		if len(p.Addresses) == 1 {
+			destination = p.Addresses[0]
+		} else if len(wallet.order) == 1 {
+			destination = wallet.order[0]
+		} else {
+			return nil, newError(ErrApplication, "Destination address is required")
+		}
+	}
+	if _, err := wallet.lookup(destination); err != nil {
+		return nil, err
+	}
+
+	delayed := &walletdSpend{spent: outputs}
+	tx := &WalletdTransaction{
+		TransactionHash: randomHex(32),
+		BlockIndex:      UnconfirmedBlockIndex,
+		State:           TransactionSucceeded,
+	}
+	fused := 0
+	for address, spent := range outputs {
+		amount := 0
+		for _, output := range spent {
+			amount += output.amount
+		}
+		fused += amount
+		tx.Transfers = append(tx.Transfers, WalletdTransfer{Address: address, Amount: -amount})
+	}
+	tx.Transfers = append(tx.Transfers, WalletdTransfer{Address: destination, Amount: fused})
+	delayed.tx = tx
+
+	wallet.spend(delayed)
+	wallet.credit(delayed)
+	wallet.transactions = append(wallet.transactions, tx)
+	return map[string]interface{}{"transactionHash": tx.TransactionHash}, nil
+}
+
+func (wallet *Walletd) createIntegratedAddress(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Address   string `json:"address"`
+		PaymentID string `json:"paymentId"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if _, err := wallet.lookup(p.Address); err != nil {
+		return nil, err
+	}
+	if !isHex(p.PaymentID, 32) {
+		return nil, newError(ErrApplication, "Wrong payment id format")
+	}
+
+	integrated, err := trpc.NewIntegratedAddress(p.Address, p.PaymentID)
+	if err != nil {
+		return nil, newError(ErrApplication, err.Error())
+	}
+	return map[string]interface{}{"integratedAddress": integrated}, nil
+}
+
+func (wallet *Walletd) getFeeInfo(params json.RawMessage) (interface{}, *rpcError) {
+	return map[string]interface{}{
+		"address": wallet.FeeAddress,
+		"amount":  wallet.FeeAmount,
+	}, nil
+}
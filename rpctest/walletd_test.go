@@ -0,0 +1,70 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package rpctest
+
+import (
+	"testing"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+	"github.com/turtlecoin/turtlecoin-rpc-go/keys"
+)
+
+func walletdResult(t *testing.T, resp map[string]interface{}, err error) map[string]interface{} {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := trpc.RPCResult(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.(map[string]interface{})
+}
+
+func TestWalletdKeys(t *testing.T) {
+	fake := NewWalletd("secret")
+	defer fake.Close()
+	client := fake.Client()
+	address := fake.Addresses()[0]
+
+	// The seed of the first address restores its keys and address
+	resp, err := client.GetMnemonicSeed(address)
+	seed := walletdResult(t, resp, err)["mnemonicSeed"].(string)
+	wallet, err := keys.FromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wallet.Address != address {
+		t.Errorf("seed restores %s, want %s", wallet.Address, address)
+	}
+	resp, err = client.GetViewKey()
+	if viewKey := walletdResult(t, resp, err)["viewSecretKey"]; viewKey != wallet.View.PrivateKey {
+		t.Errorf("view key = %s, want %s", viewKey, wallet.View.PrivateKey)
+	}
+
+	// Other addresses share the view key and have no seed
+	resp, err = client.CreateAddress("", "", 0, false)
+	other := walletdResult(t, resp, err)["address"].(string)
+	resp, err = client.GetSpendKeys(other)
+	spendKeys := walletdResult(t, resp, err)
+	if err = keys.CheckKeys(spendKeys["spendSecretKey"].(string), wallet.View.PrivateKey, other); err != nil {
+		t.Error(err)
+	}
+	if resp, err = client.GetMnemonicSeed(other); err == nil {
+		if _, err = trpc.RPCResult(resp); err == nil {
+			t.Error("address with a random spend key has a seed")
+		}
+	}
+
+	paymentID := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	resp, err = client.CreateIntegratedAddress(address, paymentID)
+	integrated := walletdResult(t, resp, err)["integratedAddress"].(string)
+	decoded, err := trpc.ParseIntegratedAddress(integrated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.PaymentID != paymentID || decoded.PublicSpendKey != wallet.Spend.PublicKey {
+		t.Errorf("integrated address decodes to %+v", decoded)
+	}
+}
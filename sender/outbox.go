@@ -0,0 +1,202 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package sender
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/turtlecoin/turtlecoin-rpc-go/internal/storage"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrNotFound is returned by Outbox.Entry when
+// nothing is stored under the given key
+var ErrNotFound = storage.ErrNotFound
+
+var entriesBucket = []byte("entries")
+
+// EntryStatus is the outcome of the transfer of an entry
+type EntryStatus int
+
+const (
+	// EntryPending is a transfer which was not sent yet,
+	// or whose outcome is unknown if Hash is set
+	EntryPending EntryStatus = iota
+	// EntrySent is a transfer accepted by the wallet
+	EntrySent
+	// EntryFailed is a transfer the wallet refused to prepare,
+	// which is prepared again by the next Send with its key
+	EntryFailed
+)
+
+// Entry is a transfer recorded in an Outbox under its idempotency key.
+// Hash is recorded once the wallet prepared the transaction, before it
+// is sent, and Attempted is the time of its first send.
+type Entry struct {
+	Key       string      `json:"key"`
+	Transfer  Transfer    `json:"transfer"`
+	Status    EntryStatus `json:"status"`
+	Hash      string      `json:"hash,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Attempted time.Time   `json:"attempted"`
+	Created   time.Time   `json:"created"`
+}
+
+// Outbox persists the entries of a Sender. An entry must be stored
+// durably before PutEntry returns, as it is written before every send.
+// An Outbox must be safe for concurrent use.
+type Outbox interface {
+	// Entry returns the entry with the key, or ErrNotFound
+	Entry(key string) (*Entry, error)
+	// PutEntry stores the entry, replacing the one with the same key
+	PutEntry(entry *Entry) error
+	// Entries returns the entries by key
+	Entries() ([]*Entry, error)
+	// Close releases the resources held by the outbox
+	Close() error
+}
+
+// MemoryOutbox is an Outbox keeping the entries in a map,
+// it is lost when the process exits
+type MemoryOutbox struct {
+	mutex   sync.RWMutex
+	entries map[string][]byte
+}
+
+// NewMemoryOutbox returns an empty MemoryOutbox
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{entries: make(map[string][]byte)}
+}
+
+// Entry returns the entry with the key
+func (outbox *MemoryOutbox) Entry(key string) (*Entry, error) {
+	outbox.mutex.RLock()
+	defer outbox.mutex.RUnlock()
+
+	value, ok := outbox.entries[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var entry Entry
+	if err := json.Unmarshal(value, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// PutEntry stores the entry, replacing the one with the same key
+func (outbox *MemoryOutbox) PutEntry(entry *Entry) error {
+	// The entry is stored encoded so later changes
+	// by the caller do not reach the outbox
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	outbox.entries[entry.Key] = value
+	return nil
+}
+
+// Entries returns the entries by key
+func (outbox *MemoryOutbox) Entries() ([]*Entry, error) {
+	outbox.mutex.RLock()
+	defer outbox.mutex.RUnlock()
+
+	entries := make([]*Entry, 0, len(outbox.entries))
+	for _, value := range outbox.entries {
+		var entry Entry
+		if err := json.Unmarshal(value, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries, nil
+}
+
+// Close does nothing for a MemoryOutbox
+func (outbox *MemoryOutbox) Close() error {
+	return nil
+}
+
+// BoltOutbox is an Outbox kept in a bbolt database
+// file, with the entries keyed by idempotency key
+type BoltOutbox struct {
+	db *bolt.DB
+}
+
+// OpenBoltOutbox opens the database file at path, creating it if needed
+func OpenBoltOutbox(path string) (*BoltOutbox, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(entriesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltOutbox{db}, nil
+}
+
+// Entry returns the entry with the key
+func (outbox *BoltOutbox) Entry(key string) (*Entry, error) {
+	var entry *Entry
+	err := outbox.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(entriesBucket).Get([]byte(key))
+		if value == nil {
+			return ErrNotFound
+		}
+		entry = new(Entry)
+		return json.Unmarshal(value, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// PutEntry stores the entry, replacing the one with the same key.
+// The entry is synced to the file before PutEntry returns.
+func (outbox *BoltOutbox) PutEntry(entry *Entry) error {
+	return outbox.db.Update(func(tx *bolt.Tx) error {
+		return storage.PutJSON(tx.Bucket(entriesBucket), []byte(entry.Key), entry)
+	})
+}
+
+// Entries returns the entries by key
+func (outbox *BoltOutbox) Entries() ([]*Entry, error) {
+	var entries []*Entry
+	err := outbox.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(entriesBucket).ForEach(func(key []byte, value []byte) error {
+			var entry Entry
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, &entry)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Close closes the database file
+func (outbox *BoltOutbox) Close() error {
+	return outbox.db.Close()
+}
@@ -0,0 +1,260 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+// Package sender sends transfers at most once per idempotency key. Each
+// transfer is prepared by the wallet and the hash of the transaction is
+// written to an Outbox before the transaction is sent, so when the
+// outcome of a send is unknown, because the request or the process
+// failed, the wallet is asked for that hash and the same prepared
+// transaction is sent again.
+package sender
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrUnresolved is returned when the wallet refuses to send again a
+// prepared transaction it does not know, until ResendAfter has passed
+// since the first send
+var ErrUnresolved = errors.New("Outcome of the last send is not known yet, retry later")
+
+// Destination is an address paid by a transfer
+type Destination struct {
+	Address string `json:"address"`
+	Amount  uint64 `json:"amount"`
+}
+
+// Transfer is a transaction to send. A Fee of 0 lets
+// the wallet choose the fee, when the wallet can.
+type Transfer struct {
+	Destinations []Destination `json:"destinations"`
+	PaymentID    string        `json:"paymentID,omitempty"`
+	Fee          uint64        `json:"fee,omitempty"`
+}
+
+// Sender sends transfers through a Wallet, recording each one in an
+// Outbox under its idempotency key. A transfer whose outcome is unknown
+// is looked up in the wallet by the hash of its prepared transaction,
+// which is sent again while the wallet does not know it.
+//
+// When the wallet refuses the prepared transaction, because it lost it,
+// and still does not know it once ResendAfter has passed since the first
+// send, the transfer is prepared again. This leaves time for a slow
+// wallet to record the transaction.
+type Sender struct {
+	ResendAfter time.Duration
+
+	wallet Wallet
+	outbox Outbox
+	mu     sync.Mutex
+}
+
+// New returns a sender sending through the wallet
+// and recording the transfers in the outbox
+func New(wallet Wallet, outbox Outbox) *Sender {
+	return &Sender{
+		ResendAfter: time.Minute,
+		wallet:      wallet,
+		outbox:      outbox,
+	}
+}
+
+// Outbox returns the outbox of the sender
+func (sender *Sender) Outbox() Outbox {
+	return sender.outbox
+}
+
+// Send sends the transfer under the idempotency key. Sending again
+// under the same key returns the recorded entry once the transfer is
+// sent, so Send can be retried after any error, or after a crash,
+// without sending the transfer twice. The key must not be reused for
+// another transfer.
+func (sender *Sender) Send(key string, transfer Transfer) (*Entry, error) {
+	if key == "" {
+		return nil, errors.New("Idempotency key is required")
+	}
+	if len(transfer.Destinations) == 0 {
+		return nil, errors.New("At least one destination is required")
+	}
+	for _, destination := range transfer.Destinations {
+		if destination.Address == "" || destination.Amount == 0 {
+			return nil, errors.New("Every destination needs an address and an amount")
+		}
+	}
+
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	entry, err := sender.outbox.Entry(key)
+	switch {
+	case err == ErrNotFound:
+		entry = &Entry{Key: key, Transfer: transfer, Created: time.Now()}
+		if err = sender.outbox.PutEntry(entry); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case !sameTransfer(entry.Transfer, transfer):
+		return entry, errors.New("Idempotency key was used for another transfer")
+	}
+	return entry, sender.send(entry)
+}
+
+// Retry sends again the transfer recorded under the idempotency key
+func (sender *Sender) Retry(key string) (*Entry, error) {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	entry, err := sender.outbox.Entry(key)
+	if err != nil {
+		return nil, err
+	}
+	return entry, sender.send(entry)
+}
+
+// Resolve looks up in the wallet the transfers whose outcome is
+// unknown, as it should be done after a crash. It returns the
+// entries which are not sent, which can be completed with Retry.
+func (sender *Sender) Resolve() ([]*Entry, error) {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	entries, err := sender.outbox.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var unsent []*Entry
+	for _, entry := range entries {
+		if entry.Status == EntryPending && entry.Hash != "" {
+			if err := sender.resolve(entry); err != nil {
+				return unsent, err
+			}
+		}
+		if entry.Status != EntrySent {
+			unsent = append(unsent, entry)
+		}
+	}
+	return unsent, nil
+}
+
+// send prepares and sends the transfer of the entry unless it was sent
+// already, or sends again its prepared transaction if the outcome of
+// the last send is unknown
+func (sender *Sender) send(entry *Entry) error {
+	if entry.Status == EntrySent {
+		return nil
+	}
+	if entry.Status == EntryPending && entry.Hash != "" {
+		if err := sender.resend(entry); err != nil || entry.Status == EntrySent {
+			return err
+		}
+	}
+
+	hash, err := sender.wallet.Prepare(entry.Transfer)
+	if err != nil {
+		// Nothing was sent, the transfer is prepared again by the next send
+		entry.Error = err.Error()
+		var rejected *RejectedError
+		if errors.As(err, &rejected) {
+			entry.Status = EntryFailed
+		}
+		if putErr := sender.outbox.PutEntry(entry); putErr != nil {
+			return putErr
+		}
+		return err
+	}
+
+	// Record the hash before sending, so a crash during the
+	// send leaves the transfer to be looked up by hash
+	entry.Status = EntryPending
+	entry.Hash = hash
+	entry.Attempted = time.Now()
+	entry.Error = ""
+	if err := sender.outbox.PutEntry(entry); err != nil {
+		return err
+	}
+
+	if err = sender.wallet.SendPrepared(hash); err != nil {
+		// The outcome is unknown, the next send resends it
+		entry.Error = err.Error()
+		if putErr := sender.outbox.PutEntry(entry); putErr != nil {
+			return putErr
+		}
+		return err
+	}
+	entry.Status = EntrySent
+	return sender.outbox.PutEntry(entry)
+}
+
+// resend sends again the prepared transaction of the entry, unless the
+// wallet knows it already. The transaction is dropped, to be prepared
+// again, when the wallet refuses it after ResendAfter.
+func (sender *Sender) resend(entry *Entry) error {
+	if err := sender.resolve(entry); err != nil || entry.Status == EntrySent {
+		return err
+	}
+
+	err := sender.wallet.SendPrepared(entry.Hash)
+	if err == nil {
+		entry.Status = EntrySent
+		entry.Error = ""
+		return sender.outbox.PutEntry(entry)
+	}
+
+	entry.Error = err.Error()
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) || time.Since(entry.Attempted) < sender.ResendAfter {
+		if putErr := sender.outbox.PutEntry(entry); putErr != nil {
+			return putErr
+		}
+		if errors.As(err, &rejected) {
+			return ErrUnresolved
+		}
+		return err
+	}
+
+	// The wallet refuses the prepared transaction and still does not
+	// know it, so it is taken as never sent and prepared again
+	sender.wallet.Cancel(entry.Hash)
+	entry.Hash = ""
+	return sender.outbox.PutEntry(entry)
+}
+
+// resolve marks the entry as sent when the wallet knows
+// the hash of its prepared transaction
+func (sender *Sender) resolve(entry *Entry) error {
+	known, err := sender.wallet.Known(entry.Hash)
+	if err != nil || !known {
+		return err
+	}
+	entry.Status = EntrySent
+	entry.Error = ""
+	return sender.outbox.PutEntry(entry)
+}
+
+// sameTransfer reports whether both transfers pay the same
+// amounts to the same addresses with the same payment ID and fee
+func sameTransfer(a Transfer, b Transfer) bool {
+	if a.PaymentID != b.PaymentID || a.Fee != b.Fee || len(a.Destinations) != len(b.Destinations) {
+		return false
+	}
+	amounts := make(map[string]uint64)
+	for _, destination := range a.Destinations {
+		amounts[destination.Address] += destination.Amount
+	}
+	for _, destination := range b.Destinations {
+		if amounts[destination.Address] < destination.Amount {
+			return false
+		}
+		amounts[destination.Address] -= destination.Amount
+	}
+	for _, amount := range amounts {
+		if amount != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package sender
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/turtlecoin/turtlecoin-rpc-go/rpctest"
+)
+
+func fault(kind rpctest.FaultKind, method string, afterSend bool) http.RoundTripper {
+	return rpctest.NewFaultInjector(nil, 1, rpctest.Fault{
+		Kind:        kind,
+		Probability: 1,
+		Methods:     []string{method},
+		AfterSend:   afterSend,
+	})
+}
+
+var testTransfer = Transfer{Destinations: []Destination{{Address: "TRTLa", Amount: 1000}}}
+
+func TestWalletAPILostResponse(t *testing.T) {
+	fake := rpctest.NewWalletAPI("secret")
+	defer fake.Close()
+	fake.Credit(100000)
+
+	client := fake.Client()
+	sender := New(&WalletAPI{Wallet: client}, NewMemoryOutbox())
+
+	client.Transport = fault(rpctest.DropConnection, "transactions/send/prepared", true)
+	entry, err := sender.Send("k1", testTransfer)
+	if err == nil {
+		t.Fatal("expected the dropped response to fail the send")
+	}
+	if entry.Status != EntryPending || entry.Hash == "" {
+		t.Fatalf("entry = %+v, want pending with the prepared hash", entry)
+	}
+
+	// The node fee makes the amount leaving the wallet differ from the
+	// transfer, the transaction is still found by its hash
+	client.Transport = nil
+	if entry, err = sender.Send("k1", testTransfer); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Status != EntrySent {
+		t.Fatalf("entry = %+v, want sent", entry)
+	}
+	if outgoing := fake.Outgoing(); len(outgoing) != 1 || outgoing[0].Hash != entry.Hash {
+		t.Errorf("outgoing = %+v, want the single transaction %s", outgoing, entry.Hash)
+	}
+	if relayed := fake.Relayed(entry.Hash); relayed != 1 {
+		t.Errorf("transaction relayed %d times, want 1", relayed)
+	}
+}
+
+func TestWalletAPIServerErrorIsUnknown(t *testing.T) {
+	fake := rpctest.NewWalletAPI("secret")
+	defer fake.Close()
+	fake.Credit(100000)
+
+	client := fake.Client()
+	wallet := &WalletAPI{Wallet: client}
+	sender := New(wallet, NewMemoryOutbox())
+
+	client.Transport = fault(rpctest.ServerError, "transactions/send/prepared", false)
+	entry, err := sender.Send("k1", testTransfer)
+	var rejected *RejectedError
+	if err == nil || errors.As(err, &rejected) {
+		t.Fatalf("err = %v, want an unknown outcome", err)
+	}
+	if entry.Status != EntryPending {
+		t.Fatalf("entry = %+v, want pending", entry)
+	}
+
+	client.Transport = nil
+	if _, err = wallet.Prepare(Transfer{Destinations: []Destination{{Address: "bad", Amount: 1}}}); !errors.As(err, &rejected) {
+		t.Errorf("err = %v, want a rejection", err)
+	}
+	if entry, err = sender.Retry("k1"); err != nil || entry.Status != EntrySent {
+		t.Fatalf("entry = %+v, err = %v, want sent", entry, err)
+	}
+	if len(fake.Outgoing()) != 1 {
+		t.Errorf("%d outgoing transactions, want 1", len(fake.Outgoing()))
+	}
+}
+
+func TestWalletdResolve(t *testing.T) {
+	fake := rpctest.NewWalletd("secret")
+	defer fake.Close()
+	fake.Credit(fake.Addresses()[0], 100000, "")
+	fake.Credit(fake.Addresses()[0], 100000, "")
+
+	client := fake.Client()
+	outbox := NewMemoryOutbox()
+	sender := New(&Walletd{Wallet: client, DefaultFee: rpctest.MinimumFee}, outbox)
+
+	client.Transport = fault(rpctest.DropConnection, "sendDelayedTransaction", true)
+	if _, err := sender.Send("k1", testTransfer); err == nil {
+		t.Fatal("expected the dropped response to fail the send")
+	}
+	client.Transport = fault(rpctest.DropConnection, "sendDelayedTransaction", false)
+	if _, err := sender.Send("k2", testTransfer); err == nil {
+		t.Fatal("expected the dropped request to fail the send")
+	}
+
+	// After a restart, the first transfer is found sent
+	// and the second one is left to Retry
+	client.Transport = nil
+	sender = New(&Walletd{Wallet: client, DefaultFee: rpctest.MinimumFee}, outbox)
+	unsent, err := sender.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unsent) != 1 || unsent[0].Key != "k2" || unsent[0].Hash == "" {
+		t.Fatalf("unsent = %+v, want k2", unsent)
+	}
+
+	entry, err := sender.Retry("k2")
+	if err != nil || entry.Status != EntrySent || entry.Hash != unsent[0].Hash {
+		t.Fatalf("entry = %+v, err = %v, want the prepared transaction sent", entry, err)
+	}
+}
+
+func TestWalletdKnown(t *testing.T) {
+	fake := rpctest.NewWalletd("secret")
+	defer fake.Close()
+	client := fake.Client()
+	wallet := &Walletd{Wallet: client}
+
+	known, err := wallet.Known(strings.Repeat("ab", 32))
+	if known || err != nil {
+		t.Errorf("unknown transaction: known = %v, err = %v", known, err)
+	}
+
+	// Other errors of the wallet are not taken as an unknown transaction
+	if known, err = wallet.Known("ab"); known || err == nil || err.Error() != "Wrong hash format" {
+		t.Errorf("bad hash: known = %v, err = %v, want Wrong hash format", known, err)
+	}
+	client.Transport = rpctest.NewFaultInjector(nil, 1, rpctest.Fault{
+		Kind:        rpctest.RPCError,
+		Probability: 1,
+		Code:        rpctest.ErrInternalError,
+		Message:     "Internal error",
+	})
+	if known, err = wallet.Known(strings.Repeat("ab", 32)); known || err == nil {
+		t.Errorf("failing wallet: known = %v, err = %v, want an error", known, err)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package sender
+
+import (
+	"errors"
+	"net/http"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+)
+
+// Wallet prepares and sends the transactions paying transfers.
+// WalletAPI and Walletd adapt the clients of this module.
+type Wallet interface {
+	// Prepare builds the transaction paying the transfer without
+	// sending it and returns its hash. The error is a *RejectedError
+	// when the wallet refused the transfer.
+	Prepare(transfer Transfer) (string, error)
+	// SendPrepared sends the prepared transaction. The error is a
+	// *RejectedError when the wallet refused to send it, other
+	// errors leave the outcome unknown.
+	SendPrepared(hash string) error
+	// Cancel drops the prepared transaction
+	Cancel(hash string) error
+	// Known reports whether the wallet sent the transaction,
+	// confirmed or not
+	Known(hash string) (bool, error)
+}
+
+// RejectedError is returned by a Wallet when the
+// wallet refused the request, so nothing was sent
+type RejectedError struct {
+	Err error
+}
+
+func (err *RejectedError) Error() string {
+	return err.Err.Error()
+}
+
+// WalletAPI is a Wallet sending with a wallet-api. Transfers with a
+// single destination and no fee are prepared with
+// PrepareBasicTransaction, so the wallet chooses the fee. The others
+// are prepared with PrepareAdvancedTransaction, with Mixin,
+// SourceAddresses and ChangeAddress if set.
+type WalletAPI struct {
+	Wallet          *trpc.WalletAPI
+	Mixin           int
+	SourceAddresses []string
+	ChangeAddress   string
+}
+
+// Prepare builds the transaction paying the transfer and returns its hash
+func (wallet *WalletAPI) Prepare(transfer Transfer) (string, error) {
+	var prepared *trpc.PreparedTransaction
+	var err error
+	if len(transfer.Destinations) == 1 && transfer.Fee == 0 &&
+		len(wallet.SourceAddresses) == 0 && wallet.ChangeAddress == "" {
+		destination := transfer.Destinations[0]
+		prepared, err = wallet.Wallet.PrepareBasicTransaction(destination.Address, int(destination.Amount), transfer.PaymentID)
+	} else {
+		destinations := make([]map[string]interface{}, len(transfer.Destinations))
+		for i, destination := range transfer.Destinations {
+			destinations[i] = map[string]interface{}{
+				"address": destination.Address,
+				"amount":  int(destination.Amount),
+			}
+		}
+		prepared, err = wallet.Wallet.PrepareAdvancedTransaction(
+			destinations, wallet.Mixin, int(transfer.Fee), wallet.SourceAddresses,
+			transfer.PaymentID, wallet.ChangeAddress, 0)
+	}
+	if err != nil {
+		return "", walletAPIError(err)
+	}
+	if prepared.TransactionHash == "" {
+		return "", errors.New("Missing transaction hash in the response")
+	}
+	return prepared.TransactionHash, nil
+}
+
+// SendPrepared sends the prepared transaction
+func (wallet *WalletAPI) SendPrepared(hash string) error {
+	_, err := wallet.Wallet.SendPreparedTransaction(hash)
+	if err != nil {
+		return walletAPIError(err)
+	}
+	return nil
+}
+
+// Cancel drops the prepared transaction
+func (wallet *WalletAPI) Cancel(hash string) error {
+	return wallet.Wallet.CancelPreparedTransaction(hash)
+}
+
+// Known reports whether the wallet sent the transaction
+func (wallet *WalletAPI) Known(hash string) (bool, error) {
+	return wallet.Wallet.HasTransaction(hash)
+}
+
+// walletAPIError tells apart the errors of a wallet-api refusing a
+// request, answered with a client error status, from the others where
+// the request may have been processed: the request or the response
+// failing, or the wallet failing while processing the request
+func walletAPIError(err error) error {
+	var statusError *trpc.StatusError
+	if errors.As(err, &statusError) && statusError.StatusCode < http.StatusInternalServerError {
+		return &RejectedError{err}
+	}
+	return err
+}
+
+// Walletd is a Wallet sending with a walletd from Addresses, or from
+// every address of the container if Addresses is empty. Transfers
+// without a fee are sent with DefaultFee. Transactions are prepared
+// as delayed transactions.
+type Walletd struct {
+	Wallet        *trpc.Walletd
+	Addresses     []string
+	ChangeAddress string
+	DefaultFee    uint64
+}
+
+// walletdSucceeded is the state of the transactions which were sent
+const walletdSucceeded = 0
+
+// walletdNotFound is the error of walletd for unknown transactions,
+// an application error with the message of OBJECT_NOT_FOUND
+var walletdNotFound = trpc.RPCError{Code: -32000, Message: "Object not found"}
+
+// Prepare builds the transaction paying the transfer and returns its hash
+func (wallet *Walletd) Prepare(transfer Transfer) (string, error) {
+	transfers := make([]map[string]interface{}, len(transfer.Destinations))
+	for i, destination := range transfer.Destinations {
+		transfers[i] = map[string]interface{}{
+			"address": destination.Address,
+			"amount":  int(destination.Amount),
+		}
+	}
+	fee := transfer.Fee
+	if fee == 0 {
+		fee = wallet.DefaultFee
+	}
+
+	resp, err := wallet.Wallet.CreateDelayedTransaction(
+		wallet.Addresses, transfers, int(fee), 0, "", transfer.PaymentID, wallet.ChangeAddress)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		TransactionHash string `json:"transactionHash"`
+	}
+	if err = walletdResult(resp, &result); err != nil {
+		return "", &RejectedError{err}
+	}
+	if result.TransactionHash == "" {
+		return "", errors.New("Missing transaction hash in the response")
+	}
+	return result.TransactionHash, nil
+}
+
+// SendPrepared sends the delayed transaction
+func (wallet *Walletd) SendPrepared(hash string) error {
+	resp, err := wallet.Wallet.SendDelayedTransaction(hash)
+	if err != nil {
+		return err
+	}
+	if err = walletdResult(resp, nil); err != nil {
+		return &RejectedError{err}
+	}
+	return nil
+}
+
+// Cancel deletes the delayed transaction
+func (wallet *Walletd) Cancel(hash string) error {
+	resp, err := wallet.Wallet.DeleteDelayedTransaction(hash)
+	if err != nil {
+		return err
+	}
+	return walletdResult(resp, nil)
+}
+
+// Known reports whether the wallet sent the transaction, delayed
+// transactions which were not sent yet are not known
+func (wallet *Walletd) Known(hash string) (bool, error) {
+	resp, err := wallet.Wallet.GetTransaction(hash)
+	if err != nil {
+		return false, err
+	}
+
+	var result struct {
+		Transaction struct {
+			State int `json:"state"`
+		} `json:"transaction"`
+	}
+	var rpcError *trpc.RPCError
+	err = walletdResult(resp, &result)
+	if errors.As(err, &rpcError) && *rpcError == walletdNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return result.Transaction.State == walletdSucceeded, nil
+}
+
+// walletdResult decodes the result of a walletd response into the
+// typed structure pointed to by v, or returns the error of the
+// response. The result is only checked when v is nil.
+func walletdResult(resp map[string]interface{}, v interface{}) error {
+	result, err := trpc.RPCResult(resp)
+	if err != nil || v == nil {
+		return err
+	}
+	return trpc.ConvertResponse(result, v)
+}
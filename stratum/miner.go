@@ -0,0 +1,431 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package stratum
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+	"github.com/turtlecoin/turtlecoin-rpc-go/cryptonote"
+	"github.com/turtlecoin/turtlecoin-rpc-go/pow"
+)
+
+// maxJobs is the number of recent jobs of a miner accepting shares
+const maxJobs = 4
+
+// maxRequestSize is the longest request line read from a miner
+const maxRequestSize = 10 * 1024
+
+// Job is the work sent to a miner. Blob is the hashing blob
+// with the nonce at bytes 39 to 42 and Target is the little
+// endian share target.
+type Job struct {
+	Blob   string `json:"blob"`
+	JobID  string `json:"job_id"`
+	Target string `json:"target"`
+	Height int    `json:"height"`
+	Algo   string `json:"algo,omitempty"`
+}
+
+// job is a job along with the block it was made from. The jobs
+// of a miner made from one template share their blob, and so the
+// nonces already submitted.
+type job struct {
+	id         string
+	template   *template
+	block      cryptonote.Block
+	difficulty uint64
+	nonces     map[uint32]bool
+}
+
+// miner is a logged in connection
+type miner struct {
+	server     *Server
+	conn       net.Conn
+	id         string
+	login      string
+	extraNonce []byte
+
+	mu            sync.Mutex
+	encoder       *json.Encoder
+	difficulty    uint64
+	jobs          []*job
+	shares        int
+	retargetStart time.Time
+}
+
+type request struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type response struct {
+	ID      json.RawMessage `json:"id"`
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result"`
+	Error   *responseError  `json:"error"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// serveConn answers the requests of a connection until it
+// is closed, times out or ctx is done
+func (server *Server) serveConn(ctx context.Context, conn net.Conn) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	encoder := json.NewEncoder(conn)
+	var current *miner
+	defer func() {
+		if current != nil {
+			server.unregister(current)
+		}
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 1024), maxRequestSize)
+	for {
+		conn.SetReadDeadline(time.Now().Add(server.ConnectionTimeout))
+		if !scanner.Scan() {
+			return
+		}
+
+		var req request
+		var result interface{}
+		err := json.Unmarshal(scanner.Bytes(), &req)
+		if err == nil {
+			if current == nil && req.Method == "login" {
+				current, result, err = server.login(req.Params, conn, encoder)
+			} else if current == nil {
+				err = errors.New("Unauthenticated")
+			} else {
+				result, err = current.handle(req.Method, req.Params)
+			}
+		}
+
+		resp := response{ID: req.ID, JSONRPC: "2.0", Result: result}
+		if err != nil {
+			resp.Result = nil
+			resp.Error = &responseError{Code: -1, Message: err.Error()}
+		}
+		if current != nil {
+			current.mu.Lock()
+			err = current.encoder.Encode(resp)
+			current.mu.Unlock()
+		} else {
+			err = encoder.Encode(resp)
+		}
+		if err != nil {
+			return
+		}
+
+		// Jobs are only pushed once the miner got its login response
+		if req.Method == "login" && current != nil {
+			server.register(current)
+		}
+	}
+}
+
+// login creates the miner of the connection along with its first job
+func (server *Server) login(params json.RawMessage, conn net.Conn, encoder *json.Encoder) (*miner, interface{}, error) {
+	var p struct {
+		Login string `json:"login"`
+		Pass  string `json:"pass"`
+		Agent string `json:"agent"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, nil, errors.New("Invalid params")
+	}
+	if _, err := trpc.ParseAddress(p.Login); err != nil {
+		if _, err = trpc.ParseIntegratedAddress(p.Login); err != nil {
+			return nil, nil, errors.New("Invalid address used for login")
+		}
+	}
+
+	server.mu.Lock()
+	server.nextMiner++
+	extraNonce := make([]byte, ReserveSize)
+	copy(extraNonce, server.instance)
+	binary.BigEndian.PutUint32(extraNonce[ReserveSize/2:], server.nextMiner)
+	miner := &miner{
+		server:        server,
+		conn:          conn,
+		id:            hex.EncodeToString(extraNonce),
+		login:         p.Login,
+		extraNonce:    extraNonce,
+		encoder:       encoder,
+		difficulty:    server.StartDifficulty,
+		retargetStart: time.Now(),
+	}
+	server.mu.Unlock()
+
+	miner.mu.Lock()
+	defer miner.mu.Unlock()
+	job, err := miner.newJob()
+	if err != nil {
+		return nil, nil, err
+	}
+	return miner, map[string]interface{}{
+		"id":     miner.id,
+		"job":    job,
+		"status": "OK",
+	}, nil
+}
+
+// register adds the miner to the miners receiving new jobs, and sends
+// it a job right away if the template changed since it logged in
+func (server *Server) register(miner *miner) {
+	server.mu.Lock()
+	server.miners[miner] = true
+	current := server.template
+	server.mu.Unlock()
+
+	miner.mu.Lock()
+	stale := miner.jobs[len(miner.jobs)-1].template != current
+	miner.mu.Unlock()
+	if stale {
+		miner.pushJob()
+	}
+}
+
+// unregister stops sending new jobs to the miner
+func (server *Server) unregister(miner *miner) {
+	server.mu.Lock()
+	delete(server.miners, miner)
+	server.mu.Unlock()
+}
+
+// handle answers a request of a logged in miner
+func (miner *miner) handle(method string, params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ID     string `json:"id"`
+		JobID  string `json:"job_id"`
+		Nonce  string `json:"nonce"`
+		Result string `json:"result"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, errors.New("Invalid params")
+	}
+	if p.ID != miner.id {
+		return nil, errors.New("Unauthenticated")
+	}
+
+	switch method {
+	case "getjob":
+		miner.mu.Lock()
+		defer miner.mu.Unlock()
+		return miner.newJob()
+	case "submit":
+		return miner.submit(p.JobID, p.Nonce, p.Result)
+	case "keepalived":
+		return map[string]interface{}{"status": "KEEPALIVED"}, nil
+	}
+	return nil, errors.New("Invalid method")
+}
+
+// newJob makes a job from the current template at the difficulty of
+// the miner. It must be called with the lock of the miner held.
+func (miner *miner) newJob() (*Job, error) {
+	server := miner.server
+	server.mu.Lock()
+	template := server.template
+	server.nextJob++
+	id := strconv.FormatUint(server.nextJob, 10)
+	server.mu.Unlock()
+
+	block := *template.block
+	if err := block.SetExtraNonce(miner.extraNonce); err != nil {
+		return nil, err
+	}
+
+	nonces := make(map[uint32]bool)
+	for _, j := range miner.jobs {
+		if j.template == template {
+			nonces = j.nonces
+		}
+	}
+	miner.jobs = append(miner.jobs, &job{
+		id:         id,
+		template:   template,
+		block:      block,
+		difficulty: miner.difficulty,
+		nonces:     nonces,
+	})
+	if len(miner.jobs) > maxJobs {
+		miner.jobs = miner.jobs[1:]
+	}
+
+	algo := "argon2/chukwav2"
+	if block.MajorVersion < pow.ChukwaV2MajorVersion {
+		algo = "argon2/chukwa"
+	}
+	return &Job{
+		Blob:   hex.EncodeToString(block.HashingBlob()),
+		JobID:  id,
+		Target: targetHex(miner.difficulty),
+		Height: template.height,
+		Algo:   algo,
+	}, nil
+}
+
+// pushJob sends a new job to the miner. A miner which cannot get
+// the job is unregistered and its connection closed, rather than
+// left hashing a stale job.
+func (miner *miner) pushJob() {
+	miner.mu.Lock()
+	job, err := miner.newJob()
+	if err == nil {
+		err = miner.encoder.Encode(notification{JSONRPC: "2.0", Method: "job", Params: job})
+	}
+	miner.mu.Unlock()
+
+	if err != nil {
+		miner.server.unregister(miner)
+		miner.conn.Close()
+	}
+}
+
+// submit checks a share and submits it to the daemon
+// when it meets the difficulty of the block
+func (miner *miner) submit(jobID string, nonceHex string, result string) (interface{}, error) {
+	server := miner.server
+	raw, err := hex.DecodeString(nonceHex)
+	if err != nil || len(raw) != 4 {
+		return nil, errors.New("Invalid nonce")
+	}
+	nonce := binary.LittleEndian.Uint32(raw)
+
+	miner.mu.Lock()
+	var job *job
+	for _, j := range miner.jobs {
+		if j.id == jobID {
+			job = j
+		}
+	}
+	if job == nil {
+		miner.mu.Unlock()
+		return nil, errors.New("Invalid job id")
+	}
+	if job.nonces[nonce] {
+		miner.mu.Unlock()
+		return nil, errors.New("Duplicate share")
+	}
+	job.nonces[nonce] = true
+	miner.mu.Unlock()
+
+	server.mu.Lock()
+	current := server.template
+	server.mu.Unlock()
+	if job.template.block.PreviousBlockHash != current.block.PreviousBlockHash {
+		return nil, errors.New("Block expired")
+	}
+
+	block := job.block
+	block.Nonce = nonce
+	hash, err := server.Hasher(&block)
+	if err != nil {
+		return nil, err
+	}
+	if result != "" && !strings.EqualFold(result, hash.String()) {
+		return nil, errors.New("Bad hash")
+	}
+	if !pow.CheckHash(hash, job.difficulty) {
+		return nil, errors.New("Low difficulty share")
+	}
+
+	share := Share{
+		Login:      miner.login,
+		Difficulty: job.difficulty,
+		Height:     job.template.height,
+		Time:       time.Now(),
+	}
+	if pow.CheckHash(hash, job.template.difficulty) {
+		if err := server.submitBlock(&block); err == nil {
+			share.BlockHash = block.Hash().String()
+		}
+		go server.refresh()
+	}
+	if server.OnShare != nil {
+		server.OnShare(share)
+	}
+
+	miner.mu.Lock()
+	retargeted := miner.retarget(share.Time)
+	miner.mu.Unlock()
+	if retargeted {
+		go miner.pushJob()
+	}
+	return map[string]interface{}{"status": "OK"}, nil
+}
+
+// retarget counts an accepted share and, every RetargetTime, sets the
+// difficulty giving a share every ShareTargetTime at the hashrate seen
+// since the last retarget. It reports whether the difficulty changed.
+func (miner *miner) retarget(now time.Time) bool {
+	server := miner.server
+	miner.shares++
+	elapsed := now.Sub(miner.retargetStart)
+	if elapsed < server.RetargetTime {
+		return false
+	}
+
+	hashrate := float64(miner.difficulty) * float64(miner.shares) / elapsed.Seconds()
+	difficulty := uint64(hashrate * server.ShareTargetTime.Seconds())
+	if difficulty < server.MinDifficulty {
+		difficulty = server.MinDifficulty
+	}
+	if difficulty > server.MaxDifficulty {
+		difficulty = server.MaxDifficulty
+	}
+	miner.shares = 0
+	miner.retargetStart = now
+
+	// Small changes are not worth a new job
+	if difficulty*10 > miner.difficulty*9 && difficulty*10 < miner.difficulty*11 {
+		return false
+	}
+	miner.difficulty = difficulty
+	return true
+}
+
+// targetHex returns the little endian share target of the difficulty,
+// as 4 bytes while it fits and as 8 bytes for high difficulties
+func targetHex(difficulty uint64) string {
+	if difficulty == 0 {
+		difficulty = 1
+	}
+	raw := make([]byte, 8)
+	binary.LittleEndian.PutUint64(raw, ^uint64(0)/difficulty)
+	if difficulty <= 0xffffffff {
+		return hex.EncodeToString(raw[4:])
+	}
+	return hex.EncodeToString(raw)
+}
@@ -0,0 +1,260 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+// Package stratum runs a stratum mining server for TurtleCoin miners.
+// Block templates are fetched with TurtleCoind.GetBlockTemplate, every
+// miner hashes its own copy of the template through a unique extra
+// nonce, and found blocks are sent with TurtleCoind.SubmitBlock.
+package stratum
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+	"github.com/turtlecoin/turtlecoin-rpc-go/cryptonote"
+	"github.com/turtlecoin/turtlecoin-rpc-go/pow"
+)
+
+// ReserveSize is the size of the extra nonce requested in block
+// templates. The first half identifies the server and the second
+// half the miner, so several servers can mine to one address.
+const ReserveSize = 8
+
+// Hasher returns the proof of work hash of a block
+type Hasher func(block *cryptonote.Block) (cryptonote.Key, error)
+
+// ChukwaHasher hashes blocks with the Chukwa variant of their version
+func ChukwaHasher(block *cryptonote.Block) (cryptonote.Key, error) {
+	variant, err := pow.VariantForVersion(block.MajorVersion)
+	if err != nil {
+		return cryptonote.Key{}, err
+	}
+	return variant.Hash(block.HashingBlob())
+}
+
+// Share is a share accepted from a miner. BlockHash is set
+// when the share was a block accepted by the daemon.
+type Share struct {
+	Login      string
+	Difficulty uint64
+	Height     int
+	Time       time.Time
+	BlockHash  string
+}
+
+// Server is a stratum server mining to WalletAddress. The difficulty
+// of each miner starts at StartDifficulty and is retargeted every
+// RetargetTime so that it submits a share every ShareTargetTime.
+//
+// The daemon is polled every PollInterval for new blocks, and the
+// template is refreshed at least every TemplateInterval to pick up
+// new transactions. OnShare, if set, is called for every accepted
+// share and must not block.
+type Server struct {
+	WalletAddress     string
+	StartDifficulty   uint64
+	MinDifficulty     uint64
+	MaxDifficulty     uint64
+	ShareTargetTime   time.Duration
+	RetargetTime      time.Duration
+	PollInterval      time.Duration
+	TemplateInterval  time.Duration
+	ConnectionTimeout time.Duration
+	Hasher            Hasher
+	OnShare           func(share Share)
+
+	daemon    *trpc.TurtleCoind
+	instance  []byte
+	mu        sync.Mutex
+	template  *template
+	miners    map[*miner]bool
+	nextMiner uint32
+	nextJob   uint64
+}
+
+// template is a block template along with the
+// difficulty required for a block
+type template struct {
+	block      *cryptonote.Block
+	height     int
+	difficulty uint64
+	fetched    time.Time
+}
+
+// NewServer returns a server mining to walletAddress
+// through the daemon, with default settings
+func NewServer(daemon *trpc.TurtleCoind, walletAddress string) *Server {
+	instance := make([]byte, ReserveSize/2)
+	rand.Read(instance)
+
+	return &Server{
+		WalletAddress:     walletAddress,
+		StartDifficulty:   5000,
+		MinDifficulty:     100,
+		MaxDifficulty:     1 << 40,
+		ShareTargetTime:   15 * time.Second,
+		RetargetTime:      90 * time.Second,
+		PollInterval:      time.Second,
+		TemplateInterval:  30 * time.Second,
+		ConnectionTimeout: 10 * time.Minute,
+		Hasher:            ChukwaHasher,
+		daemon:            daemon,
+		instance:          instance,
+		miners:            make(map[*miner]bool),
+	}
+}
+
+// ListenAndServe listens on the TCP address and calls Serve
+func (server *Server) ListenAndServe(ctx context.Context, address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return server.Serve(ctx, listener)
+}
+
+// Serve fetches a first block template and accepts miners on the
+// listener until ctx is done or the listener fails. The listener
+// and the connections of the miners are closed on return.
+func (server *Server) Serve(ctx context.Context, listener net.Listener) error {
+	defer listener.Close()
+	if err := server.refresh(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	go server.poll(ctx)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.serveConn(ctx, conn)
+		}()
+	}
+}
+
+// poll refreshes the template when the top block of the
+// daemon changes or the template gets too old. Failures
+// are retried on the next poll.
+func (server *Server) poll(ctx context.Context) {
+	ticker := time.NewTicker(server.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		server.mu.Lock()
+		current := server.template
+		server.mu.Unlock()
+
+		if time.Since(current.fetched) < server.TemplateInterval {
+			hash, err := server.topBlockHash()
+			if err != nil || hash == current.block.PreviousBlockHash.String() {
+				continue
+			}
+		}
+		server.refresh()
+	}
+}
+
+// refresh fetches a new block template and sends
+// new jobs to every miner
+func (server *Server) refresh() error {
+	var result struct {
+		Blob       string `json:"blocktemplate_blob"`
+		Difficulty uint64 `json:"difficulty"`
+		Height     int    `json:"height"`
+	}
+	resp, err := server.daemon.GetBlockTemplate(ReserveSize, server.WalletAddress)
+	if err = decodeResult(resp, err, &result); err != nil {
+		return err
+	}
+	block, err := cryptonote.ParseBlockHex(result.Blob)
+	if err != nil {
+		return err
+	}
+
+	server.mu.Lock()
+	server.template = &template{
+		block:      block,
+		height:     result.Height,
+		difficulty: result.Difficulty,
+		fetched:    time.Now(),
+	}
+	miners := make([]*miner, 0, len(server.miners))
+	for miner := range server.miners {
+		miners = append(miners, miner)
+	}
+	server.mu.Unlock()
+
+	for _, miner := range miners {
+		miner.pushJob()
+	}
+	return nil
+}
+
+// topBlockHash returns the hash of the top block of the daemon
+func (server *Server) topBlockHash() (string, error) {
+	var result struct {
+		BlockHeader struct {
+			Hash string `json:"hash"`
+		} `json:"block_header"`
+	}
+	resp, err := server.daemon.GetLastBlockHeader()
+	if err = decodeResult(resp, err, &result); err != nil {
+		return "", err
+	}
+	return result.BlockHeader.Hash, nil
+}
+
+// submitBlock sends a found block to the daemon
+func (server *Server) submitBlock(block *cryptonote.Block) error {
+	var result struct {
+		Status string `json:"status"`
+	}
+	resp, err := server.daemon.SubmitBlock(block.Hex())
+	if err = decodeResult(resp, err, &result); err != nil {
+		return err
+	}
+	if result.Status != "OK" {
+		return errors.New("Block not accepted: " + result.Status)
+	}
+	return nil
+}
+
+// decodeResult decodes the result of a JSON-RPC response of the daemon
+func decodeResult(resp map[string]interface{}, err error, v interface{}) error {
+	if err != nil {
+		return err
+	}
+	result, err := trpc.RPCResult(resp)
+	if err != nil {
+		return err
+	}
+	return trpc.ConvertResponse(result, v)
+}
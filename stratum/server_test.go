@@ -0,0 +1,298 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package stratum
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/turtlecoin/turtlecoin-rpc-go/cryptonote"
+	"github.com/turtlecoin/turtlecoin-rpc-go/keys"
+	"github.com/turtlecoin/turtlecoin-rpc-go/pow"
+	"github.com/turtlecoin/turtlecoin-rpc-go/rpctest"
+)
+
+// testMiner is the stratum client side of a miner. Job
+// notifications read while waiting for a response are kept
+// for nextJob.
+type testMiner struct {
+	t       *testing.T
+	conn    net.Conn
+	scanner *bufio.Scanner
+	nextID  int
+	id      string
+	jobs    []Job
+}
+
+// message is a response or a notification read by a testMiner
+type message struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+func dialMiner(t *testing.T, address string) *testMiner {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(time.Minute))
+	return &testMiner{t: t, conn: conn, scanner: bufio.NewScanner(conn)}
+}
+
+// read returns the next message of the server
+func (m *testMiner) read() *message {
+	m.t.Helper()
+	if !m.scanner.Scan() {
+		m.t.Fatalf("connection ended: %v", m.scanner.Err())
+	}
+	var msg message
+	if err := json.Unmarshal(m.scanner.Bytes(), &msg); err != nil {
+		m.t.Fatal(err)
+	}
+	return &msg
+}
+
+// call sends a request and returns the result decoded into v,
+// or the error message of the response
+func (m *testMiner) call(method string, params map[string]interface{}, v interface{}) string {
+	m.t.Helper()
+	m.nextID++
+	if m.id != "" {
+		params["id"] = m.id
+	}
+	req := map[string]interface{}{"id": m.nextID, "jsonrpc": "2.0", "method": method, "params": params}
+	if err := json.NewEncoder(m.conn).Encode(req); err != nil {
+		m.t.Fatal(err)
+	}
+
+	for {
+		msg := m.read()
+		if msg.Method == "job" {
+			var job Job
+			json.Unmarshal(msg.Params, &job)
+			m.jobs = append(m.jobs, job)
+			continue
+		}
+		if string(msg.ID) != strconv.Itoa(m.nextID) {
+			m.t.Fatalf("response to request %s, want %d", msg.ID, m.nextID)
+		}
+		if msg.Error != nil {
+			return msg.Error.Message
+		}
+		if v != nil {
+			if err := json.Unmarshal(msg.Result, v); err != nil {
+				m.t.Fatal(err)
+			}
+		}
+		return ""
+	}
+}
+
+// nextJob returns the next job pushed by the server
+func (m *testMiner) nextJob() Job {
+	m.t.Helper()
+	for len(m.jobs) == 0 {
+		msg := m.read()
+		if msg.Method != "job" {
+			m.t.Fatalf("unexpected message %+v", msg)
+		}
+		var job Job
+		json.Unmarshal(msg.Params, &job)
+		m.jobs = append(m.jobs, job)
+	}
+	job := m.jobs[0]
+	m.jobs = m.jobs[1:]
+	return job
+}
+
+// mine returns the first nonce of the job, with its hash, whose
+// hash meets the difficulty and, only if block is set, the block
+// difficulty as well
+func mine(t *testing.T, variant pow.Variant, job Job, difficulty uint64, blockDifficulty uint64, block bool) (string, cryptonote.Key) {
+	t.Helper()
+	blob, err := hex.DecodeString(job.Blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for nonce := uint32(0); ; nonce++ {
+		binary.LittleEndian.PutUint32(blob[39:43], nonce)
+		hash, err := variant.Hash(blob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pow.CheckHash(hash, difficulty) && pow.CheckHash(hash, blockDifficulty) == block {
+			return hex.EncodeToString(blob[39:43]), hash
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	daemon := rpctest.NewTurtleCoind()
+	defer daemon.Close()
+	wallet, err := keys.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	variant, err := pow.VariantForVersion(daemon.MajorVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shares := make(chan Share, 10)
+	server := NewServer(daemon.Client(), wallet.Address)
+	server.StartDifficulty = 10
+	server.MinDifficulty = 10
+	server.MaxDifficulty = 2 * daemon.Difficulty
+	// Any share retargets, at a share per hour the
+	// difficulty goes up to MaxDifficulty
+	server.RetargetTime = time.Nanosecond
+	server.ShareTargetTime = time.Hour
+	server.PollInterval = 10 * time.Millisecond
+	server.OnShare = func(share Share) { shares <- share }
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() { served <- server.Serve(ctx, listener) }()
+	defer func() {
+		cancel()
+		if err := <-served; err != context.Canceled {
+			t.Errorf("Serve returned %v", err)
+		}
+	}()
+
+	miner := dialMiner(t, listener.Addr().String())
+	if msg := miner.call("getjob", map[string]interface{}{}, nil); msg != "Unauthenticated" {
+		t.Errorf("getjob before login: error %q", msg)
+	}
+	if msg := miner.call("login", map[string]interface{}{"login": "TRTL", "pass": "x"}, nil); msg != "Invalid address used for login" {
+		t.Errorf("login with a bad address: error %q", msg)
+	}
+
+	var login struct {
+		ID  string `json:"id"`
+		Job Job    `json:"job"`
+	}
+	if msg := miner.call("login", map[string]interface{}{"login": wallet.Address, "pass": "x"}, &login); msg != "" {
+		t.Fatal(msg)
+	}
+	miner.id = login.ID
+	if login.Job.Height != 1 || login.Job.Target != targetHex(10) {
+		t.Errorf("login job at height %d with target %s", login.Job.Height, login.Job.Target)
+	}
+
+	var job Job
+	if msg := miner.call("getjob", map[string]interface{}{}, &job); msg != "" {
+		t.Fatal(msg)
+	}
+	if job.JobID == login.Job.JobID || job.Blob != login.Job.Blob {
+		t.Errorf("getjob returned job %s with blob %s", job.JobID, job.Blob)
+	}
+
+	// A share below the block difficulty is accepted,
+	// and retargets the miner
+	nonce, hash := mine(t, variant, job, 10, daemon.Difficulty, false)
+	submit := map[string]interface{}{"job_id": job.JobID, "nonce": nonce, "result": hash.String()}
+	var status struct {
+		Status string `json:"status"`
+	}
+	if msg := miner.call("submit", submit, &status); msg != "" || status.Status != "OK" {
+		t.Fatalf("submit: status %q, error %q", status.Status, msg)
+	}
+	if share := <-shares; share.Difficulty != 10 || share.BlockHash != "" {
+		t.Errorf("share = %+v", share)
+	}
+	if msg := miner.call("submit", submit, &status); msg != "Duplicate share" {
+		t.Errorf("duplicate share: error %q", msg)
+	}
+	// The jobs of a template share their blob, so
+	// the share is not counted again under another job
+	submit["job_id"] = login.Job.JobID
+	if msg := miner.call("submit", submit, &status); msg != "Duplicate share" {
+		t.Errorf("duplicate share of the login job: error %q", msg)
+	}
+
+	retargeted := miner.nextJob()
+	if retargeted.Target != targetHex(server.MaxDifficulty) || retargeted.Height != 1 {
+		t.Errorf("retargeted job at height %d with target %s", retargeted.Height, retargeted.Target)
+	}
+	submit["job_id"] = retargeted.JobID
+	if msg := miner.call("submit", submit, &status); msg != "Duplicate share" {
+		t.Errorf("duplicate share of the retargeted job: error %q", msg)
+	}
+
+	// A share of the new difficulty is a block
+	nonce, hash = mine(t, variant, retargeted, server.MaxDifficulty, daemon.Difficulty, true)
+	submit = map[string]interface{}{"job_id": retargeted.JobID, "nonce": nonce, "result": hash.String()}
+	if msg := miner.call("submit", submit, &status); msg != "" {
+		t.Fatal(msg)
+	}
+	share := <-shares
+	if daemon.Height() != 2 || len(daemon.Submitted()) != 1 {
+		t.Fatalf("daemon at height %d with %d submitted blocks", daemon.Height(), len(daemon.Submitted()))
+	}
+	if top := daemon.Blocks()[1]; share.BlockHash != top.Hash || share.Difficulty != server.MaxDifficulty {
+		t.Errorf("share = %+v, want block %s", share, top.Hash)
+	}
+
+	// The found block expires the jobs of the miner
+	if next := miner.nextJob(); next.Height != 2 {
+		t.Errorf("job after the block at height %d", next.Height)
+	}
+	if msg := miner.call("submit", submit, &status); msg != "Duplicate share" {
+		t.Errorf("resubmitted block: error %q", msg)
+	}
+	submit = map[string]interface{}{"job_id": retargeted.JobID, "nonce": "ffffffff"}
+	if msg := miner.call("submit", submit, &status); msg != "Block expired" {
+		t.Errorf("share of the previous block: error %q", msg)
+	}
+}
+
+func TestPushJobFailure(t *testing.T) {
+	daemon := rpctest.NewTurtleCoind()
+	defer daemon.Close()
+	wallet, err := keys.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(daemon.Client(), wallet.Address)
+	if err := server.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	conn, client := net.Pipe()
+	params, _ := json.Marshal(map[string]string{"login": wallet.Address})
+	miner, _, err := server.login(params, conn, json.NewEncoder(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.register(miner)
+
+	// The job cannot be written once the miner hung up
+	client.Close()
+	miner.pushJob()
+	if server.miners[miner] {
+		t.Error("miner still registered")
+	}
+	// Reading from a pipe only fails this way once closed on this end
+	if _, err := conn.Read(make([]byte, 1)); err != io.ErrClosedPipe {
+		t.Errorf("read from the connection of the miner: err = %v, want %v", err, io.ErrClosedPipe)
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package turtlecoinrpc
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// MaxReorgDepth is the number of recent blocks remembered by a
+// BlockSubscription to find the fork point of a reorganization
+const MaxReorgDepth = 1000
+
+// blocksPerRequest is the number of blocks returned by GetBlocks
+const blocksPerRequest = 30
+
+// minPollInterval is the first wait of a subscription once it
+// is caught up, the wait doubles up to the PollInterval of the
+// daemon while no new block is found
+const minPollInterval = time.Second
+
+// BlockEventType tells whether a block joined or left the main chain
+type BlockEventType int
+
+const (
+	// BlockConnected is sent when a block joins the main chain
+	BlockConnected BlockEventType = iota
+	// BlockDisconnected is sent when a block leaves the main chain
+	// because of a reorganization, newest block first
+	BlockDisconnected
+)
+
+// BlockHeader holds the fields of a block shared by
+// the block and block header responses of the daemon
+type BlockHeader struct {
+	Height    int    `json:"height"`
+	Hash      string `json:"hash"`
+	PrevHash  string `json:"prev_hash"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// BlockEvent is a change of the main chain. ForkHeight is the height
+// of the last block shared with the previous chain for the events of
+// a reorganization, and -1 otherwise. Block is the full f_block_json
+// result of connected blocks.
+type BlockEvent struct {
+	Type BlockEventType
+	BlockHeader
+	ForkHeight int
+	Block      map[string]interface{}
+}
+
+// BlockSubscription follows the main chain of a daemon. Events is
+// closed once the subscription stops, after which Err returns the
+// reason it stopped.
+type BlockSubscription struct {
+	Events <-chan BlockEvent
+
+	daemon *TurtleCoind
+	events chan BlockEvent
+	next   int
+	hashes map[int]string
+	err    error
+}
+
+/*
+SubscribeBlocks method sends a Connected event for every block from fromHeight up
+to the top of the chain and for every block mined after that. Missed heights are
+backfilled with f_blocks_list_json and f_block_json. When the chain reorganizes, the replaced
+blocks are sent as Disconnected events down to the fork point before the blocks
+of the new chain are sent as Connected events.
+*/
+func (daemon *TurtleCoind) SubscribeBlocks(ctx context.Context, fromHeight int) *BlockSubscription {
+	events := make(chan BlockEvent, blocksPerRequest)
+	subscription := &BlockSubscription{
+		Events: events,
+		daemon: daemon,
+		events: events,
+		next:   fromHeight,
+		hashes: make(map[int]string),
+	}
+
+	go subscription.run(ctx)
+	return subscription
+}
+
+// Err returns the error which stopped the subscription.
+// It must only be called once Events is closed.
+func (subscription *BlockSubscription) Err() error {
+	return subscription.err
+}
+
+func (subscription *BlockSubscription) run(ctx context.Context) {
+	defer close(subscription.events)
+
+	maxWait := subscription.daemon.pollInterval()
+	wait := minPollInterval
+	for {
+		found, err := subscription.poll(ctx)
+		if err != nil {
+			// Requests cut short by the context fail with it
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			subscription.err = err
+			return
+		}
+
+		// Keep going at once while catching up, and back
+		// off while the chain does not move
+		if found {
+			wait = minPollInterval
+			continue
+		}
+
+		if wait > maxWait {
+			wait = maxWait
+		}
+		select {
+		case <-ctx.Done():
+			subscription.err = ctx.Err()
+			return
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// poll sends the events for the next window of blocks
+// and reports whether any block was connected
+func (subscription *BlockSubscription) poll(ctx context.Context) (bool, error) {
+	top, err := subscription.daemon.blockHeaderByHeight(ctx, -1)
+	if err != nil {
+		return false, err
+	}
+
+	// The top block replaced the last block we know of
+	if known, ok := subscription.hashes[top.Height]; ok && known != top.Hash {
+		return true, subscription.reorganize(ctx, top.Height)
+	}
+	if subscription.next > top.Height {
+		return false, nil
+	}
+
+	last := subscription.next + blocksPerRequest - 1
+	if last > top.Height {
+		last = top.Height
+	}
+	hashes, err := subscription.daemon.blockHashes(ctx, subscription.next, last)
+	if err != nil {
+		return false, err
+	}
+
+	for height := subscription.next; height <= last; height++ {
+		hash, ok := hashes[height]
+		if !ok {
+			return false, errors.New("Missing block " + strconv.Itoa(height) + " in the response")
+		}
+
+		block, header, err := subscription.daemon.blockDetails(ctx, hash)
+		if err != nil {
+			return false, err
+		}
+
+		if prev, ok := subscription.hashes[height-1]; ok && prev != header.PrevHash {
+			return true, subscription.reorganize(ctx, height-1)
+		}
+
+		event := BlockEvent{Type: BlockConnected, BlockHeader: header, ForkHeight: -1, Block: block}
+		if err = subscription.send(ctx, event); err != nil {
+			return false, err
+		}
+
+		subscription.hashes[height] = header.Hash
+		delete(subscription.hashes, height-MaxReorgDepth)
+		subscription.next = height + 1
+	}
+
+	return true, nil
+}
+
+// reorganize finds the last known block at or below height which is
+// still in the main chain and disconnects every known block above it
+func (subscription *BlockSubscription) reorganize(ctx context.Context, height int) error {
+	fork := height
+	for ; ; fork-- {
+		known, ok := subscription.hashes[fork]
+		if !ok {
+			return errors.New("Chain reorganization deeper than " + strconv.Itoa(MaxReorgDepth) + " blocks")
+		}
+
+		header, err := subscription.daemon.blockHeaderByHeight(ctx, fork)
+		if err != nil {
+			return err
+		}
+		if header.Hash == known {
+			break
+		}
+	}
+
+	for height := subscription.next - 1; height > fork; height-- {
+		event := BlockEvent{
+			Type:        BlockDisconnected,
+			BlockHeader: BlockHeader{Height: height, Hash: subscription.hashes[height]},
+			ForkHeight:  fork,
+		}
+		if err := subscription.send(ctx, event); err != nil {
+			return err
+		}
+		delete(subscription.hashes, height)
+	}
+
+	subscription.next = fork + 1
+	return nil
+}
+
+func (subscription *BlockSubscription) send(ctx context.Context, event BlockEvent) error {
+	select {
+	case subscription.events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// blockHeaderByHeight returns the header of the block at the
+// height, or of the top block if the height is negative
+func (daemon *TurtleCoind) blockHeaderByHeight(ctx context.Context, height int) (BlockHeader, error) {
+	method := "getblockheaderbyheight"
+	params := make(map[string]interface{})
+	if height < 0 {
+		method = "getlastblockheader"
+	} else {
+		params["height"] = height
+	}
+	resp, err := daemon.makePostRequest(ctx, method, params)
+	if err != nil {
+		return BlockHeader{}, err
+	}
+	obj, _ := resp.(map[string]interface{})
+	result, err := RPCResult(obj)
+	if err != nil {
+		return BlockHeader{}, err
+	}
+
+	var header struct {
+		BlockHeader BlockHeader `json:"block_header"`
+	}
+	if err = ConvertResponse(result, &header); err != nil {
+		return BlockHeader{}, err
+	}
+	return header.BlockHeader, nil
+}
+
+// blockHashes returns the hashes of the blocks from first to
+// last, fetched with as few f_blocks_list_json calls as possible
+func (daemon *TurtleCoind) blockHashes(ctx context.Context, first int, last int) (map[int]string, error) {
+	hashes := make(map[int]string, last-first+1)
+	for top := last; top >= first; top -= blocksPerRequest {
+		params := make(map[string]interface{})
+		params["height"] = top
+		resp, err := daemon.makePostRequest(ctx, "f_blocks_list_json", params)
+		if err != nil {
+			return nil, err
+		}
+		obj, _ := resp.(map[string]interface{})
+		result, err := RPCResult(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		var list struct {
+			Blocks []BlockHeader `json:"blocks"`
+		}
+		if err = ConvertResponse(result, &list); err != nil {
+			return nil, err
+		}
+		sort.Slice(list.Blocks, func(i, j int) bool {
+			return list.Blocks[i].Height > list.Blocks[j].Height
+		})
+		for _, block := range list.Blocks {
+			if block.Height >= first && block.Height <= top {
+				hashes[block.Height] = block.Hash
+			}
+		}
+	}
+	return hashes, nil
+}
+
+// blockDetails returns the f_block_json result of a block along with its header
+func (daemon *TurtleCoind) blockDetails(ctx context.Context, hash string) (map[string]interface{}, BlockHeader, error) {
+	params := make(map[string]interface{})
+	params["hash"] = hash
+	resp, err := daemon.makePostRequest(ctx, "f_block_json", params)
+	if err != nil {
+		return nil, BlockHeader{}, err
+	}
+	obj, _ := resp.(map[string]interface{})
+	result, err := RPCResult(obj)
+	if err != nil {
+		return nil, BlockHeader{}, err
+	}
+
+	var details struct {
+		Block map[string]interface{} `json:"block"`
+	}
+	if err = ConvertResponse(result, &details); err != nil {
+		return nil, BlockHeader{}, err
+	}
+
+	var header BlockHeader
+	if err = ConvertResponse(details.Block, &header); err != nil {
+		return nil, BlockHeader{}, err
+	}
+	if header.Hash == "" {
+		return nil, BlockHeader{}, errors.New("Missing block " + hash + " in the response")
+	}
+	return details.Block, header, nil
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package turtlecoinrpc_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	trpc "github.com/turtlecoin/turtlecoin-rpc-go"
+	"github.com/turtlecoin/turtlecoin-rpc-go/rpctest"
+)
+
+// fakeDaemon starts a fake daemon with blocks mined on top of the
+// genesis block, and returns it with a client polling it quickly
+func fakeDaemon(t *testing.T, blocks int) (*rpctest.TurtleCoind, *trpc.TurtleCoind) {
+	fake := rpctest.NewTurtleCoind()
+	t.Cleanup(fake.Close)
+	for i := 0; i < blocks; i++ {
+		fake.MineBlock()
+	}
+
+	client := fake.Client()
+	client.PollInterval = 10 * time.Millisecond
+	return fake, client
+}
+
+// blockEvent is the short form of an event compared by the tests
+type blockEvent struct {
+	connected bool
+	height    int
+	hash      string
+	fork      int
+}
+
+func (event blockEvent) String() string {
+	kind := "disconnected"
+	if event.connected {
+		kind = "connected"
+	}
+	return kind + " " + strconv.Itoa(event.height) + " " + event.hash + " fork " + strconv.Itoa(event.fork)
+}
+
+// connected returns the connected events of the blocks of the
+// fake daemon from first to last
+func connected(fake *rpctest.TurtleCoind, first int, last int) []blockEvent {
+	var events []blockEvent
+	for _, block := range fake.Blocks()[first : last+1] {
+		events = append(events, blockEvent{true, block.Height, block.Hash, -1})
+	}
+	return events
+}
+
+// disconnected returns the disconnected events of the blocks,
+// newest first, down to the fork height
+func disconnected(blocks []rpctest.DaemonBlock, fork int) []blockEvent {
+	var events []blockEvent
+	for i := len(blocks) - 1; i >= 0 && blocks[i].Height > fork; i-- {
+		events = append(events, blockEvent{false, blocks[i].Height, blocks[i].Hash, fork})
+	}
+	return events
+}
+
+// expectEvents reads as many events as wanted from the subscription
+func expectEvents(t *testing.T, step string, subscription *trpc.BlockSubscription, want []blockEvent) {
+	t.Helper()
+	for i, w := range want {
+		select {
+		case event, ok := <-subscription.Events:
+			if !ok {
+				t.Fatalf("%s: subscription stopped before event %d: %v", step, i, subscription.Err())
+			}
+			got := blockEvent{event.Type == trpc.BlockConnected, event.Height, event.Hash, event.ForkHeight}
+			if got != w {
+				t.Fatalf("%s: event %d is %s, want %s", step, i, got, w)
+			}
+			if got.connected && event.Block["hash"] != event.Hash {
+				t.Fatalf("%s: event %d carries the block %v", step, i, event.Block["hash"])
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: no event %d, want %s", step, i, w)
+		}
+	}
+}
+
+// expectStop waits for the subscription to stop and returns its error
+func expectStop(t *testing.T, subscription *trpc.BlockSubscription) error {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return subscription.Err()
+			}
+			t.Errorf("unexpected event %+v", event.BlockHeader)
+		case <-timeout:
+			t.Fatal("subscription did not stop")
+		}
+	}
+}
+
+func TestSubscribeBlocksBackfill(t *testing.T) {
+	fake, client := fakeDaemon(t, 70)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Blocks older than the subscription are sent over several windows
+	subscription := client.SubscribeBlocks(ctx, 5)
+	expectEvents(t, "backfill", subscription, connected(fake, 5, 70))
+
+	fake.MineBlock()
+	fake.MineBlock()
+	expectEvents(t, "new blocks", subscription, connected(fake, 71, 72))
+
+	cancel()
+	if err := expectStop(t, subscription); err != context.Canceled {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestSubscribeBlocksReorg(t *testing.T) {
+	fake, client := fakeDaemon(t, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	subscription := client.SubscribeBlocks(ctx, 0)
+	expectEvents(t, "backfill", subscription, connected(fake, 0, 10))
+
+	// The top block is replaced, then a block is mined on the new chain
+	old := fake.Blocks()
+	fake.Orphan(10)
+	fake.MineBlock()
+	expectEvents(t, "one block", subscription, disconnected(old, 9))
+	expectEvents(t, "one block", subscription, connected(fake, 10, 11))
+
+	// Four blocks are replaced by as many blocks
+	old = fake.Blocks()
+	fake.Orphan(8)
+	expectEvents(t, "four blocks", subscription, disconnected(old, 7))
+	expectEvents(t, "four blocks", subscription, connected(fake, 8, 11))
+}
+
+func TestSubscribeBlocksTooDeep(t *testing.T) {
+	fake, client := fakeDaemon(t, trpc.MaxReorgDepth+5)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	subscription := client.SubscribeBlocks(ctx, 0)
+	expectEvents(t, "backfill", subscription, connected(fake, 0, trpc.MaxReorgDepth+5))
+
+	// The fork point is older than the blocks remembered, so
+	// the subscription stops without disconnecting any block
+	fake.Orphan(2)
+	err := expectStop(t, subscription)
+	if err == nil || !strings.Contains(err.Error(), "deeper than "+strconv.Itoa(trpc.MaxReorgDepth)+" blocks") {
+		t.Errorf("err = %v", err)
+	}
+}
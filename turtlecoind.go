@@ -3,11 +3,26 @@
 
 package turtlecoinrpc
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
 // TurtleCoind structure contains the
-// URL and Port info of node for RPC calls
+// URL and Port info of node for RPC calls.
+// Transport is used to perform the requests,
+// http.DefaultTransport is used if it is nil.
+// PollInterval is the time between two polls of the
+// helpers which wait on the daemon, DefaultPollInterval
+// is used if it is zero.
 type TurtleCoind struct {
-	URL  string
-	Port int
+	URL          string
+	Port         int
+	Transport    http.RoundTripper
+	PollInterval time.Duration
 }
 
 func (daemon *TurtleCoind) check() {
@@ -85,7 +100,7 @@ func (daemon *TurtleCoind) GetBlocks(height int) (map[string]interface{}, error)
 	daemon.check()
 	params := make(map[string]interface{})
 	params["height"] = height
-	resp, err := daemon.makePostRequest("f_blocks_list_json", params)
+	resp, err := daemon.makePostRequest(context.Background(), "f_blocks_list_json", params)
 	if resp != nil {
 		return resp.(map[string]interface{}), err
 	}
@@ -100,7 +115,7 @@ func (daemon *TurtleCoind) GetBlock(hash string) (map[string]interface{}, error)
 	daemon.check()
 	params := make(map[string]interface{})
 	params["hash"] = hash
-	resp, err := daemon.makePostRequest("f_block_json", params)
+	resp, err := daemon.makePostRequest(context.Background(), "f_block_json", params)
 	if resp != nil {
 		return resp.(map[string]interface{}), err
 	}
@@ -115,7 +130,7 @@ func (daemon *TurtleCoind) GetTransaction(hash string) (map[string]interface{},
 	daemon.check()
 	params := make(map[string]interface{})
 	params["hash"] = hash
-	resp, err := daemon.makePostRequest("f_transaction_json", params)
+	resp, err := daemon.makePostRequest(context.Background(), "f_transaction_json", params)
 	if resp != nil {
 		return resp.(map[string]interface{}), err
 	}
@@ -129,7 +144,7 @@ GetTransactionPool method returns the list of unconfirmed transactions present i
 func (daemon *TurtleCoind) GetTransactionPool() (map[string]interface{}, error) {
 	daemon.check()
 	params := make(map[string]interface{})
-	resp, err := daemon.makePostRequest("f_on_transactions_pool_json", params)
+	resp, err := daemon.makePostRequest(context.Background(), "f_on_transactions_pool_json", params)
 	if resp != nil {
 		return resp.(map[string]interface{}), err
 	}
@@ -143,7 +158,7 @@ GetBlockCount method returns the height of the top block
 func (daemon *TurtleCoind) GetBlockCount() (map[string]interface{}, error) {
 	daemon.check()
 	params := make(map[string]interface{})
-	resp, err := daemon.makePostRequest("getblockcount", params)
+	resp, err := daemon.makePostRequest(context.Background(), "getblockcount", params)
 	if resp != nil {
 		return resp.(map[string]interface{}), err
 	}
@@ -157,7 +172,7 @@ GetBlockHash method returns the block hash by height
 func (daemon *TurtleCoind) GetBlockHash(height int) (map[string]interface{}, error) {
 	daemon.check()
 	params := []int{height}
-	resp, err := daemon.makePostRequest("on_getblockhash", params)
+	resp, err := daemon.makePostRequest(context.Background(), "on_getblockhash", params)
 	if resp != nil {
 		return resp.(map[string]interface{}), err
 	}
@@ -173,7 +188,7 @@ func (daemon *TurtleCoind) GetBlockTemplate(reserveSize int, walletAddress strin
 	params := make(map[string]interface{})
 	params["reserve_size"] = reserveSize
 	params["wallet_address"] = walletAddress
-	resp, err := daemon.makePostRequest("getblocktemplate", params)
+	resp, err := daemon.makePostRequest(context.Background(), "getblocktemplate", params)
 	if resp != nil {
 		return resp.(map[string]interface{}), err
 	}
@@ -187,7 +202,7 @@ GetCurrencyID method returns the currency id of the network
 func (daemon *TurtleCoind) GetCurrencyID() (map[string]interface{}, error) {
 	daemon.check()
 	params := make(map[string]interface{})
-	resp, err := daemon.makePostRequest("getcurrencyid", params)
+	resp, err := daemon.makePostRequest(context.Background(), "getcurrencyid", params)
 	if resp != nil {
 		return resp.(map[string]interface{}), err
 	}
@@ -201,7 +216,7 @@ SubmitBlock method submits a block to the network corresponding to the input blo
 func (daemon *TurtleCoind) SubmitBlock(blockBlob string) (map[string]interface{}, error) {
 	daemon.check()
 	params := []string{blockBlob}
-	resp, err := daemon.makePostRequest("submitblock", params)
+	resp, err := daemon.makePostRequest(context.Background(), "submitblock", params)
 	if resp != nil {
 		return resp.(map[string]interface{}), err
 	}
@@ -215,7 +230,7 @@ GetLastBlockHeader method returns the block header of the last block
 func (daemon *TurtleCoind) GetLastBlockHeader() (map[string]interface{}, error) {
 	daemon.check()
 	params := make(map[string]interface{})
-	resp, err := daemon.makePostRequest("getlastblockheader", params)
+	resp, err := daemon.makePostRequest(context.Background(), "getlastblockheader", params)
 	if resp != nil {
 		return resp.(map[string]interface{}), err
 	}
@@ -230,7 +245,7 @@ func (daemon *TurtleCoind) GetBlockHeaderByHash(hash string) (map[string]interfa
 	daemon.check()
 	params := make(map[string]interface{})
 	params["hash"] = hash
-	resp, err := daemon.makePostRequest("getblockheaderbyhash", params)
+	resp, err := daemon.makePostRequest(context.Background(), "getblockheaderbyhash", params)
 	if resp != nil {
 		return resp.(map[string]interface{}), err
 	}
@@ -245,10 +260,408 @@ func (daemon *TurtleCoind) GetBlockHeaderByHeight(height int) (map[string]interf
 	daemon.check()
 	params := make(map[string]interface{})
 	params["height"] = height
-	resp, err := daemon.makePostRequest("getblockheaderbyheight", params)
+	resp, err := daemon.makePostRequest(context.Background(), "getblockheaderbyheight", params)
 	if resp != nil {
 		return resp.(map[string]interface{}), err
 	}
 
 	return nil, err
 }
+
+// WalletSyncData is the response of GetWalletSyncData
+type WalletSyncData struct {
+	Items    []WalletBlockInfo `json:"items"`
+	Synced   bool              `json:"synced"`
+	TopBlock *TopBlock         `json:"topBlock"`
+	Status   string            `json:"status"`
+}
+
+// TopBlock is the hash and height of the top block of the daemon
+type TopBlock struct {
+	Hash   string `json:"hash"`
+	Height int    `json:"height"`
+}
+
+// WalletBlockInfo holds the transactions of a block
+// needed by a wallet to scan for its outputs
+type WalletBlockInfo struct {
+	BlockHash      string                  `json:"blockHash"`
+	BlockHeight    int                     `json:"blockHeight"`
+	BlockTimestamp int64                   `json:"blockTimestamp"`
+	CoinbaseTX     *RawCoinbaseTransaction `json:"coinbaseTX"`
+	Transactions   []RawTransaction        `json:"transactions"`
+}
+
+// RawCoinbaseTransaction is the coinbase transaction of a block
+type RawCoinbaseTransaction struct {
+	Hash        string      `json:"hash"`
+	TxPublicKey string      `json:"txPublicKey"`
+	UnlockTime  uint64      `json:"unlockTime"`
+	Outputs     []KeyOutput `json:"outputs"`
+}
+
+// RawTransaction is a transaction of a block
+// along with its key inputs and payment id
+type RawTransaction struct {
+	RawCoinbaseTransaction
+	PaymentID string     `json:"paymentID"`
+	Inputs    []KeyInput `json:"inputs"`
+}
+
+// KeyOutput is an output sent to a one time public key
+type KeyOutput struct {
+	Key    string `json:"key"`
+	Amount uint64 `json:"amount"`
+}
+
+// KeyInput is an input spending outputs referenced by key offsets
+type KeyInput struct {
+	Amount     uint64   `json:"amount"`
+	KeyOffsets []uint64 `json:"key_offsets"`
+	KeyImage   string   `json:"k_image"`
+}
+
+// QueryBlocksLiteResult is the response of QueryBlocksLite
+type QueryBlocksLiteResult struct {
+	StartHeight   int              `json:"startHeight"`
+	CurrentHeight int              `json:"currentHeight"`
+	FullOffset    int              `json:"fullOffset"`
+	Items         []BlockShortInfo `json:"items"`
+	Status        string           `json:"status"`
+}
+
+// BlockShortInfo is a block hash with its hex encoded
+// block blob and its transaction prefixes
+type BlockShortInfo struct {
+	BlockID    string                  `json:"blockShortInfo.blockId"`
+	Block      string                  `json:"blockShortInfo.block"`
+	TxPrefixes []TransactionPrefixInfo `json:"blockShortInfo.txPrefixes"`
+}
+
+// TransactionPrefixInfo is a transaction hash with its prefix
+// as returned by the daemon
+type TransactionPrefixInfo struct {
+	TxHash   string          `json:"transactionPrefixInfo.txHash"`
+	TxPrefix json.RawMessage `json:"transactionPrefixInfo.txPrefix"`
+}
+
+/*
+GetWalletSyncData method returns the blocks following the newest of the given
+checkpoints, or following startHeight/startTimestamp when none of them is known.
+Only the data a wallet needs to scan for its outputs is included.
+*/
+func (daemon *TurtleCoind) GetWalletSyncData(
+	blockHashCheckpoints []string,
+	startHeight int,
+	startTimestamp int,
+	blockCount int,
+	skipCoinbaseTransactions bool) (*WalletSyncData, error) {
+	daemon.check()
+	if blockHashCheckpoints == nil {
+		blockHashCheckpoints = []string{}
+	}
+	params := make(map[string]interface{})
+	params["blockHashCheckpoints"] = blockHashCheckpoints
+	params["startHeight"] = startHeight
+	params["startTimestamp"] = startTimestamp
+	params["blockCount"] = blockCount
+	params["skipCoinbaseTransactions"] = skipCoinbaseTransactions
+	resp, err := daemon.makeJSONRequest(context.Background(), "getwalletsyncdata", params)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	var data WalletSyncData
+	if err = ConvertResponse(resp, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+/*
+QueryBlocksLite method returns the blocks following the newest of the given
+checkpoints with their transaction prefixes, starting at the timestamp when
+none of the checkpoints is known
+*/
+func (daemon *TurtleCoind) QueryBlocksLite(blockHashCheckpoints []string, timestamp int) (*QueryBlocksLiteResult, error) {
+	daemon.check()
+	if blockHashCheckpoints == nil {
+		blockHashCheckpoints = []string{}
+	}
+	params := make(map[string]interface{})
+	params["blockIds"] = blockHashCheckpoints
+	params["timestamp"] = timestamp
+	resp, err := daemon.makeJSONRequest(context.Background(), "queryblockslite", params)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	var result QueryBlocksLiteResult
+	if err = ConvertResponse(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+/*
+GetGlobalIndexesForRange method returns the global output indexes of every
+transaction in the blocks from startHeight up to endHeight, keyed by transaction hash
+*/
+func (daemon *TurtleCoind) GetGlobalIndexesForRange(startHeight int, endHeight int) (map[string][]uint64, error) {
+	daemon.check()
+	if endHeight < startHeight {
+		return nil, errors.New("endHeight cannot be less than startHeight")
+	}
+	params := make(map[string]interface{})
+	params["startHeight"] = startHeight
+	params["endHeight"] = endHeight
+	resp, err := daemon.makeJSONRequest(context.Background(), "get_global_indexes_for_range", params)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Indexes []struct {
+			Key   string   `json:"key"`
+			Value []uint64 `json:"value"`
+		} `json:"indexes"`
+	}
+	if err = ConvertResponse(resp, &result); err != nil {
+		return nil, err
+	}
+
+	indexes := make(map[string][]uint64, len(result.Indexes))
+	for _, item := range result.Indexes {
+		indexes[item.Key] = item.Value
+	}
+	return indexes, nil
+}
+
+/*
+BlockHashCheckpoints method builds the sparse list of block hashes used as
+checkpoints by GetWalletSyncData and QueryBlocksLite. The hashes run from the
+given height down to the genesis block, newest first: the latest 10 blocks are
+consecutive and the gaps double after that. Heights are the ones taken by GetBlockHash.
+*/
+func (daemon *TurtleCoind) BlockHashCheckpoints(height int) ([]string, error) {
+	var checkpoints []string
+	offset, step := 0, 1
+	for height-offset >= 1 {
+		hash, err := daemon.blockHash(height - offset)
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, hash)
+
+		if len(checkpoints) >= 10 {
+			step *= 2
+		}
+		offset += step
+	}
+
+	// The genesis block is always the last checkpoint
+	if height-offset+step > 1 {
+		hash, err := daemon.blockHash(1)
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, hash)
+	}
+
+	return checkpoints, nil
+}
+
+// blockHash returns the hash from the GetBlockHash response
+func (daemon *TurtleCoind) blockHash(height int) (string, error) {
+	resp, err := daemon.GetBlockHash(height)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := RPCResult(resp)
+	if err != nil {
+		return "", err
+	}
+
+	hash, ok := result.(string)
+	if !ok {
+		return "", errors.New("Unexpected block hash in the response")
+	}
+	return hash, nil
+}
+
+// ErrTransactionDropped is returned by WaitForTransaction when the
+// transaction is neither in the pool nor in a block
+var ErrTransactionDropped = errors.New("Transaction is no longer in the pool or the blockchain")
+
+// DefaultPollInterval is the time between two polls of the
+// helpers which wait on the daemon when PollInterval is not set
+const DefaultPollInterval = 5 * time.Second
+
+// pollInterval returns PollInterval or its default
+func (daemon *TurtleCoind) pollInterval() time.Duration {
+	if daemon.PollInterval <= 0 {
+		return DefaultPollInterval
+	}
+	return daemon.PollInterval
+}
+
+// TransactionsStatus is the response of TransactionsStatus
+type TransactionsStatus struct {
+	InPool  []string `json:"transactionsInPool"`
+	InBlock []string `json:"transactionsInBlock"`
+	Unknown []string `json:"transactionsUnknown"`
+	Status  string   `json:"status"`
+}
+
+/*
+SendRawTransaction method broadcasts a hex encoded transaction blob to the network
+*/
+func (daemon *TurtleCoind) SendRawTransaction(ctx context.Context, txHex string) error {
+	daemon.check()
+	if txHex == "" {
+		return errors.New("Transaction blob cannot be empty")
+	}
+	params := make(map[string]interface{})
+	params["tx_as_hex"] = txHex
+	resp, err := daemon.makeJSONRequest(ctx, "sendrawtransaction", params)
+	if err != nil {
+		return err
+	}
+
+	if obj, ok := resp.(map[string]interface{}); ok {
+		if message, _ := obj["error"].(string); message != "" {
+			return errors.New(message)
+		}
+	}
+	return checkStatus(resp)
+}
+
+/*
+TransactionsStatus method returns whether each of the given transactions
+is in the pool, in a block or unknown to the daemon
+*/
+func (daemon *TurtleCoind) TransactionsStatus(ctx context.Context, hashes []string) (*TransactionsStatus, error) {
+	daemon.check()
+	params := make(map[string]interface{})
+	params["transactionHashes"] = hashes
+	resp, err := daemon.makeJSONRequest(ctx, "get_transactions_status", params)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	var status TransactionsStatus
+	if err = ConvertResponse(resp, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+/*
+WaitForTransaction method polls the daemon until the transaction is in a block
+with at least the given number of confirmations and returns the height of that
+block. ErrTransactionDropped is returned if the transaction leaves the pool
+without being mined.
+*/
+func (daemon *TurtleCoind) WaitForTransaction(ctx context.Context, hash string, confirmations int) (int, error) {
+	daemon.check()
+	for {
+		status, err := daemon.TransactionsStatus(ctx, []string{hash})
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case contains(status.InBlock, hash):
+			height, depth, err := daemon.transactionDepth(ctx, hash)
+			if err != nil {
+				return 0, err
+			}
+			if depth >= confirmations {
+				return height, nil
+			}
+		case contains(status.InPool, hash):
+		default:
+			return 0, ErrTransactionDropped
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(daemon.pollInterval()):
+		}
+	}
+}
+
+// transactionDepth returns the height of the block holding
+// the transaction and its number of confirmations
+func (daemon *TurtleCoind) transactionDepth(ctx context.Context, hash string) (int, int, error) {
+	params := make(map[string]interface{})
+	params["hash"] = hash
+	resp, err := daemon.makePostRequest(ctx, "f_transaction_json", params)
+	if err != nil {
+		return 0, 0, err
+	}
+	obj, _ := resp.(map[string]interface{})
+	result, err := RPCResult(obj)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var tx struct {
+		Block struct {
+			Height int `json:"height"`
+		} `json:"block"`
+	}
+	if err = ConvertResponse(result, &tx); err != nil {
+		return 0, 0, err
+	}
+
+	count, err := daemon.blockCount(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	return tx.Block.Height, count - tx.Block.Height, nil
+}
+
+// blockCount returns the count of the GetBlockCount response
+func (daemon *TurtleCoind) blockCount(ctx context.Context) (int, error) {
+	resp, err := daemon.makePostRequest(ctx, "getblockcount", make(map[string]interface{}))
+	if err != nil {
+		return 0, err
+	}
+	obj, _ := resp.(map[string]interface{})
+	result, err := RPCResult(obj)
+	if err != nil {
+		return 0, err
+	}
+
+	var count struct {
+		Count int `json:"count"`
+	}
+	if err = ConvertResponse(result, &count); err != nil {
+		return 0, err
+	}
+	return count.Count, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
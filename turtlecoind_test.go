@@ -0,0 +1,77 @@
+// Copyright (c) 2018-2019 Rashed Mohammed, The TurtleCoin Developers
+// Please see the included LICENSE file for more information
+
+package turtlecoinrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDaemon returns a client of a daemon served by the handler
+func testDaemon(t *testing.T, handler http.HandlerFunc) *TurtleCoind {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	addr := strings.TrimPrefix(server.URL, "http://")
+	i := strings.LastIndex(addr, ":")
+	port, _ := strconv.Atoi(addr[i+1:])
+	return &TurtleCoind{URL: addr[:i], Port: port, PollInterval: 10 * time.Millisecond}
+}
+
+func TestSendRawTransactionContext(t *testing.T) {
+	// The handler is released once the test ends, as the
+	// server only notices a cancelled request on the next read
+	release := make(chan struct{})
+	daemon := testDaemon(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := daemon.SendRawTransaction(ctx, "00"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWaitForTransaction(t *testing.T) {
+	polls := make(chan struct{}, 100)
+	daemon := testDaemon(t, func(w http.ResponseWriter, r *http.Request) {
+		var params struct {
+			Hashes []string `json:"transactionHashes"`
+		}
+		json.NewDecoder(r.Body).Decode(&params)
+		polls <- struct{}{}
+
+		status := TransactionsStatus{InPool: []string{}, InBlock: []string{}, Unknown: []string{}, Status: "OK"}
+		if params.Hashes[0] == "pool" {
+			status.InPool = params.Hashes
+		} else {
+			status.Unknown = params.Hashes
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+
+	if _, err := daemon.WaitForTransaction(context.Background(), "gone", 1); err != ErrTransactionDropped {
+		t.Errorf("err = %v, want %v", err, ErrTransactionDropped)
+	}
+
+	// The transaction stays in the pool until the context ends,
+	// the daemon being polled every PollInterval meanwhile
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := daemon.WaitForTransaction(ctx, "pool", 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if len(polls) < 3 {
+		t.Errorf("daemon polled %d times", len(polls))
+	}
+}
@@ -5,6 +5,7 @@ package turtlecoinrpc
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -19,7 +20,7 @@ func (daemon *TurtleCoind) makeGetRequest(method string) (interface{}, error) {
 		return nil, err
 	}
 
-	resp, err := performRequest(req)
+	resp, err := performRequest(req, daemon.Transport)
 	if err != nil {
 		return nil, err
 	}
@@ -31,7 +32,7 @@ func (daemon *TurtleCoind) makeGetRequest(method string) (interface{}, error) {
 	return decodeResponse(resp.Body)
 }
 
-func (daemon *TurtleCoind) makePostRequest(method string, params interface{}) (interface{}, error) {
+func (daemon *TurtleCoind) makePostRequest(ctx context.Context, method string, params interface{}) (interface{}, error) {
 	payload := make(map[string]interface{})
 	payload["jsonrpc"] = "2.0"
 	payload["method"] = method
@@ -44,12 +45,37 @@ func (daemon *TurtleCoind) makePostRequest(method string, params interface{}) (i
 
 	body := bytes.NewBuffer(jsonpayload)
 
-	req, err := http.NewRequest("POST", "http://"+daemon.URL+":"+strconv.Itoa(daemon.Port)+"/json_rpc", body)
+	req, err := http.NewRequestWithContext(ctx, "POST", "http://"+daemon.URL+":"+strconv.Itoa(daemon.Port)+"/json_rpc", body)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := performRequest(req)
+	resp, err := performRequest(req, daemon.Transport)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(strconv.Itoa(resp.StatusCode) + " " + resp.Status)
+	}
+
+	return decodeResponse(resp.Body)
+}
+
+func (daemon *TurtleCoind) makeJSONRequest(ctx context.Context, method string, params interface{}) (interface{}, error) {
+	jsonpayload, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	body := bytes.NewBuffer(jsonpayload)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "http://"+daemon.URL+":"+strconv.Itoa(daemon.Port)+"/"+method, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := performRequest(req, daemon.Transport)
 	if err != nil {
 		return nil, err
 	}
@@ -81,7 +107,7 @@ func (wallet *Walletd) makePostRequest(method string, params interface{}) (inter
 		return nil, err
 	}
 
-	resp, err := performRequest(req)
+	resp, err := performRequest(req, wallet.Transport)
 	if err != nil {
 		return nil, err
 	}
@@ -100,7 +126,7 @@ func (wallet *WalletAPI) makeGetRequest(method string) (interface{}, error) {
 	}
 
 	req.Header.Set("X-API-KEY", wallet.RPCPassword)
-	resp, err := performRequest(req)
+	resp, err := performRequest(req, wallet.Transport)
 	if err != nil {
 		return nil, err
 	}
@@ -120,7 +146,7 @@ func (wallet *WalletAPI) makeDeleteRequest(method string) (interface{}, error) {
 	}
 
 	req.Header.Set("X-API-KEY", wallet.RPCPassword)
-	resp, err := performRequest(req)
+	resp, err := performRequest(req, wallet.Transport)
 	if err != nil {
 		return nil, err
 	}
@@ -147,7 +173,7 @@ func (wallet *WalletAPI) makePutRequest(method string, params map[string]interfa
 	}
 
 	req.Header.Set("X-API-KEY", wallet.RPCPassword)
-	resp, err := performRequest(req)
+	resp, err := performRequest(req, wallet.Transport)
 	if err != nil {
 		return nil, err
 	}
@@ -174,7 +200,7 @@ func (wallet *WalletAPI) makePostRequest(method string, params map[string]interf
 	}
 
 	req.Header.Set("X-API-KEY", wallet.RPCPassword)
-	resp, err := performRequest(req)
+	resp, err := performRequest(req, wallet.Transport)
 	if err != nil {
 		return nil, err
 	}
@@ -195,8 +221,8 @@ func (wallet *WalletAPI) checkSSL() string {
 	return "http"
 }
 
-func performRequest(req *http.Request) (*http.Response, error) {
-	client := &http.Client{}
+func performRequest(req *http.Request, transport http.RoundTripper) (*http.Response, error) {
+	client := &http.Client{Transport: transport}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -206,6 +232,19 @@ func performRequest(req *http.Request) (*http.Response, error) {
 	return resp, nil
 }
 
+// StatusError is returned by the WalletAPI methods when the
+// wallet-api answers with an error status. ErrorCode is the error
+// code of the wallet-api for the 400 responses, and 0 otherwise.
+type StatusError struct {
+	StatusCode int
+	ErrorCode  int
+	Message    string
+}
+
+func (err *StatusError) Error() string {
+	return err.Message
+}
+
 func handleResponseStatusCode(resp *http.Response) error {
 	if resp.StatusCode == http.StatusBadRequest {
 		responseError, err := decodeResponse(resp.Body)
@@ -213,23 +252,25 @@ func handleResponseStatusCode(resp *http.Response) error {
 			return err
 		}
 
-		return errors.New(responseError.(map[string]interface{})["errorMessage"].(string))
+		body := responseError.(map[string]interface{})
+		code, _ := body["errorCode"].(float64)
+		return &StatusError{StatusCode: resp.StatusCode, ErrorCode: int(code), Message: body["errorMessage"].(string)}
 	}
 
 	switch resp.StatusCode {
 	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
 		return nil
 	case http.StatusUnauthorized:
-		return errors.New("API key is missing or invalid")
+		return &StatusError{StatusCode: resp.StatusCode, Message: "API key is missing or invalid"}
 	case http.StatusForbidden:
-		return errors.New("A wallet is already open. Call DELETE on /wallet first, to close it")
+		return &StatusError{StatusCode: resp.StatusCode, Message: "A wallet is already open. Call DELETE on /wallet first, to close it"}
 	case http.StatusNotFound:
-		return errors.New("The transaction hash was not found")
+		return &StatusError{StatusCode: resp.StatusCode, Message: "The transaction hash was not found"}
 	case http.StatusInternalServerError:
-		return errors.New("An exception was thrown whilst processing the request. See the WalletAPI console for logs")
+		return &StatusError{StatusCode: resp.StatusCode, Message: "An exception was thrown whilst processing the request. See the WalletAPI console for logs"}
 	}
 
-	return errors.New("Unhandled Status " + strconv.Itoa(resp.StatusCode))
+	return &StatusError{StatusCode: resp.StatusCode, Message: "Unhandled Status " + strconv.Itoa(resp.StatusCode)}
 }
 
 func decodeResponse(body io.ReadCloser) (interface{}, error) {
@@ -252,8 +293,67 @@ func decodeResponse(body io.ReadCloser) (interface{}, error) {
 	return respBodyInterface, nil
 }
 
+// ConvertResponse converts a decoded response into
+// the typed structure pointed to by v
+func ConvertResponse(resp interface{}, v interface{}) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// checkStatus returns an error if the daemon
+// did not report an OK status
+func checkStatus(resp interface{}) error {
+	obj, ok := resp.(map[string]interface{})
+	if !ok {
+		return errors.New("Unexpected response from the daemon")
+	}
+
+	if status, _ := obj["status"].(string); status != "OK" {
+		if status == "" {
+			status = "Missing status in the daemon response"
+		}
+		return errors.New(status)
+	}
+
+	return nil
+}
+
+// RPCError is the error object of a JSON-RPC response
+type RPCError struct {
+	Code    int
+	Message string
+}
+
+func (err *RPCError) Error() string {
+	return err.Message
+}
+
+// RPCResult returns the result of a JSON-RPC response,
+// or its error object as an *RPCError
+func RPCResult(resp map[string]interface{}) (interface{}, error) {
+	if rpcError, ok := resp["error"].(map[string]interface{}); ok {
+		code, _ := rpcError["code"].(float64)
+		message, _ := rpcError["message"].(string)
+		return nil, &RPCError{int(code), message}
+	}
+
+	result, ok := resp["result"]
+	if !ok {
+		return nil, errors.New("Missing result in the response")
+	}
+
+	return result, nil
+}
+
 // PrettyPrint prints the given map
 // as a JSON object
+//
+// Deprecated: the trtl-rpc command prints the responses
+// of every method as JSON, tables or CSV.
 func PrettyPrint(response interface{}) {
 	resp, err := json.MarshalIndent(response, "", " ")
 	if err != nil {
@@ -5,11 +5,14 @@ package turtlecoinrpc
 
 import (
 	"errors"
+	"net/http"
 	"strconv"
 )
 
 // WalletAPI structure contains the info of wallet
-// URL and Port, Daemon URL and Port, and RPCPassword
+// URL and Port, Daemon URL and Port, and RPCPassword.
+// Transport is used to perform the requests,
+// http.DefaultTransport is used if it is nil
 type WalletAPI struct {
 	URL         string
 	Port        int
@@ -17,6 +20,7 @@ type WalletAPI struct {
 	DaemonPort  int
 	DaemonSSL   bool
 	RPCPassword string
+	Transport   http.RoundTripper
 }
 
 func (wallet *WalletAPI) check() error {
@@ -618,6 +622,43 @@ func (wallet *WalletAPI) GetTransactionDetails(hash string) (map[string]interfac
 	return nil, err
 }
 
+// HasTransaction reports whether the wallet knows the
+// transaction with the given hash, confirmed or not
+func (wallet *WalletAPI) HasTransaction(hash string) (bool, error) {
+	err := wallet.check()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = wallet.makeGetRequest("transactions/hash/" + hash)
+	var statusError *StatusError
+	switch {
+	case err == nil:
+		return true, nil
+	case !errors.As(err, &statusError) || statusError.StatusCode != http.StatusNotFound:
+		return false, err
+	}
+
+	resp, err := wallet.makeGetRequest("transactions/unconfirmed")
+	if err != nil {
+		return false, err
+	}
+	var result struct {
+		Transactions []struct {
+			Hash string `json:"hash"`
+		} `json:"transactions"`
+	}
+	if err = ConvertResponse(resp, &result); err != nil {
+		return false, err
+	}
+	for _, tx := range result.Transactions {
+		if tx.Hash == hash {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // UnconfirmedTransactions returns the list of
 // unconfirmed transactions for the specified
 // address. If the address is empty then it
@@ -704,20 +745,9 @@ func (wallet *WalletAPI) SendBasicTransaction(
 		return nil, err
 	}
 
-	if destinationAddress == "" {
-		return nil, errors.New("Destination Address is required")
-	}
-
-	if amount == 0 {
-		return nil, errors.New("Amount must be greater than 0")
-	}
-
-	params := make(map[string]interface{})
-	params["destination"] = destinationAddress
-	params["amount"] = amount
-
-	if paymentID != "" {
-		params["paymentID"] = paymentID
+	params, err := basicTransactionParams(destinationAddress, amount, paymentID)
+	if err != nil {
+		return nil, err
 	}
 
 	resp, err := wallet.makePostRequest("transactions/send/basic", params)
@@ -743,33 +773,12 @@ func (wallet *WalletAPI) SendAdvancedTransaction(
 		return nil, err
 	}
 
-	for _, obj := range destinations {
-		if obj["address"] == "" {
-			return nil, errors.New("Address is required in every destination")
-		}
-
-		if obj["amount"] == 0 {
-			return nil, errors.New("Amount must be greater than 0 in every destination")
-		}
-	}
-
-	params := make(map[string]interface{})
-	params["destinations"] = destinations
-	params["fee"] = fee
-	params["unlockTime"] = unlockTime
-	params["mixin"] = mixin
-
-	if paymentID != "" {
-		params["paymentID"] = paymentID
-	}
-	if len(sourceAddresses) != 0 {
-		params["sourceAddresses"] = sourceAddresses
-	}
-	if changeAddress != "" {
-		params["changeAddress"] = changeAddress
+	params, err := advancedTransactionParams(destinations, mixin, fee, sourceAddresses, paymentID, changeAddress, unlockTime)
+	if err != nil {
+		return nil, err
 	}
 
-	resp, err := wallet.makePostRequest("transactions/send/basic", params)
+	resp, err := wallet.makePostRequest("transactions/send/advanced", params)
 	if resp != nil {
 		return resp.(map[string]interface{}), err
 	}
@@ -816,10 +825,208 @@ func (wallet *WalletAPI) SendAdvancedFusion(
 		params["sourceAddresses"] = sourceAddress
 	}
 
-	resp, err := wallet.makePostRequest("transactions/send/fusion/basic", params)
+	resp, err := wallet.makePostRequest("transactions/send/fusion/advanced", params)
 	if resp != nil {
 		return resp.(map[string]interface{}), err
 	}
 
 	return nil, err
 }
+
+// <--------- Prepared Transaction Operations --------->
+
+// PreparedTransaction is a transaction built by the wallet but not
+// sent yet. Fee is the network fee of the transaction and NodeFee
+// the fee of the node the wallet is connected to, which are both
+// paid on top of the amounts of the destinations.
+type PreparedTransaction struct {
+	TransactionHash  string `json:"transactionHash"`
+	Fee              uint64 `json:"fee"`
+	NodeFee          uint64 `json:"nodeFee"`
+	RelayedToNetwork bool   `json:"relayedToNetwork"`
+}
+
+// PrepareBasicTransaction builds a transaction sending the specified
+// amount to the specified address with the specified paymentID. The
+// transaction is sent with SendPreparedTransaction or dropped with
+// CancelPreparedTransaction.
+func (wallet *WalletAPI) PrepareBasicTransaction(
+	destinationAddress string,
+	amount int,
+	paymentID string) (*PreparedTransaction, error) {
+	err := wallet.check()
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := basicTransactionParams(destinationAddress, amount, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return wallet.prepareTransaction("transactions/prepare/basic", params)
+}
+
+// PrepareAdvancedTransaction builds a transaction with the parameters
+// of SendAdvancedTransaction. The transaction is sent with
+// SendPreparedTransaction or dropped with CancelPreparedTransaction.
+func (wallet *WalletAPI) PrepareAdvancedTransaction(
+	destinations []map[string]interface{},
+	mixin int,
+	fee int,
+	sourceAddresses []string,
+	paymentID string,
+	changeAddress string,
+	unlockTime int) (*PreparedTransaction, error) {
+	err := wallet.check()
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := advancedTransactionParams(destinations, mixin, fee, sourceAddresses, paymentID, changeAddress, unlockTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return wallet.prepareTransaction("transactions/prepare/advanced", params)
+}
+
+// SendPreparedTransaction sends the prepared transaction
+// with the specified hash and returns its hash
+func (wallet *WalletAPI) SendPreparedTransaction(hash string) (string, error) {
+	err := wallet.check()
+	if err != nil {
+		return "", err
+	}
+
+	if hash == "" {
+		return "", errors.New("Transaction hash is required")
+	}
+
+	params := make(map[string]interface{})
+	params["transactionHash"] = hash
+
+	resp, err := wallet.makePostRequest("transactions/send/prepared", params)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		TransactionHash string `json:"transactionHash"`
+	}
+	if err = ConvertResponse(resp, &result); err != nil {
+		return "", err
+	}
+	if result.TransactionHash == "" {
+		return hash, nil
+	}
+	return result.TransactionHash, nil
+}
+
+// CancelPreparedTransaction drops the prepared
+// transaction with the specified hash
+func (wallet *WalletAPI) CancelPreparedTransaction(hash string) error {
+	err := wallet.check()
+	if err != nil {
+		return err
+	}
+
+	if hash == "" {
+		return errors.New("Transaction hash is required")
+	}
+
+	_, err = wallet.makeDeleteRequest("transactions/prepared/" + hash)
+	return err
+}
+
+// prepareTransaction posts the parameters to the prepare endpoint and
+// fills the node fee of the prepared transaction from the node details
+func (wallet *WalletAPI) prepareTransaction(method string, params map[string]interface{}) (*PreparedTransaction, error) {
+	resp, err := wallet.makePostRequest(method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var prepared PreparedTransaction
+	if err = ConvertResponse(resp, &prepared); err != nil {
+		return nil, err
+	}
+
+	node, err := wallet.makeGetRequest("node")
+	if err != nil {
+		return nil, err
+	}
+	var details struct {
+		NodeFee uint64 `json:"nodeFee"`
+	}
+	if err = ConvertResponse(node, &details); err != nil {
+		return nil, err
+	}
+	prepared.NodeFee = details.NodeFee
+
+	return &prepared, nil
+}
+
+// basicTransactionParams checks and returns the parameters
+// of the basic send and prepare requests
+func basicTransactionParams(
+	destinationAddress string,
+	amount int,
+	paymentID string) (map[string]interface{}, error) {
+	if destinationAddress == "" {
+		return nil, errors.New("Destination Address is required")
+	}
+
+	if amount == 0 {
+		return nil, errors.New("Amount must be greater than 0")
+	}
+
+	params := make(map[string]interface{})
+	params["destination"] = destinationAddress
+	params["amount"] = amount
+
+	if paymentID != "" {
+		params["paymentID"] = paymentID
+	}
+
+	return params, nil
+}
+
+// advancedTransactionParams checks and returns the parameters
+// of the advanced send and prepare requests
+func advancedTransactionParams(
+	destinations []map[string]interface{},
+	mixin int,
+	fee int,
+	sourceAddresses []string,
+	paymentID string,
+	changeAddress string,
+	unlockTime int) (map[string]interface{}, error) {
+	for _, obj := range destinations {
+		if obj["address"] == "" {
+			return nil, errors.New("Address is required in every destination")
+		}
+
+		if obj["amount"] == 0 {
+			return nil, errors.New("Amount must be greater than 0 in every destination")
+		}
+	}
+
+	params := make(map[string]interface{})
+	params["destinations"] = destinations
+	params["fee"] = fee
+	params["unlockTime"] = unlockTime
+	params["mixin"] = mixin
+
+	if paymentID != "" {
+		params["paymentID"] = paymentID
+	}
+	if len(sourceAddresses) != 0 {
+		params["sourceAddresses"] = sourceAddresses
+	}
+	if changeAddress != "" {
+		params["changeAddress"] = changeAddress
+	}
+
+	return params, nil
+}
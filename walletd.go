@@ -5,14 +5,18 @@ package turtlecoinrpc
 
 import (
 	"errors"
+	"net/http"
 )
 
 // Walletd structure contains the URL and Port info of
-// the wallet service and RPC Password for RPC calls
+// the wallet service and RPC Password for RPC calls.
+// Transport is used to perform the requests,
+// http.DefaultTransport is used if it is nil
 type Walletd struct {
 	URL         string
 	Port        int
 	RPCPassword string
+	Transport   http.RoundTripper
 }
 
 func (wallet *Walletd) check() error {